@@ -0,0 +1,230 @@
+package alert
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/aprln/vwap-engine/config"
+	"github.com/aprln/vwap-engine/model"
+	"github.com/shopspring/decimal"
+)
+
+// percentPlaces is the number of decimal places of the published percentages
+const percentPlaces = 4
+
+// SetUp parses the configured rules, whose trading pairs must all be streamed.
+func SetUp(alertCfg config.Alert, vwapCfg config.VWAP) (Engine, error) {
+	tradingPairs := make(map[string]bool, len(vwapCfg.TradingPairs))
+	for _, tradingPair := range vwapCfg.TradingPairs {
+		tradingPairs[tradingPair] = true
+	}
+
+	rules := make([]Rule, 0, len(alertCfg.Rules))
+	for _, s := range alertCfg.Rules {
+		r, err := ParseRule(s)
+		if err != nil {
+			return Engine{}, err
+		}
+
+		if !tradingPairs[r.TradingPair] {
+			return Engine{}, fmt.Errorf(`alert rule "%s" uses trading pair "%s" which is not streamed`, s, r.TradingPair)
+		}
+
+		rules = append(rules, r)
+	}
+
+	return New(rules, alertCfg.Cooldown, alertCfg.Hysteresis), nil
+}
+
+// New creates an engine evaluating the rules. After an alert, a rule is silent until its value moves back
+// past the threshold by the hysteresis ratio of the threshold, and for at least the cooldown in trade time.
+func New(rules []Rule, cooldown time.Duration, hysteresis decimal.Decimal) Engine {
+	return Engine{
+		rules:      rules,
+		cooldown:   cooldown,
+		hysteresis: hysteresis,
+	}
+}
+
+// Engine evaluates alerting rules on VWAP updates.
+type Engine struct {
+	rules      []Rule
+	cooldown   time.Duration
+	hysteresis decimal.Decimal
+}
+
+// GoEvaluate evaluates the rules of the trading pairs read from in. The rules of other trading pairs are ignored,
+// so an engine can evaluate the pipeline of a single trading pair as well as merged pipelines.
+func (e Engine) GoEvaluate(in <-chan model.VWAP) chan model.Alert {
+	out := make(chan model.Alert, 1)
+
+	go e.evaluateForever(in, out)
+
+	return out
+}
+
+// ruleState is the state of a rule, each evaluation goroutine having its own.
+type ruleState struct {
+	started bool
+	// firing is true from an alert until the rule re-arms
+	firing        bool
+	lastAlertAt   time.Time
+	alertedBefore bool
+	// extremes holds the lowest and highest VWAPs within the duration of a KindChange rule
+	extremes extremes
+}
+
+// extremes keeps the lowest and highest VWAPs within a duration, as the largest change of a VWAP is from either
+// of them. Each is a monotonic deque, oldest first, so a VWAP is added and evicted once instead of every VWAP
+// within the duration being compared on every update.
+type extremes struct {
+	// lows are increasing, each being the lowest VWAP since the previous one
+	lows []model.VWAP
+	// highs are decreasing, each being the highest VWAP since the previous one
+	highs []model.VWAP
+}
+
+// evict drops the VWAPs older than within before at.
+func (x *extremes) evict(at time.Time, within time.Duration) {
+	for len(x.lows) > 0 && at.Sub(x.lows[0].LastTradeAt) > within {
+		x.lows = x.lows[1:]
+	}
+	for len(x.highs) > 0 && at.Sub(x.highs[0].LastTradeAt) > within {
+		x.highs = x.highs[1:]
+	}
+}
+
+// add adds the VWAP, dropping those it outlives without being lower or higher.
+func (x *extremes) add(vwap model.VWAP) {
+	for len(x.lows) > 0 && x.lows[len(x.lows)-1].VWAP.GreaterThanOrEqual(vwap.VWAP) {
+		x.lows = x.lows[:len(x.lows)-1]
+	}
+	x.lows = append(x.lows, vwap)
+
+	for len(x.highs) > 0 && x.highs[len(x.highs)-1].VWAP.LessThanOrEqual(vwap.VWAP) {
+		x.highs = x.highs[:len(x.highs)-1]
+	}
+	x.highs = append(x.highs, vwap)
+}
+
+func (e Engine) evaluateForever(in <-chan model.VWAP, out chan<- model.Alert) {
+	defer close(out)
+
+	states := make([]ruleState, len(e.rules))
+	for {
+		vwap, more := <-in
+		if !more {
+			log.Println("no more to read from the vwap channel")
+
+			break
+		}
+
+		for i, r := range e.rules {
+			if r.TradingPair != vwap.TradingPair {
+				continue
+			}
+
+			if alert, ok := e.evaluate(r, &states[i], vwap); ok {
+				out <- alert
+			}
+		}
+	}
+}
+
+func (e Engine) evaluate(r Rule, state *ruleState, vwap model.VWAP) (model.Alert, bool) {
+	value, ok := r.value(state, vwap)
+	if !ok {
+		return model.Alert{}, false
+	}
+
+	started := state.started
+	state.started = true
+	if !started && r.crossesLevel() {
+		// VWAP starting past the level has not crossed it
+		state.firing = r.breached(value)
+
+		return model.Alert{}, false
+	}
+
+	if state.firing {
+		if r.rearmed(value, e.hysteresis) {
+			state.firing = false
+		}
+
+		return model.Alert{}, false
+	}
+
+	if !r.breached(value) {
+		return model.Alert{}, false
+	}
+
+	// within the cooldown, the rule stays armed, so it alerts once the cooldown is over if still breached
+	if state.alertedBefore && vwap.LastTradeAt.Sub(state.lastAlertAt) < e.cooldown {
+		return model.Alert{}, false
+	}
+
+	state.firing = true
+	state.alertedBefore = true
+	state.lastAlertAt = vwap.LastTradeAt
+
+	if !r.crossesLevel() {
+		value = value.Round(percentPlaces)
+	}
+
+	return model.Alert{
+		TradingPair: vwap.TradingPair,
+		Rule:        r.String(),
+		Message:     r.message(value),
+		Value:       value,
+		Threshold:   r.Threshold,
+		VWAP:        vwap.VWAP,
+		LastTradeAt: vwap.LastTradeAt,
+	}, true
+}
+
+// value returns what the rule compares with its threshold, or false if it cannot be computed yet.
+func (r Rule) value(state *ruleState, vwap model.VWAP) (decimal.Decimal, bool) {
+	switch r.Kind {
+	case KindDeviation:
+		if vwap.VWAP.IsZero() {
+			return decimal.Zero, false
+		}
+
+		return relativeChange(vwap.VWAP, vwap.LastPrice), true
+
+	case KindChange:
+		return r.change(state, vwap)
+
+	default:
+		return vwap.VWAP, true
+	}
+}
+
+// change returns the largest change, in percent, between the VWAP and the VWAPs within the rule's duration.
+// Zero VWAPs are not kept, as there is no change from them.
+func (r Rule) change(state *ruleState, vwap model.VWAP) (decimal.Decimal, bool) {
+	x := &state.extremes
+	x.evict(vwap.LastTradeAt, r.Within)
+	defer func() {
+		if !vwap.VWAP.IsZero() {
+			x.add(vwap)
+		}
+	}()
+
+	if len(x.lows) == 0 {
+		return decimal.Zero, false
+	}
+
+	change := relativeChange(x.lows[0].VWAP, vwap.VWAP)
+	if c := relativeChange(x.highs[0].VWAP, vwap.VWAP); c.GreaterThan(change) {
+		change = c
+	}
+
+	return change, true
+}
+
+// relativeChange returns |to - from| / from in percent.
+func relativeChange(from, to decimal.Decimal) decimal.Decimal {
+	return to.Sub(from).Abs().Div(from.Abs()).Mul(hundred)
+}
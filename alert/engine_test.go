@@ -0,0 +1,197 @@
+package alert
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aprln/vwap-engine/config"
+	"github.com/aprln/vwap-engine/model"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var at = time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func TestSetUp(t *testing.T) {
+	vwapCfg := config.VWAP{TradingPairs: []string{"BTC-USD", "ETH-USD"}}
+	e, err := SetUp(config.Alert{Rules: []string{"BTC-USD:above@30000"}, Cooldown: time.Minute}, vwapCfg)
+	require.NoError(t, err)
+	assert.Equal(t, []Rule{{TradingPair: "BTC-USD", Kind: KindAbove, Threshold: decimal.NewFromInt(30000)}}, e.rules)
+
+	_, err = SetUp(config.Alert{Rules: []string{"banana"}}, vwapCfg)
+	require.Error(t, err)
+
+	_, err = SetUp(config.Alert{Rules: []string{"SOL-USD:above@30"}}, vwapCfg)
+	require.Error(t, err, "the trading pair is not streamed")
+}
+
+// update is a VWAP update of BTC-USD, secs seconds after at
+type update struct {
+	secs      int
+	vwap      string
+	lastPrice string
+}
+
+func TestEngine_GoEvaluate(t *testing.T) {
+	tests := []struct {
+		name       string
+		rule       string
+		cooldown   time.Duration
+		hysteresis string
+		updates    []update
+		// wantAlerts are the indexes of the updates raising an alert
+		wantAlerts []int
+		wantValues []string
+	}{
+		{
+			name:       "above",
+			rule:       "BTC-USD:above@100",
+			hysteresis: "0.01",
+			updates: []update{
+				{secs: 0, vwap: "99"},
+				{secs: 1, vwap: "101"},
+				// flapping within the hysteresis does not alert again
+				{secs: 2, vwap: "99.5"},
+				{secs: 3, vwap: "100.5"},
+				// back past the hysteresis re-arms the rule
+				{secs: 4, vwap: "98.9"},
+				{secs: 5, vwap: "100.1"},
+			},
+			wantAlerts: []int{1, 5},
+			wantValues: []string{"101", "100.1"},
+		},
+		{
+			name: "above from the start is not a cross",
+			rule: "BTC-USD:above@100",
+			updates: []update{
+				{secs: 0, vwap: "101"},
+				{secs: 1, vwap: "102"},
+				{secs: 2, vwap: "99"},
+				{secs: 3, vwap: "101"},
+			},
+			wantAlerts: []int{3},
+			wantValues: []string{"101"},
+		},
+		{
+			name: "below",
+			rule: "BTC-USD:below@100",
+			updates: []update{
+				{secs: 0, vwap: "101"},
+				{secs: 1, vwap: "100"},
+				{secs: 2, vwap: "99"},
+			},
+			wantAlerts: []int{2},
+			wantValues: []string{"99"},
+		},
+		{
+			name:     "cooldown",
+			rule:     "BTC-USD:below@100",
+			cooldown: 10 * time.Second,
+			updates: []update{
+				{secs: 0, vwap: "101"},
+				{secs: 1, vwap: "99"},
+				{secs: 2, vwap: "101"},
+				// re-armed but within the cooldown
+				{secs: 3, vwap: "99"},
+				// still breached once the cooldown is over
+				{secs: 11, vwap: "98"},
+			},
+			wantAlerts: []int{1, 4},
+			wantValues: []string{"99", "98"},
+		},
+		{
+			name: "deviation",
+			rule: "BTC-USD:deviation@0.5",
+			updates: []update{
+				{secs: 0, vwap: "100", lastPrice: "100.5"},
+				{secs: 1, vwap: "100", lastPrice: "99.4"},
+				{secs: 2, vwap: "100", lastPrice: "100"},
+				{secs: 3, vwap: "200", lastPrice: "201.5"},
+			},
+			wantAlerts: []int{1, 3},
+			wantValues: []string{"0.6", "0.75"},
+		},
+		{
+			name: "change",
+			rule: "BTC-USD:change@1/10s",
+			updates: []update{
+				{secs: 0, vwap: "100"},
+				{secs: 5, vwap: "100.5"},
+				// 1.5% from 100, 5s ago
+				{secs: 5, vwap: "101.5"},
+				// the rule re-arms once the change within 10s is back under the threshold
+				{secs: 30, vwap: "101"},
+				{secs: 31, vwap: "101"},
+				// 100 at 45s is more than 10s after the last values
+				{secs: 45, vwap: "100"},
+				{secs: 50, vwap: "101.01"},
+			},
+			wantAlerts: []int{2, 6},
+			wantValues: []string{"1.5", "1.01"},
+		},
+		{
+			name: "change from the extremes within the duration",
+			rule: "BTC-USD:change@5/10s",
+			updates: []update{
+				{secs: 0, vwap: "100"},
+				{secs: 4, vwap: "98"},
+				{secs: 8, vwap: "99"},
+				// 100 is evicted, 4% from 100 would not alert, 6.12% from 98 does
+				{secs: 11, vwap: "104"},
+				{secs: 17, vwap: "103"},
+				// only 103 is left within 10s, 99 and 104 being evicted
+				{secs: 27, vwap: "97.5"},
+			},
+			wantAlerts: []int{3, 5},
+			wantValues: []string{"6.1224", "5.3398"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				r, err := ParseRule(tt.rule)
+				require.NoError(t, err)
+				hysteresis := decimal.Zero
+				if tt.hysteresis != "" {
+					hysteresis = decimal.RequireFromString(tt.hysteresis)
+				}
+
+				in := make(chan model.VWAP, len(tt.updates)+1)
+				for _, u := range tt.updates {
+					lastPrice := u.lastPrice
+					if lastPrice == "" {
+						lastPrice = u.vwap
+					}
+					in <- model.VWAP{
+						TradingPair: "BTC-USD",
+						LastTradeAt: at.Add(time.Duration(u.secs) * time.Second),
+						VWAP:        decimal.RequireFromString(u.vwap),
+						LastPrice:   decimal.RequireFromString(lastPrice),
+					}
+				}
+				// rules of other trading pairs are ignored
+				in <- model.VWAP{TradingPair: "ETH-USD", LastTradeAt: at, VWAP: decimal.NewFromInt(1_000_000)}
+				close(in)
+
+				var gotAlerts []int
+				var gotValues []string
+				for alert := range New([]Rule{r}, tt.cooldown, hysteresis).GoEvaluate(in) {
+					assert.Equal(t, "BTC-USD", alert.TradingPair)
+					assert.Equal(t, tt.rule, alert.Rule)
+					assert.NotEmpty(t, alert.Message)
+					gotAlerts = append(gotAlerts, int(alert.LastTradeAt.Sub(at)/time.Second))
+					gotValues = append(gotValues, alert.Value.String())
+				}
+
+				wantSecs := make([]int, len(tt.wantAlerts))
+				for i, idx := range tt.wantAlerts {
+					wantSecs[i] = tt.updates[idx].secs
+				}
+				assert.Equal(t, wantSecs, gotAlerts)
+				assert.Equal(t, tt.wantValues, gotValues)
+			},
+		)
+	}
+}
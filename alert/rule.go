@@ -0,0 +1,129 @@
+package alert
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+type Kind string
+
+const (
+	// KindAbove alerts when VWAP crosses above a level.
+	KindAbove Kind = "above"
+	// KindBelow alerts when VWAP crosses below a level.
+	KindBelow Kind = "below"
+	// KindDeviation alerts when the last trade price deviates from VWAP by more than a percentage.
+	KindDeviation Kind = "deviation"
+	// KindChange alerts when VWAP changes by more than a percentage within a duration.
+	KindChange Kind = "change"
+)
+
+var hundred = decimal.NewFromInt(100)
+
+// Rule is an alerting rule on the VWAP updates of a trading pair.
+type Rule struct {
+	TradingPair string
+	Kind        Kind
+	// Threshold is the level of KindAbove and KindBelow rules, and the percentage of the other rules.
+	Threshold decimal.Decimal
+	// Within is the duration of KindChange rules.
+	Within time.Duration
+}
+
+// ParseRule parses "PAIR:above@LEVEL", "PAIR:below@LEVEL", "PAIR:deviation@PERCENT"
+// or "PAIR:change@PERCENT/DURATION", e.g. "BTC-USD:change@1/30s".
+func ParseRule(s string) (Rule, error) {
+	tradingPair, condition, ok := strings.Cut(s, ":")
+	if !ok || strings.TrimSpace(tradingPair) == "" {
+		return Rule{}, fmt.Errorf(`invalid alert rule "%s", want PAIR:KIND@THRESHOLD`, s)
+	}
+
+	kind, threshold, ok := strings.Cut(condition, "@")
+	if !ok {
+		return Rule{}, fmt.Errorf(`invalid alert rule "%s", want PAIR:KIND@THRESHOLD`, s)
+	}
+
+	r := Rule{TradingPair: strings.TrimSpace(tradingPair), Kind: Kind(kind)}
+
+	switch r.Kind {
+	case KindAbove, KindBelow, KindDeviation:
+
+	case KindChange:
+		var within string
+		threshold, within, ok = strings.Cut(threshold, "/")
+		if !ok {
+			return Rule{}, fmt.Errorf(`invalid alert rule "%s", want PAIR:change@PERCENT/DURATION`, s)
+		}
+
+		d, err := time.ParseDuration(within)
+		if err != nil || d <= 0 {
+			return Rule{}, fmt.Errorf(`invalid duration in alert rule "%s"`, s)
+		}
+		r.Within = d
+
+	default:
+		return Rule{}, fmt.Errorf(`alert rule kind "%s" is unsupported`, kind)
+	}
+
+	d, err := decimal.NewFromString(threshold)
+	if err != nil || !d.IsPositive() {
+		return Rule{}, fmt.Errorf(`invalid threshold in alert rule "%s"`, s)
+	}
+	r.Threshold = d
+
+	return r, nil
+}
+
+func (r Rule) String() string {
+	if r.Kind == KindChange {
+		return fmt.Sprintf("%s:%s@%s/%s", r.TradingPair, r.Kind, r.Threshold, r.Within)
+	}
+
+	return fmt.Sprintf("%s:%s@%s", r.TradingPair, r.Kind, r.Threshold)
+}
+
+// crossesLevel returns whether the rule alerts on VWAP crossing a level,
+// in which case the first update only tells which side of the level VWAP starts on.
+func (r Rule) crossesLevel() bool {
+	return r.Kind == KindAbove || r.Kind == KindBelow
+}
+
+// breached returns whether the value is past the threshold.
+func (r Rule) breached(value decimal.Decimal) bool {
+	if r.Kind == KindBelow {
+		return value.LessThan(r.Threshold)
+	}
+
+	return value.GreaterThan(r.Threshold)
+}
+
+// rearmed returns whether the value is back past the threshold by the hysteresis, a ratio of the threshold.
+func (r Rule) rearmed(value, hysteresis decimal.Decimal) bool {
+	band := r.Threshold.Mul(hysteresis)
+	if r.Kind == KindBelow {
+		return value.GreaterThanOrEqual(r.Threshold.Add(band))
+	}
+
+	return value.LessThanOrEqual(r.Threshold.Sub(band))
+}
+
+func (r Rule) message(value decimal.Decimal) string {
+	switch r.Kind {
+	case KindAbove:
+		return fmt.Sprintf("%s VWAP %s crossed above %s", r.TradingPair, value, r.Threshold)
+
+	case KindBelow:
+		return fmt.Sprintf("%s VWAP %s crossed below %s", r.TradingPair, value, r.Threshold)
+
+	case KindDeviation:
+		return fmt.Sprintf("%s last trade price deviates from VWAP by %s%%, over %s%%", r.TradingPair, value, r.Threshold)
+
+	default:
+		return fmt.Sprintf(
+			"%s VWAP changed by %s%% within %s, over %s%%", r.TradingPair, value, r.Within, r.Threshold,
+		)
+	}
+}
@@ -0,0 +1,63 @@
+package alert
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRule(t *testing.T) {
+	tests := []struct {
+		s       string
+		want    Rule
+		wantErr bool
+	}{
+		{
+			s:    "BTC-USD:above@30000",
+			want: Rule{TradingPair: "BTC-USD", Kind: KindAbove, Threshold: decimal.NewFromInt(30000)},
+		},
+		{
+			s:    "BTC-USD:below@25000.5",
+			want: Rule{TradingPair: "BTC-USD", Kind: KindBelow, Threshold: decimal.RequireFromString("25000.5")},
+		},
+		{
+			s:    "ETH-USD:deviation@0.5",
+			want: Rule{TradingPair: "ETH-USD", Kind: KindDeviation, Threshold: decimal.RequireFromString("0.5")},
+		},
+		{
+			s: "ETH-USD:change@1/30s",
+			want: Rule{
+				TradingPair: "ETH-USD", Kind: KindChange, Threshold: decimal.NewFromInt(1), Within: 30 * time.Second,
+			},
+		},
+		{s: "above@30000", wantErr: true},
+		{s: ":above@30000", wantErr: true},
+		{s: "BTC-USD:above", wantErr: true},
+		{s: "BTC-USD:above@-1", wantErr: true},
+		{s: "BTC-USD:above@banana", wantErr: true},
+		{s: "BTC-USD:change@1", wantErr: true},
+		{s: "BTC-USD:change@1/0s", wantErr: true},
+		{s: "BTC-USD:banana@1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.s, func(t *testing.T) {
+				got, err := ParseRule(tt.s)
+
+				if tt.wantErr {
+					require.Error(t, err)
+
+					return
+				}
+
+				require.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+				assert.Equal(t, tt.s, got.String())
+			},
+		)
+	}
+}
@@ -0,0 +1,301 @@
+package api
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aprln/vwap-engine/config"
+)
+
+const (
+	vwapPath          = "/v1/vwap"
+	streamPath        = "/v1/stream"
+	statsPath         = "/v1/stats"
+	adminPairsPath    = "/v1/admin/pairs"
+	resetAction       = "reset"
+	verifyAction      = "verify"
+	readHeaderTimeout = 10 * time.Second
+)
+
+// SetUp starts an HTTP server serving the latest VWAPs of the store, the counters of the stats and the stream
+// of VWAPs on the configured address, and the admin endpoints of the trading pairs if an admin token is set.
+func SetUp(
+	httpServerCfg config.HTTPServer, store *Store, stats *Stats, stream http.Handler, admin PairAdmin,
+) (*Server, error) {
+	ln, err := net.Listen("tcp", httpServerCfg.Addr)
+	if err != nil {
+		return nil, err
+	}
+
+	s := New(store, stats, httpServerCfg.StaleAfter, stream)
+	if httpServerCfg.AdminToken != "" {
+		s = s.WithAdmin(admin, httpServerCfg.AdminToken)
+	}
+	s.server = &http.Server{Handler: s.Handler(), ReadHeaderTimeout: readHeaderTimeout}
+
+	go func() {
+		if err := s.server.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("HTTP server error: %v", err)
+		}
+	}()
+
+	log.Printf("HTTP server listening on %s", ln.Addr())
+
+	return s, nil
+}
+
+// New creates a server. The stream handler, e.g. a publisher.SSESender, is optional.
+func New(store *Store, stats *Stats, staleAfter time.Duration, stream http.Handler) *Server {
+	return &Server{
+		store:      store,
+		stats:      stats,
+		staleAfter: staleAfter,
+		stream:     stream,
+	}
+}
+
+// PairAdmin starts and stops the pipelines of trading pairs, e.g. a supervisor.Supervisor.
+type PairAdmin interface {
+	Add(tradingPair string) error
+	Remove(tradingPair string) error
+	TradingPairs() []string
+	ResetSession(tradingPair string) error
+	Verify(tradingPair string) error
+}
+
+// WithAdmin returns a copy of the server also serving the admin endpoints, which require the bearer token.
+func (s *Server) WithAdmin(admin PairAdmin, token string) *Server {
+	c := *s
+	c.admin = admin
+	c.adminToken = token
+
+	return &c
+}
+
+// Server serves the latest VWAPs:
+//   - GET /v1/vwap lists the latest VWAP of every running trading pair.
+//   - GET /v1/vwap/{pair} returns the latest VWAP of the trading pair, 404 if it is not running,
+//     or 503 while it is warming up.
+//   - GET /v1/stream?pairs=BTC-USD,ETH-USD streams the VWAPs, if there is a stream handler.
+//   - GET /v1/stats returns the counters of every running trading pair.
+//
+// With an admin, it also serves the admin endpoints, which require the admin token as a bearer token:
+//   - GET /v1/admin/pairs lists the running trading pairs.
+//   - POST /v1/admin/pairs/{pair} starts the pipeline of the trading pair, once it is connected.
+//   - DELETE /v1/admin/pairs/{pair} stops the pipeline of the trading pair, once it is drained.
+//   - POST /v1/admin/pairs/{pair}/reset starts a new session of the trading pair with its next trade.
+//   - POST /v1/admin/pairs/{pair}/verify verifies the running totals of the trading pair before its next trade.
+//
+// They respond 409 if the trading pair cannot be changed, e.g. it is already running or not running.
+type Server struct {
+	store      *Store
+	stats      *Stats
+	staleAfter time.Duration
+	stream     http.Handler
+	admin      PairAdmin
+	adminToken string
+	server     *http.Server
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+type listResponse struct {
+	VWAPs []Latest `json:"vwaps"`
+}
+
+type statsResponse struct {
+	TradingPairs map[string]map[string]uint64 `json:"trading_pairs"`
+}
+
+type pairsResponse struct {
+	TradingPairs []string `json:"trading_pairs"`
+}
+
+type pairResponse struct {
+	TradingPair string `json:"trading_pair"`
+}
+
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(vwapPath, s.list)
+	mux.HandleFunc(vwapPath+"/", s.get)
+	mux.HandleFunc(statsPath, s.listStats)
+	if s.stream != nil {
+		mux.Handle(streamPath, s.stream)
+	}
+	if s.admin != nil {
+		mux.HandleFunc(adminPairsPath, s.listPairs)
+		mux.HandleFunc(adminPairsPath+"/", s.changePair)
+	}
+
+	return mux
+}
+
+// Close stops the server, if any.
+func (s *Server) Close() error {
+	if s.server == nil {
+		return nil
+	}
+
+	return s.server.Close()
+}
+
+func (s *Server) list(w http.ResponseWriter, r *http.Request) {
+	if !allowGet(w, r) {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, listResponse{VWAPs: s.store.All(s.staleAfter)})
+}
+
+func (s *Server) get(w http.ResponseWriter, r *http.Request) {
+	if !allowGet(w, r) {
+		return
+	}
+
+	tradingPair := strings.TrimPrefix(r.URL.Path, vwapPath+"/")
+	latest, ok := s.store.Latest(tradingPair, s.staleAfter)
+	if !ok {
+		writeJSON(w, http.StatusNotFound, errorResponse{Error: fmt.Sprintf(`trading pair "%s" is unknown`, tradingPair)})
+
+		return
+	}
+
+	if latest.WarmingUp {
+		w.Header().Set("Retry-After", "1")
+		writeJSON(w, http.StatusServiceUnavailable, latest)
+
+		return
+	}
+
+	writeJSON(w, http.StatusOK, latest)
+}
+
+func (s *Server) listStats(w http.ResponseWriter, r *http.Request) {
+	if !allowGet(w, r) {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, statsResponse{TradingPairs: s.stats.All()})
+}
+
+func (s *Server) listPairs(w http.ResponseWriter, r *http.Request) {
+	if !s.authorize(w, r) || !allowGet(w, r) {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, pairsResponse{TradingPairs: s.admin.TradingPairs()})
+}
+
+func (s *Server) changePair(w http.ResponseWriter, r *http.Request) {
+	if !s.authorize(w, r) {
+		return
+	}
+
+	tradingPair, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, adminPairsPath+"/"), "/")
+	if action != "" {
+		s.actOnPair(w, r, tradingPair, action)
+
+		return
+	}
+
+	var err error
+	switch r.Method {
+	case http.MethodPost:
+		err = s.admin.Add(tradingPair)
+	case http.MethodDelete:
+		err = s.admin.Remove(tradingPair)
+	default:
+		w.Header().Set("Allow", http.MethodPost+", "+http.MethodDelete)
+		writeJSON(w, http.StatusMethodNotAllowed, errorResponse{Error: fmt.Sprintf("method %s is not allowed", r.Method)})
+
+		return
+	}
+	if err != nil {
+		writeJSON(w, http.StatusConflict, errorResponse{Error: err.Error()})
+
+		return
+	}
+
+	writeJSON(w, http.StatusOK, pairsResponse{TradingPairs: s.admin.TradingPairs()})
+}
+
+// actOnPair runs the action on the running trading pair, which takes effect with its next trade.
+func (s *Server) actOnPair(w http.ResponseWriter, r *http.Request, tradingPair, action string) {
+	var act func(tradingPair string) error
+	switch action {
+	case resetAction:
+		act = s.admin.ResetSession
+	case verifyAction:
+		act = s.admin.Verify
+	default:
+		writeJSON(w, http.StatusNotFound, errorResponse{Error: fmt.Sprintf(`action "%s" is unknown`, action)})
+
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeJSON(w, http.StatusMethodNotAllowed, errorResponse{Error: fmt.Sprintf("method %s is not allowed", r.Method)})
+
+		return
+	}
+
+	if err := act(tradingPair); err != nil {
+		writeJSON(w, http.StatusConflict, errorResponse{Error: err.Error()})
+
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, pairResponse{TradingPair: tradingPair})
+}
+
+// authorize checks the request carries the admin token as a bearer token.
+func (s *Server) authorize(w http.ResponseWriter, r *http.Request) bool {
+	auth := r.Header.Get("Authorization")
+	token := strings.TrimPrefix(auth, "Bearer ")
+	if token != auth && subtle.ConstantTimeCompare([]byte(token), []byte(s.adminToken)) == 1 {
+		return true
+	}
+
+	w.Header().Set("WWW-Authenticate", "Bearer")
+	writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "invalid admin token"})
+
+	return false
+}
+
+func allowGet(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method == http.MethodGet {
+		return true
+	}
+
+	w.Header().Set("Allow", http.MethodGet)
+	writeJSON(w, http.StatusMethodNotAllowed, errorResponse{Error: fmt.Sprintf("method %s is not allowed", r.Method)})
+
+	return false
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		log.Printf("JSON marshal error in HTTP server: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if _, err := w.Write(body); err != nil {
+		log.Printf("HTTP write error: %v", err)
+	}
+}
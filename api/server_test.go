@@ -0,0 +1,429 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aprln/vwap-engine/model"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var now = time.Date(2022, 1, 1, 0, 0, 30, 0, time.UTC)
+
+func newVWAP(tradingPair string, lastTradeAt time.Time, full bool) model.VWAP {
+	return model.VWAP{
+		TradingPair: tradingPair,
+		LastTradeAt: lastTradeAt,
+		VWAP:        decimal.NewFromInt(100),
+		Window:      &model.Window{TradeCount: 3, Full: full},
+	}
+}
+
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+func (c *fakeClock) Set(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = now
+}
+
+// storeAll feeds the VWAPs to the store and waits until the last one is stored.
+func storeAll(t *testing.T, store *Store, tradingPair string, vwaps ...model.VWAP) (closeFn func()) {
+	t.Helper()
+
+	ch := make(chan model.VWAP, len(vwaps))
+	var wg sync.WaitGroup
+	wg.Add(1)
+	store.GoStore(tradingPair, ch, nil, &wg)
+
+	for _, vwap := range vwaps {
+		ch <- vwap
+	}
+	if len(vwaps) > 0 {
+		last := vwaps[len(vwaps)-1]
+		assert.Eventually(
+			t, func() bool {
+				latest, ok := store.Latest(tradingPair, 0)
+
+				return ok && latest.VWAP != nil && latest.VWAP.LastTradeAt.Equal(last.LastTradeAt)
+			}, time.Second, time.Millisecond,
+		)
+	}
+
+	return func() {
+		close(ch)
+		wg.Wait()
+	}
+}
+
+func TestServer(t *testing.T) {
+	receivedAt := now.Add(-20 * time.Second)
+	clock := &fakeClock{now: receivedAt}
+	store := NewStore(clock.Now)
+
+	closeBTC := storeAll(t, store, "BTC-USD", newVWAP("BTC-USD", receivedAt.Add(-time.Second), true))
+	defer closeBTC()
+	closeETH := storeAll(t, store, "ETH-USD", newVWAP("ETH-USD", receivedAt, false))
+	defer closeETH()
+	closeSOL := storeAll(t, store, "SOL-USD")
+	clock.Set(now)
+
+	stats := NewStats()
+	stats.Register("BTC-USD", "calculator_resyncs", func() uint64 { return 2 })
+	stats.Register("ETH-USD", "calculator_resyncs", func() uint64 { return 0 })
+	stats.Forget("ETH-USD")
+
+	svr := httptest.NewServer(New(store, stats, 10*time.Second, nil).Handler())
+	defer svr.Close()
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "latest",
+			path:       "/v1/vwap/BTC-USD",
+			wantStatus: http.StatusOK,
+			wantBody: `{"trading_pair":"BTC-USD","warming_up":false,"stale":true,
+				"received_at":"2022-01-01T00:00:10Z","age_ms":20000,"last_trade_age_ms":21000,
+				"vwap":{"trading_pair":"BTC-USD","last_trade_at":"2022-01-01T00:00:09Z","vwap":"100",
+					"window":{"trade_count":3,"full":true,"volume":"0","notional":"0",
+						"oldest_trade_at":"0001-01-01T00:00:00Z","newest_trade_at":"0001-01-01T00:00:00Z"}}}`,
+		},
+		{
+			name:       "window not full",
+			path:       "/v1/vwap/ETH-USD",
+			wantStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:       "no vwap yet",
+			path:       "/v1/vwap/SOL-USD",
+			wantStatus: http.StatusServiceUnavailable,
+			wantBody: `{"trading_pair":"SOL-USD","vwap":null,"warming_up":true,
+				"age_ms":0,"last_trade_age_ms":0,"stale":false}`,
+		},
+		{
+			name:       "unknown",
+			path:       "/v1/vwap/DOGE-USD",
+			wantStatus: http.StatusNotFound,
+			wantBody:   `{"error":"trading pair \"DOGE-USD\" is unknown"}`,
+		},
+		{
+			name:       "stats",
+			path:       "/v1/stats",
+			wantStatus: http.StatusOK,
+			wantBody:   `{"trading_pairs":{"BTC-USD":{"calculator_resyncs":2}}}`,
+		},
+		{
+			name:       "not allowed",
+			method:     http.MethodPost,
+			path:       "/v1/vwap/BTC-USD",
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				method := tt.method
+				if method == "" {
+					method = http.MethodGet
+				}
+				req, err := http.NewRequest(method, svr.URL+tt.path, nil)
+				require.NoError(t, err)
+
+				resp, err := http.DefaultClient.Do(req)
+				require.NoError(t, err)
+				defer resp.Body.Close()
+				body, err := io.ReadAll(resp.Body)
+				require.NoError(t, err)
+
+				assert.Equal(t, tt.wantStatus, resp.StatusCode)
+				assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+				if tt.wantBody != "" {
+					assert.JSONEq(t, tt.wantBody, string(body))
+				}
+			},
+		)
+	}
+
+	t.Run(
+		"list", func(t *testing.T) {
+			resp, err := http.Get(svr.URL + "/v1/vwap")
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			var got listResponse
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&got))
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+			require.Len(t, got.VWAPs, 3)
+			assert.Equal(t, "BTC-USD", got.VWAPs[0].TradingPair)
+			assert.Equal(t, "ETH-USD", got.VWAPs[1].TradingPair)
+			assert.True(t, got.VWAPs[1].WarmingUp)
+			assert.Equal(t, "SOL-USD", got.VWAPs[2].TradingPair)
+		},
+	)
+
+	t.Run(
+		"stopped trading pair", func(t *testing.T) {
+			closeSOL()
+
+			resp, err := http.Get(svr.URL + "/v1/vwap/SOL-USD")
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+		},
+	)
+}
+
+// fakeAdmin runs the trading pairs it is asked to, rejecting those already running or not running.
+type fakeAdmin struct {
+	mu           sync.Mutex
+	tradingPairs map[string]bool
+	resets       []string
+	verifies     []string
+}
+
+func (a *fakeAdmin) Add(tradingPair string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.tradingPairs[tradingPair] {
+		return fmt.Errorf(`trading pair "%s" is already running`, tradingPair)
+	}
+	a.tradingPairs[tradingPair] = true
+
+	return nil
+}
+
+func (a *fakeAdmin) Remove(tradingPair string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if !a.tradingPairs[tradingPair] {
+		return fmt.Errorf(`trading pair "%s" is not running`, tradingPair)
+	}
+	delete(a.tradingPairs, tradingPair)
+
+	return nil
+}
+
+func (a *fakeAdmin) ResetSession(tradingPair string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if !a.tradingPairs[tradingPair] {
+		return fmt.Errorf(`trading pair "%s" is not running`, tradingPair)
+	}
+	a.resets = append(a.resets, tradingPair)
+
+	return nil
+}
+
+func (a *fakeAdmin) Verify(tradingPair string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if !a.tradingPairs[tradingPair] {
+		return fmt.Errorf(`trading pair "%s" is not running`, tradingPair)
+	}
+	a.verifies = append(a.verifies, tradingPair)
+
+	return nil
+}
+
+func (a *fakeAdmin) TradingPairs() []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	tradingPairs := make([]string, 0, len(a.tradingPairs))
+	for tradingPair := range a.tradingPairs {
+		tradingPairs = append(tradingPairs, tradingPair)
+	}
+	sort.Strings(tradingPairs)
+
+	return tradingPairs
+}
+
+func TestServer_admin(t *testing.T) {
+	admin := &fakeAdmin{tradingPairs: map[string]bool{"BTC-USD": true}}
+	svr := httptest.NewServer(New(NewStore(time.Now), NewStats(), 0, nil).WithAdmin(admin, "token").Handler())
+	defer svr.Close()
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		token      string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "list",
+			method:     http.MethodGet,
+			path:       "/v1/admin/pairs",
+			token:      "token",
+			wantStatus: http.StatusOK,
+			wantBody:   `{"trading_pairs":["BTC-USD"]}`,
+		},
+		{
+			name:       "no token",
+			method:     http.MethodPost,
+			path:       "/v1/admin/pairs/ETH-USD",
+			wantStatus: http.StatusUnauthorized,
+			wantBody:   `{"error":"invalid admin token"}`,
+		},
+		{
+			name:       "invalid token",
+			method:     http.MethodGet,
+			path:       "/v1/admin/pairs",
+			token:      "other",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "add",
+			method:     http.MethodPost,
+			path:       "/v1/admin/pairs/ETH-USD",
+			token:      "token",
+			wantStatus: http.StatusOK,
+			wantBody:   `{"trading_pairs":["BTC-USD","ETH-USD"]}`,
+		},
+		{
+			name:       "add running",
+			method:     http.MethodPost,
+			path:       "/v1/admin/pairs/ETH-USD",
+			token:      "token",
+			wantStatus: http.StatusConflict,
+			wantBody:   `{"error":"trading pair \"ETH-USD\" is already running"}`,
+		},
+		{
+			name:       "remove",
+			method:     http.MethodDelete,
+			path:       "/v1/admin/pairs/BTC-USD",
+			token:      "token",
+			wantStatus: http.StatusOK,
+			wantBody:   `{"trading_pairs":["ETH-USD"]}`,
+		},
+		{
+			name:       "remove not running",
+			method:     http.MethodDelete,
+			path:       "/v1/admin/pairs/BTC-USD",
+			token:      "token",
+			wantStatus: http.StatusConflict,
+		},
+		{
+			name:       "not allowed",
+			method:     http.MethodPut,
+			path:       "/v1/admin/pairs/BTC-USD",
+			token:      "token",
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+		{
+			name:       "reset",
+			method:     http.MethodPost,
+			path:       "/v1/admin/pairs/ETH-USD/reset",
+			token:      "token",
+			wantStatus: http.StatusAccepted,
+			wantBody:   `{"trading_pair":"ETH-USD"}`,
+		},
+		{
+			name:       "reset not running",
+			method:     http.MethodPost,
+			path:       "/v1/admin/pairs/BTC-USD/reset",
+			token:      "token",
+			wantStatus: http.StatusConflict,
+		},
+		{
+			name:       "reset not allowed",
+			method:     http.MethodGet,
+			path:       "/v1/admin/pairs/ETH-USD/reset",
+			token:      "token",
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+		{
+			name:       "verify",
+			method:     http.MethodPost,
+			path:       "/v1/admin/pairs/ETH-USD/verify",
+			token:      "token",
+			wantStatus: http.StatusAccepted,
+			wantBody:   `{"trading_pair":"ETH-USD"}`,
+		},
+		{
+			name:       "verify not running",
+			method:     http.MethodPost,
+			path:       "/v1/admin/pairs/BTC-USD/verify",
+			token:      "token",
+			wantStatus: http.StatusConflict,
+		},
+		{
+			name:       "unknown action",
+			method:     http.MethodPost,
+			path:       "/v1/admin/pairs/ETH-USD/restart",
+			token:      "token",
+			wantStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				req, err := http.NewRequest(tt.method, svr.URL+tt.path, nil)
+				require.NoError(t, err)
+				if tt.token != "" {
+					req.Header.Set("Authorization", "Bearer "+tt.token)
+				}
+
+				resp, err := http.DefaultClient.Do(req)
+				require.NoError(t, err)
+				defer resp.Body.Close()
+				body, err := io.ReadAll(resp.Body)
+				require.NoError(t, err)
+
+				assert.Equal(t, tt.wantStatus, resp.StatusCode)
+				if tt.wantBody != "" {
+					assert.JSONEq(t, tt.wantBody, string(body))
+				}
+			},
+		)
+	}
+
+	assert.Equal(t, []string{"ETH-USD"}, admin.resets)
+	assert.Equal(t, []string{"ETH-USD"}, admin.verifies)
+
+	t.Run(
+		"disabled", func(t *testing.T) {
+			svr := httptest.NewServer(New(NewStore(time.Now), NewStats(), 0, nil).Handler())
+			defer svr.Close()
+
+			resp, err := http.Get(svr.URL + "/v1/admin/pairs")
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+		},
+	)
+}
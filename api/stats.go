@@ -0,0 +1,58 @@
+package api
+
+import (
+	"sync"
+)
+
+// Counter returns the current value of a counter, e.g. the VWAP results dropped by a buffer.
+// It must be safe to call from other goroutines.
+type Counter func() uint64
+
+func NewStats() *Stats {
+	return &Stats{counters: make(map[string]map[string]Counter)}
+}
+
+// Stats keeps the counters of every running trading pair for the HTTP API. The counters are only read when
+// requested. It is safe for concurrent use.
+type Stats struct {
+	mu       sync.RWMutex
+	counters map[string]map[string]Counter
+}
+
+// Register adds a counter of the trading pair, replacing the one with the same name if any.
+func (s *Stats) Register(tradingPair, name string, counter Counter) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counters, ok := s.counters[tradingPair]
+	if !ok {
+		counters = make(map[string]Counter)
+		s.counters[tradingPair] = counters
+	}
+	counters[name] = counter
+}
+
+// Forget removes the counters of the trading pair, once its pipeline is done.
+func (s *Stats) Forget(tradingPair string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.counters, tradingPair)
+}
+
+// All returns the value of every counter by trading pair, then by name.
+func (s *Stats) All() map[string]map[string]uint64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	all := make(map[string]map[string]uint64, len(s.counters))
+	for tradingPair, counters := range s.counters {
+		values := make(map[string]uint64, len(counters))
+		for name, counter := range counters {
+			values[name] = counter()
+		}
+		all[tradingPair] = values
+	}
+
+	return all
+}
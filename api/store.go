@@ -0,0 +1,137 @@
+package api
+
+import (
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aprln/vwap-engine/model"
+)
+
+func NewStore(now func() time.Time) *Store {
+	return &Store{
+		now:     now,
+		entries: make(map[string]*entry),
+	}
+}
+
+// Store keeps the latest VWAP of every running trading pair for the HTTP API.
+// The processors and calculators are not safe to read from other goroutines, so the store is fed by its own
+// stage at the end of each pipeline. It is safe for concurrent use.
+type Store struct {
+	now func() time.Time
+
+	mu      sync.RWMutex
+	entries map[string]*entry
+}
+
+type entry struct {
+	// vwap is nil until the first VWAP of the trading pair is received
+	vwap       *model.VWAP
+	receivedAt time.Time
+	// round is applied when the VWAP is served, if any
+	round func(vwap model.VWAP) model.VWAP
+}
+
+// Latest is the latest VWAP of a trading pair along with its staleness.
+type Latest struct {
+	TradingPair string      `json:"trading_pair"`
+	VWAP        *model.VWAP `json:"vwap"`
+	// WarmingUp is true until the trading pair has a VWAP over a full window.
+	// Calculators without window are warmed up from their first VWAP.
+	WarmingUp  bool       `json:"warming_up"`
+	ReceivedAt *time.Time `json:"received_at,omitempty"`
+	// AgeMs is the time since the VWAP was received, and LastTradeAgeMs the time since its last trade.
+	AgeMs          int64 `json:"age_ms"`
+	LastTradeAgeMs int64 `json:"last_trade_age_ms"`
+	Stale          bool  `json:"stale"`
+}
+
+// GoStore keeps the latest VWAP read from ch. The trading pair is known to the store from the start,
+// so it is warming up rather than unknown until its first VWAP, and it is forgotten once ch is closed.
+// The VWAP is kept at full precision and rounded by round, if not nil, when it is served.
+func (s *Store) GoStore(
+	tradingPair string, ch <-chan model.VWAP, round func(vwap model.VWAP) model.VWAP, wg *sync.WaitGroup,
+) {
+	s.mu.Lock()
+	s.entries[tradingPair] = &entry{round: round}
+	s.mu.Unlock()
+
+	go s.storeForever(tradingPair, ch, round, wg)
+}
+
+func (s *Store) storeForever(
+	tradingPair string, ch <-chan model.VWAP, round func(vwap model.VWAP) model.VWAP, wg *sync.WaitGroup,
+) {
+	defer wg.Done()
+
+	for {
+		vwap, more := <-ch
+		if !more {
+			log.Println("no more to read from the vwap channel")
+
+			break
+		}
+
+		s.mu.Lock()
+		s.entries[tradingPair] = &entry{vwap: &vwap, receivedAt: s.now(), round: round}
+		s.mu.Unlock()
+	}
+
+	s.mu.Lock()
+	delete(s.entries, tradingPair)
+	s.mu.Unlock()
+}
+
+// Latest returns the latest VWAP of the trading pair, or false if the trading pair is not running.
+func (s *Store) Latest(tradingPair string, staleAfter time.Duration) (Latest, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	e, ok := s.entries[tradingPair]
+	if !ok {
+		return Latest{}, false
+	}
+
+	return s.latest(tradingPair, e, staleAfter), true
+}
+
+// All returns the latest VWAP of every running trading pair, sorted by trading pair.
+func (s *Store) All(staleAfter time.Duration) []Latest {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	all := make([]Latest, 0, len(s.entries))
+	for tradingPair, e := range s.entries {
+		all = append(all, s.latest(tradingPair, e, staleAfter))
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].TradingPair < all[j].TradingPair })
+
+	return all
+}
+
+func (s *Store) latest(tradingPair string, e *entry, staleAfter time.Duration) Latest {
+	if e.vwap == nil {
+		return Latest{TradingPair: tradingPair, WarmingUp: true}
+	}
+
+	now := s.now()
+	age := now.Sub(e.receivedAt)
+	receivedAt := e.receivedAt
+	vwap := e.vwap
+	if e.round != nil {
+		rounded := e.round(*vwap)
+		vwap = &rounded
+	}
+
+	return Latest{
+		TradingPair:    tradingPair,
+		VWAP:           vwap,
+		WarmingUp:      e.vwap.Window != nil && !e.vwap.Window.Full,
+		ReceivedAt:     &receivedAt,
+		AgeMs:          age.Milliseconds(),
+		LastTradeAgeMs: now.Sub(e.vwap.LastTradeAt).Milliseconds(),
+		Stale:          staleAfter > 0 && age > staleAfter,
+	}
+}
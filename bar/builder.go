@@ -0,0 +1,269 @@
+package bar
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/aprln/vwap-engine/config"
+	"github.com/aprln/vwap-engine/model"
+	"github.com/shopspring/decimal"
+)
+
+func SetUp(barCfg config.Bar) (Builder, error) {
+	return New(barCfg, time.Now)
+}
+
+func New(barCfg config.Bar, now func() time.Time) (Builder, error) {
+	if !barCfg.Enabled() {
+		return Builder{}, fmt.Errorf("no bar intervals configured")
+	}
+
+	for _, interval := range barCfg.Intervals {
+		if interval <= 0 {
+			return Builder{}, fmt.Errorf("invalid bar interval %s", interval)
+		}
+	}
+
+	return Builder{barCfg: barCfg, now: now}, nil
+}
+
+// Builder aggregates a trade stream into OHLCV bars.
+// Bars are bucketed by trade time and emitted once a trade at or after the bar's close time arrives, or once the
+// clock is past the close time by the close delay, whichever comes first. A trade arriving for a bar already
+// closed by the clock is dropped as late. The open bars are emitted as partial when the trade stream ends.
+type Builder struct {
+	barCfg config.Bar
+	now    func() time.Time
+}
+
+func (b Builder) GoBuild(in <-chan model.Trade) chan model.Bar {
+	out := make(chan model.Bar, 1)
+
+	go b.buildForever(in, out)
+
+	return out
+}
+
+func (b Builder) buildForever(in <-chan model.Trade, out chan<- model.Bar) {
+	defer close(out)
+
+	bars := make([]*barState, len(b.barCfg.Intervals))
+	for i, interval := range b.barCfg.Intervals {
+		bars[i] = &barState{interval: interval}
+	}
+
+	// the timer fires once the clock is past the earliest close time of the open bars by the close delay
+	var timer *time.Timer
+	var deadline time.Time
+	defer func() {
+		if timer != nil {
+			timer.Stop()
+		}
+	}()
+
+	for {
+		var timeout <-chan time.Time
+		if closeTime, ok := earliestClose(bars); ok {
+			if next := closeTime.Add(b.barCfg.CloseDelay); timer == nil || !next.Equal(deadline) {
+				if timer != nil {
+					timer.Stop()
+				}
+				deadline = next
+				timer = time.NewTimer(deadline.Sub(b.now()))
+			}
+			timeout = timer.C
+		}
+
+		select {
+		case trade, more := <-in:
+			if !more {
+				log.Println("no more to read from the trade channel")
+
+				for _, bs := range bars {
+					if partial, ok := bs.flush(); ok {
+						out <- partial
+					}
+				}
+
+				return
+			}
+
+			for _, bs := range bars {
+				for _, closed := range bs.add(trade, b.barCfg.EmitEmpty) {
+					out <- closed
+				}
+			}
+
+		case <-timeout:
+			// the timer is renewed on the next iteration, even if the deadline is unchanged
+			timer = nil
+			cutoff := b.now().Add(-b.barCfg.CloseDelay)
+			for _, bs := range bars {
+				for _, closed := range bs.closeUntil(cutoff, b.barCfg.EmitEmpty) {
+					out <- closed
+				}
+			}
+		}
+	}
+}
+
+// earliestClose returns the earliest close time of the open bars, if any is open.
+func earliestClose(bars []*barState) (time.Time, bool) {
+	var earliest time.Time
+	found := false
+	for _, bs := range bars {
+		if bs.open && (!found || bs.bar.CloseTime.Before(earliest)) {
+			earliest = bs.bar.CloseTime
+			found = true
+		}
+	}
+
+	return earliest, found
+}
+
+type barState struct {
+	interval   time.Duration
+	open       bool
+	bar        model.Bar
+	totalValue decimal.Decimal
+	// closedUntil is the close time of the last bar closed by the clock, the trades before it are late
+	closedUntil time.Time
+}
+
+// add folds the trade into the current bar and returns any bars closed by it.
+func (s *barState) add(trade model.Trade, emitEmpty bool) []model.Bar {
+	openTime := trade.Time.Truncate(s.interval)
+
+	if !s.open {
+		if trade.Time.Before(s.closedUntil) {
+			log.Printf(
+				"dropping late %s trade at %s for %s bar closed at %s",
+				trade.TradingPair, trade.Time, s.interval, s.closedUntil,
+			)
+
+			return nil
+		}
+
+		s.start(trade.TradingPair, openTime, decimal.Zero)
+		s.update(trade)
+
+		return nil
+	}
+
+	if trade.Time.Before(s.bar.OpenTime) {
+		log.Printf(
+			"dropping late %s trade at %s for %s bar opened at %s",
+			trade.TradingPair, trade.Time, s.bar.Interval, s.bar.OpenTime,
+		)
+
+		return nil
+	}
+
+	if trade.Time.Before(s.bar.CloseTime) {
+		s.update(trade)
+
+		return nil
+	}
+
+	closed := []model.Bar{s.bar}
+	lastClose := s.bar.Close
+
+	if emitEmpty {
+		for t := s.bar.CloseTime; t.Before(openTime); t = t.Add(s.interval) {
+			s.start(trade.TradingPair, t, lastClose)
+			closed = append(closed, s.bar)
+		}
+	}
+
+	s.start(trade.TradingPair, openTime, decimal.Zero)
+	s.update(trade)
+
+	return closed
+}
+
+// closeUntil closes the open bar if its close time is at or before the cutoff, and returns the bars closed.
+// With emitEmpty, the intervals without trades up to the cutoff are closed as empty bars, and an empty bar carrying
+// the last close is left open for the next trade.
+func (s *barState) closeUntil(cutoff time.Time, emitEmpty bool) []model.Bar {
+	if !s.open || s.bar.CloseTime.After(cutoff) {
+		return nil
+	}
+
+	// an empty bar is only opened with emitEmpty, so an open bar without trades is always emitted
+	closed := []model.Bar{s.bar}
+	if !emitEmpty {
+		s.open = false
+		s.closedUntil = s.bar.CloseTime
+
+		return closed
+	}
+
+	lastClose := s.bar.Close
+	for {
+		s.start(s.bar.TradingPair, s.bar.CloseTime, lastClose)
+		if s.bar.CloseTime.After(cutoff) {
+			return closed
+		}
+		closed = append(closed, s.bar)
+	}
+}
+
+// flush returns the open bar as partial, unless it has no trades.
+func (s *barState) flush() (model.Bar, bool) {
+	if !s.open || s.bar.TradeCount == 0 {
+		return model.Bar{}, false
+	}
+
+	partial := s.bar
+	partial.Partial = true
+
+	return partial, true
+}
+
+// start opens an empty bar whose OHLC values all equal price.
+func (s *barState) start(tradingPair string, openTime time.Time, price decimal.Decimal) {
+	s.open = true
+	s.totalValue = decimal.Zero
+	s.bar = model.Bar{
+		TradingPair: tradingPair,
+		Interval:    s.interval.String(),
+		OpenTime:    openTime,
+		CloseTime:   openTime.Add(s.interval),
+		Open:        price,
+		High:        price,
+		Low:         price,
+		Close:       price,
+		Volume:      decimal.Zero,
+		VWAP:        decimal.Zero,
+	}
+}
+
+func (s *barState) update(trade model.Trade) {
+	if s.bar.TradeCount == 0 {
+		s.bar.Open = trade.Price
+		s.bar.High = trade.Price
+		s.bar.Low = trade.Price
+	}
+
+	if trade.Price.GreaterThan(s.bar.High) {
+		s.bar.High = trade.Price
+	}
+
+	if trade.Price.LessThan(s.bar.Low) {
+		s.bar.Low = trade.Price
+	}
+
+	s.bar.Close = trade.Price
+	s.bar.Volume = s.bar.Volume.Add(trade.Size)
+	s.bar.TradeCount++
+	s.totalValue = s.totalValue.Add(trade.Price.Mul(trade.Size))
+
+	if s.bar.Volume.IsZero() {
+		s.bar.VWAP = decimal.Zero
+
+		return
+	}
+
+	s.bar.VWAP = s.totalValue.Div(s.bar.Volume)
+}
@@ -0,0 +1,232 @@
+package bar
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aprln/vwap-engine/config"
+	"github.com/aprln/vwap-engine/model"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	_, err := New(config.Bar{}, time.Now)
+	require.Error(t, err)
+
+	_, err = New(config.Bar{Intervals: []time.Duration{-time.Second}}, time.Now)
+	require.Error(t, err)
+
+	_, err = New(config.Bar{Intervals: []time.Duration{time.Minute}}, time.Now)
+	require.NoError(t, err)
+}
+
+func TestBuilder_GoBuild(t *testing.T) {
+	base := time.Date(2022, 11, 2, 14, 27, 0, 0, time.UTC)
+	trades := []model.Trade{
+		newTrade(base.Add(1*time.Second), "10", "1"),
+		newTrade(base.Add(20*time.Second), "12", "3"),
+		newTrade(base.Add(40*time.Second), "9", "1"),
+		newTrade(base.Add(59*time.Second), "11", "5"),
+		// closes the first bar and skips one whole minute
+		newTrade(base.Add(2*time.Minute+5*time.Second), "13", "2"),
+		// closes the second bar
+		newTrade(base.Add(3*time.Minute), "14", "1"),
+	}
+
+	firstBar := model.Bar{
+		TradingPair: "BTC-USD",
+		Interval:    "1m0s",
+		OpenTime:    base,
+		CloseTime:   base.Add(time.Minute),
+		Open:        decimal.RequireFromString("10"),
+		High:        decimal.RequireFromString("12"),
+		Low:         decimal.RequireFromString("9"),
+		Close:       decimal.RequireFromString("11"),
+		Volume:      decimal.RequireFromString("10"),
+		TradeCount:  4,
+		VWAP:        decimal.RequireFromString("11"),
+	}
+	emptyBar := model.Bar{
+		TradingPair: "BTC-USD",
+		Interval:    "1m0s",
+		OpenTime:    base.Add(time.Minute),
+		CloseTime:   base.Add(2 * time.Minute),
+		Open:        decimal.RequireFromString("11"),
+		High:        decimal.RequireFromString("11"),
+		Low:         decimal.RequireFromString("11"),
+		Close:       decimal.RequireFromString("11"),
+		Volume:      decimal.Zero,
+		VWAP:        decimal.Zero,
+	}
+	lastBar := model.Bar{
+		TradingPair: "BTC-USD",
+		Interval:    "1m0s",
+		OpenTime:    base.Add(2 * time.Minute),
+		CloseTime:   base.Add(3 * time.Minute),
+		Open:        decimal.RequireFromString("13"),
+		High:        decimal.RequireFromString("13"),
+		Low:         decimal.RequireFromString("13"),
+		Close:       decimal.RequireFromString("13"),
+		Volume:      decimal.RequireFromString("2"),
+		TradeCount:  1,
+		VWAP:        decimal.RequireFromString("13"),
+	}
+	partialBar := model.Bar{
+		TradingPair: "BTC-USD",
+		Interval:    "1m0s",
+		OpenTime:    base.Add(3 * time.Minute),
+		CloseTime:   base.Add(4 * time.Minute),
+		Open:        decimal.RequireFromString("14"),
+		High:        decimal.RequireFromString("14"),
+		Low:         decimal.RequireFromString("14"),
+		Close:       decimal.RequireFromString("14"),
+		Volume:      decimal.RequireFromString("1"),
+		TradeCount:  1,
+		VWAP:        decimal.RequireFromString("14"),
+		Partial:     true,
+	}
+
+	tests := []struct {
+		name      string
+		emitEmpty bool
+		want      []model.Bar
+	}{
+		{
+			name: "without empty bars",
+			want: []model.Bar{firstBar, lastBar, partialBar},
+		},
+		{
+			name:      "with empty bars",
+			emitEmpty: true,
+			want:      []model.Bar{firstBar, emptyBar, lastBar, partialBar},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				// the clock stays before the close of the bars, so they are only closed by the trades
+				b, err := New(
+					config.Bar{Intervals: []time.Duration{time.Minute}, EmitEmpty: tt.emitEmpty}, func() time.Time { return base },
+				)
+				require.NoError(t, err)
+
+				in := make(chan model.Trade, len(trades))
+				for _, trade := range trades {
+					in <- trade
+				}
+				close(in)
+
+				var got []model.Bar
+				for bar := range b.GoBuild(in) {
+					got = append(got, bar)
+				}
+
+				require.Len(t, got, len(tt.want))
+				for i := range tt.want {
+					assertBarEqual(t, tt.want[i], got[i])
+				}
+			},
+		)
+	}
+}
+
+func TestBuilder_GoBuild_dropsLateTrades(t *testing.T) {
+	base := time.Date(2022, 11, 2, 14, 27, 0, 0, time.UTC)
+	b, err := New(config.Bar{Intervals: []time.Duration{time.Second}}, func() time.Time { return base })
+	require.NoError(t, err)
+
+	in := make(chan model.Trade, 4)
+	in <- newTrade(base.Add(2*time.Second), "10", "1")
+	in <- newTrade(base, "100", "1")
+	in <- newTrade(base.Add(3*time.Second), "11", "1")
+	close(in)
+
+	var got []model.Bar
+	for bar := range b.GoBuild(in) {
+		got = append(got, bar)
+	}
+
+	require.Len(t, got, 2)
+	assert.Equal(t, 1, got[0].TradeCount)
+	assert.Equal(t, "10", got[0].High.String())
+	assert.True(t, got[1].Partial, "the open bar is flushed when the trades end")
+}
+
+func TestBuilder_GoBuild_closesOnClock(t *testing.T) {
+	tests := []struct {
+		name      string
+		emitEmpty bool
+	}{
+		{name: "without empty bars"},
+		{name: "with empty bars", emitEmpty: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				interval := 20 * time.Millisecond
+				b, err := New(config.Bar{Intervals: []time.Duration{interval}, EmitEmpty: tt.emitEmpty}, time.Now)
+				require.NoError(t, err)
+
+				in := make(chan model.Trade)
+				out := b.GoBuild(in)
+				first := newTrade(time.Now(), "10", "1")
+				in <- first
+
+				select {
+				case bar := <-out:
+					assert.Equal(t, 1, bar.TradeCount)
+					assert.False(t, bar.Partial)
+					assert.Equal(t, first.Time.Truncate(interval), bar.OpenTime)
+				case <-time.After(time.Second):
+					require.Fail(t, "the bar is not closed by the clock")
+				}
+
+				if tt.emitEmpty {
+					select {
+					case bar := <-out:
+						assert.Zero(t, bar.TradeCount, "the next interval is closed as empty")
+						assert.Equal(t, "10", bar.Close.String())
+					case <-time.After(time.Second):
+						require.Fail(t, "the empty bar is not closed by the clock")
+					}
+				}
+
+				// a trade of the bar closed by the clock is late
+				in <- newTrade(first.Time, "100", "1")
+				close(in)
+				for bar := range out {
+					assert.NotEqual(t, "100", bar.High.String())
+				}
+			},
+		)
+	}
+}
+
+func newTrade(at time.Time, price, size string) model.Trade {
+	return model.Trade{
+		TradingPair: "BTC-USD",
+		Price:       decimal.RequireFromString(price),
+		Size:        decimal.RequireFromString(size),
+		Time:        at,
+	}
+}
+
+func assertBarEqual(t *testing.T, want, got model.Bar) {
+	t.Helper()
+
+	assert.Equal(t, want.TradingPair, got.TradingPair)
+	assert.Equal(t, want.Interval, got.Interval)
+	assert.Equal(t, want.OpenTime, got.OpenTime)
+	assert.Equal(t, want.CloseTime, got.CloseTime)
+	assert.Equal(t, want.Open.String(), got.Open.String())
+	assert.Equal(t, want.High.String(), got.High.String())
+	assert.Equal(t, want.Low.String(), got.Low.String())
+	assert.Equal(t, want.Close.String(), got.Close.String())
+	assert.Equal(t, want.Volume.String(), got.Volume.String())
+	assert.Equal(t, want.TradeCount, got.TradeCount)
+	assert.Equal(t, want.VWAP.String(), got.VWAP.String())
+}
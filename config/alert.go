@@ -0,0 +1,36 @@
+package config
+
+import (
+	"time"
+
+	"github.com/aprln/vwap-engine/internal/env"
+	"github.com/shopspring/decimal"
+)
+
+const deftAlertCooldown = time.Minute
+
+// an alert re-arms once its value is back 0.1% of the threshold past the threshold
+var deftAlertHysteresis = decimal.RequireFromString("0.001")
+
+func NewAlert() Alert {
+	return Alert{
+		Rules:      env.LoadEnvStringSlice("ALERT_RULES", nil),
+		Cooldown:   env.MustLoadEnvNonNegativeDuration("ALERT_COOLDOWN", deftAlertCooldown),
+		Hysteresis: env.MustLoadEnvDecimal("ALERT_HYSTERESIS", deftAlertHysteresis),
+	}
+}
+
+type Alert struct {
+	// Rules are evaluated on each VWAP update of their trading pair, as "PAIR:above@LEVEL", "PAIR:below@LEVEL",
+	// "PAIR:deviation@PERCENT" or "PAIR:change@PERCENT/DURATION", e.g. "BTC-USD:change@1/30s".
+	Rules []string
+	// Cooldown is the minimum trade time between two alerts of the same rule.
+	Cooldown time.Duration
+	// Hysteresis is the ratio of the threshold a value must move back past the threshold before its rule re-arms.
+	Hysteresis decimal.Decimal
+}
+
+// Enabled tells whether any alert rule is set in ALERT_RULES, e.g. "BTC-USD:above@30000".
+func (a Alert) Enabled() bool {
+	return len(a.Rules) > 0
+}
@@ -0,0 +1,51 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewAlert(t *testing.T) {
+	tests := []struct {
+		name    string
+		envVars map[string]string
+		want    Alert
+	}{
+		{
+			name: "no env vars",
+			want: Alert{
+				Cooldown:   deftAlertCooldown,
+				Hysteresis: deftAlertHysteresis,
+			},
+		},
+		{
+			name: "with env vars",
+			envVars: map[string]string{
+				"ALERT_RULES":      "BTC-USD:above@30000|ETH-USD:change@1/30s",
+				"ALERT_COOLDOWN":   "5m",
+				"ALERT_HYSTERESIS": "0.01",
+			},
+			want: Alert{
+				Rules:      []string{"BTC-USD:above@30000", "ETH-USD:change@1/30s"},
+				Cooldown:   5 * time.Minute,
+				Hysteresis: decimal.RequireFromString("0.01"),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				for k, v := range tt.envVars {
+					t.Setenv(k, v)
+				}
+				got := NewAlert()
+				assert.Equal(t, tt.want, got)
+				assert.Equal(t, len(tt.want.Rules) > 0, got.Enabled())
+			},
+		)
+	}
+}
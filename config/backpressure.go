@@ -0,0 +1,28 @@
+package config
+
+import "github.com/aprln/vwap-engine/internal/env"
+
+// blocking by default, so no trade nor VWAP result is dropped
+const (
+	deftBackpressureCapacity = 1
+	deftBackpressurePolicy   = "block"
+)
+
+func NewBackpressure() Backpressure {
+	return Backpressure{
+		TradesCapacity: env.MustLoadEnvPositiveInt("BACKPRESSURE_TRADES_CAPACITY", deftBackpressureCapacity),
+		TradesPolicy:   env.LoadEnvString("BACKPRESSURE_TRADES_POLICY", deftBackpressurePolicy),
+		VWAPsCapacity:  env.MustLoadEnvPositiveInt("BACKPRESSURE_VWAPS_CAPACITY", deftBackpressureCapacity),
+		VWAPsPolicy:    env.LoadEnvString("BACKPRESSURE_VWAPS_POLICY", deftBackpressurePolicy),
+	}
+}
+
+// Backpressure holds the buffers between the stages of a pipeline: the trades between the feed and the processor,
+// and the VWAP results between the processor and the publishers. A policy is "block", "drop_oldest", "drop_newest"
+// or "conflate", see pipe.ParseOverflow. The trades cannot be conflated, and dropping them skews the VWAP.
+type Backpressure struct {
+	TradesCapacity int
+	TradesPolicy   string
+	VWAPsCapacity  int
+	VWAPsPolicy    string
+}
@@ -0,0 +1,51 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewBackpressure(t *testing.T) {
+	tests := []struct {
+		name    string
+		envVars map[string]string
+		want    Backpressure
+	}{
+		{
+			name: "no env vars",
+			want: Backpressure{
+				TradesCapacity: deftBackpressureCapacity,
+				TradesPolicy:   deftBackpressurePolicy,
+				VWAPsCapacity:  deftBackpressureCapacity,
+				VWAPsPolicy:    deftBackpressurePolicy,
+			},
+		},
+		{
+			name: "with env vars",
+			envVars: map[string]string{
+				"BACKPRESSURE_TRADES_CAPACITY": "1024",
+				"BACKPRESSURE_TRADES_POLICY":   "drop_newest",
+				"BACKPRESSURE_VWAPS_CAPACITY":  "16",
+				"BACKPRESSURE_VWAPS_POLICY":    "conflate",
+			},
+			want: Backpressure{
+				TradesCapacity: 1024,
+				TradesPolicy:   "drop_newest",
+				VWAPsCapacity:  16,
+				VWAPsPolicy:    "conflate",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				for k, v := range tt.envVars {
+					t.Setenv(k, v)
+				}
+				assert.Equal(t, tt.want, NewBackpressure())
+			},
+		)
+	}
+}
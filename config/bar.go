@@ -0,0 +1,33 @@
+package config
+
+import (
+	"time"
+
+	"github.com/aprln/vwap-engine/internal/env"
+)
+
+const (
+	deftBarEmitEmpty  = false
+	deftBarCloseDelay = time.Second
+)
+
+func NewBar() Bar {
+	return Bar{
+		Intervals:  env.MustLoadEnvDurationSlice("BAR_INTERVALS", nil),
+		EmitEmpty:  env.MustLoadEnvBool("BAR_EMIT_EMPTY", deftBarEmitEmpty),
+		CloseDelay: env.MustLoadEnvNonNegativeDuration("BAR_CLOSE_DELAY", deftBarCloseDelay),
+	}
+}
+
+type Bar struct {
+	Intervals []time.Duration
+	EmitEmpty bool
+	// CloseDelay is how long after its close time a bar without a later trade is closed by the clock,
+	// leaving time for the trades in flight.
+	CloseDelay time.Duration
+}
+
+// Enabled tells whether bars are built, which takes BAR_INTERVALS (e.g. "1m|5m") to be set.
+func (b Bar) Enabled() bool {
+	return len(b.Intervals) > 0
+}
@@ -0,0 +1,49 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewBar(t *testing.T) {
+	tests := []struct {
+		name    string
+		envVars map[string]string
+		want    Bar
+	}{
+		{
+			name: "no env vars",
+			want: Bar{
+				EmitEmpty:  deftBarEmitEmpty,
+				CloseDelay: deftBarCloseDelay,
+			},
+		},
+		{
+			name: "with env vars",
+			envVars: map[string]string{
+				"BAR_INTERVALS":   "1s|1m|5m|1h",
+				"BAR_EMIT_EMPTY":  "true",
+				"BAR_CLOSE_DELAY": "0s",
+			},
+			want: Bar{
+				Intervals:  []time.Duration{time.Second, time.Minute, 5 * time.Minute, time.Hour},
+				EmitEmpty:  true,
+				CloseDelay: 0,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				for k, v := range tt.envVars {
+					t.Setenv(k, v)
+				}
+				got := NewBar()
+				assert.Equal(t, tt.want, got)
+			},
+		)
+	}
+}
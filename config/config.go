@@ -1,13 +1,74 @@
 package config
 
+import "fmt"
+
 func NewConfig() Config {
+	vwap := NewVWAP()
+
 	return Config{
-		VWAP: NewVWAP(),
-		Feed: NewFeed(),
+		VWAP:         vwap,
+		Feed:         NewFeed(),
+		Bar:          NewBar(),
+		Validation:   NewValidation(vwap.TradingPairs),
+		Snapshot:     NewSnapshot(),
+		Cross:        NewCross(),
+		Alert:        NewAlert(),
+		WSServer:     NewWSServer(),
+		HTTPServer:   NewHTTPServer(),
+		GRPCServer:   NewGRPCServer(),
+		FileSink:     NewFileSink(),
+		Output:       NewOutput(),
+		Sink:         NewSink(),
+		Backpressure: NewBackpressure(),
+		Webhook:      NewWebhook(),
 	}
 }
 
 type Config struct {
 	VWAP
 	Feed
+	Bar
+	Validation
+	Snapshot
+	Cross
+	Alert
+	WSServer
+	HTTPServer
+	GRPCServer
+	FileSink
+	Output
+	Sink
+	Backpressure
+	Webhook
+}
+
+// WithPairOverrides returns a copy of the config holding the overrides of the trading pair, which are loaded from
+// the env vars if the trading pair was not configured when the config was loaded, e.g. as it is added through
+// the admin API. Unlike NewConfig, it returns the invalid env vars as errors.
+func (c Config) WithPairOverrides(tradingPair string) (cfg Config, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+
+	if _, ok := c.VWAP.PerPair[tradingPair]; !ok {
+		perPair := make(map[string]PairVWAP, len(c.VWAP.PerPair)+1)
+		for p, v := range c.VWAP.PerPair {
+			perPair[p] = v
+		}
+		perPair[tradingPair] = newPairVWAP(tradingPair, c.VWAP.PairVWAP)
+		c.VWAP.PerPair = perPair
+	}
+
+	if _, ok := c.Validation.PerPair[tradingPair]; !ok {
+		perPair := make(map[string]PairValidation, len(c.Validation.PerPair)+1)
+		for p, v := range c.Validation.PerPair {
+			perPair[p] = v
+		}
+		perPair[tradingPair] = newPairValidation(tradingPair, c.Validation.PairValidation)
+		c.Validation.PerPair = perPair
+	}
+
+	return c, nil
 }
@@ -0,0 +1,41 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_WithPairOverrides(t *testing.T) {
+	cfg := Config{
+		VWAP: VWAP{
+			PairVWAP: PairVWAP{Calculator: CalculatorNameWindow},
+			PerPair:  map[string]PairVWAP{"BTC-USD": {Calculator: CalculatorNameFixed}},
+		},
+		Validation: Validation{
+			PairValidation: PairValidation{MaxSize: decimal.NewFromInt(1000)},
+			PerPair:        map[string]PairValidation{"BTC-USD": {MaxSize: decimal.NewFromInt(10)}},
+		},
+	}
+	t.Setenv("VWAP_CALCULATOR_SOL_USD", "ewma")
+	t.Setenv("VALIDATION_MAX_SIZE_SOL_USD", "500")
+	t.Setenv("VWAP_CALCULATOR_BTC_USD", "volume")
+
+	got, err := cfg.WithPairOverrides("SOL-USD")
+	require.NoError(t, err)
+	assert.Equal(t, CalculatorNameEWMA, got.VWAP.ForPair("SOL-USD").Calculator)
+	assert.Equal(t, "500", got.Validation.ForPair("SOL-USD").MaxSize.String())
+	// the trading pairs already configured are kept as they were loaded, and the config is not changed
+	assert.Equal(t, CalculatorNameFixed, got.VWAP.ForPair("BTC-USD").Calculator)
+	assert.NotContains(t, cfg.VWAP.PerPair, "SOL-USD")
+
+	got, err = cfg.WithPairOverrides("BTC-USD")
+	require.NoError(t, err)
+	assert.Equal(t, CalculatorNameFixed, got.VWAP.ForPair("BTC-USD").Calculator)
+
+	t.Setenv("VALIDATION_MAX_SIZE_ETH_USD", "lots")
+	_, err = cfg.WithPairOverrides("ETH-USD")
+	assert.Error(t, err)
+}
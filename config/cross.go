@@ -0,0 +1,22 @@
+package config
+
+import (
+	"github.com/aprln/vwap-engine/internal/env"
+)
+
+func NewCross() Cross {
+	return Cross{
+		Triangles: env.LoadEnvStringSlice("CROSS_TRIANGLES", nil),
+	}
+}
+
+type Cross struct {
+	// Triangles declare the observed trading pair and the two legs its rate is implied from,
+	// as "OBSERVED=NUMERATOR/DENOMINATOR", e.g. "ETH-BTC=ETH-USD/BTC-USD".
+	Triangles []string
+}
+
+// Enabled tells whether cross rates are derived, i.e. whether CROSS_TRIANGLES declares any triangle.
+func (c Cross) Enabled() bool {
+	return len(c.Triangles) > 0
+}
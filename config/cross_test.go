@@ -0,0 +1,42 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewCross(t *testing.T) {
+	tests := []struct {
+		name    string
+		envVars map[string]string
+		want    Cross
+	}{
+		{
+			name: "no env vars",
+			want: Cross{},
+		},
+		{
+			name: "with env vars",
+			envVars: map[string]string{
+				"CROSS_TRIANGLES": "ETH-BTC=ETH-USD/BTC-USD|SOL-ETH=SOL-USD/ETH-USD",
+			},
+			want: Cross{
+				Triangles: []string{"ETH-BTC=ETH-USD/BTC-USD", "SOL-ETH=SOL-USD/ETH-USD"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				for k, v := range tt.envVars {
+					t.Setenv(k, v)
+				}
+				got := NewCross()
+				assert.Equal(t, tt.want, got)
+				assert.Equal(t, len(tt.want.Triangles) > 0, got.Enabled())
+			},
+		)
+	}
+}
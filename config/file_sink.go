@@ -0,0 +1,53 @@
+package config
+
+import (
+	"time"
+
+	"github.com/aprln/vwap-engine/internal/env"
+)
+
+type FileNaming string
+
+const (
+	// FileNamingDay writes the VWAP results of every trading pair to one file per UTC day, e.g. vwap-2022-01-01.jsonl.
+	FileNamingDay FileNaming = "day"
+	// FileNamingPair writes the VWAP results to one file per trading pair, e.g. BTC-USD.jsonl.
+	FileNamingPair FileNaming = "pair"
+)
+
+const (
+	deftFileSinkNaming         = FileNamingDay
+	deftFileSinkMaxSize        = 100 << 20
+	deftFileSinkRotateInterval = 24 * time.Hour
+	deftFileSinkGzip           = false
+	deftFileSinkFsync          = "interval@1s"
+)
+
+func NewFileSink() FileSink {
+	return FileSink{
+		Dir:            env.LoadEnvString("FILE_SINK_DIR", ""),
+		Naming:         FileNaming(env.LoadEnvString("FILE_SINK_NAMING", string(deftFileSinkNaming))),
+		MaxSize:        env.MustLoadEnvNonNegativeInt("FILE_SINK_MAX_SIZE", deftFileSinkMaxSize),
+		RotateInterval: env.MustLoadEnvNonNegativeDuration("FILE_SINK_ROTATE_INTERVAL", deftFileSinkRotateInterval),
+		Gzip:           env.MustLoadEnvBool("FILE_SINK_GZIP", deftFileSinkGzip),
+		Fsync:          env.LoadEnvString("FILE_SINK_FSYNC", deftFileSinkFsync),
+	}
+}
+
+type FileSink struct {
+	// Dir is the directory the JSON lines files are written to. The file sink is off while it is empty.
+	Dir    string
+	Naming FileNaming
+	// MaxSize is the size in bytes above which a file is rotated. Zero never rotates by size.
+	MaxSize int
+	// RotateInterval is the age above which a file is rotated. Zero never rotates by time.
+	RotateInterval time.Duration
+	// Gzip compresses the rotated files.
+	Gzip bool
+	// Fsync is the fsync policy, one of "always", "never" or "interval@<duration>".
+	Fsync string
+}
+
+func (f FileSink) Enabled() bool {
+	return f.Dir != ""
+}
@@ -0,0 +1,59 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewFileSink(t *testing.T) {
+	tests := []struct {
+		name    string
+		envVars map[string]string
+		want    FileSink
+	}{
+		{
+			name: "no env vars",
+			want: FileSink{
+				Naming:         deftFileSinkNaming,
+				MaxSize:        deftFileSinkMaxSize,
+				RotateInterval: deftFileSinkRotateInterval,
+				Gzip:           deftFileSinkGzip,
+				Fsync:          deftFileSinkFsync,
+			},
+		},
+		{
+			name: "with env vars",
+			envVars: map[string]string{
+				"FILE_SINK_DIR":             "/var/lib/vwap",
+				"FILE_SINK_NAMING":          "pair",
+				"FILE_SINK_MAX_SIZE":        "1048576",
+				"FILE_SINK_ROTATE_INTERVAL": "1h",
+				"FILE_SINK_GZIP":            "true",
+				"FILE_SINK_FSYNC":           "always",
+			},
+			want: FileSink{
+				Dir:            "/var/lib/vwap",
+				Naming:         FileNamingPair,
+				MaxSize:        1 << 20,
+				RotateInterval: time.Hour,
+				Gzip:           true,
+				Fsync:          "always",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				for k, v := range tt.envVars {
+					t.Setenv(k, v)
+				}
+				got := NewFileSink()
+				assert.Equal(t, tt.want, got)
+				assert.Equal(t, tt.want.Dir != "", got.Enabled())
+			},
+		)
+	}
+}
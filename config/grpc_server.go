@@ -0,0 +1,26 @@
+package config
+
+import (
+	"github.com/aprln/vwap-engine/internal/env"
+)
+
+const deftGRPCServerBufferSize = 256
+
+func NewGRPCServer() GRPCServer {
+	return GRPCServer{
+		Addr:       env.LoadEnvString("GRPC_SERVER_ADDR", ""),
+		BufferSize: env.MustLoadEnvPositiveInt("GRPC_SERVER_BUFFER_SIZE", deftGRPCServerBufferSize),
+	}
+}
+
+type GRPCServer struct {
+	// Addr is the TCP address the gRPC server listens on.
+	Addr string
+	// BufferSize is the number of VWAPs queued for a subscriber before its stream is ended for lagging.
+	BufferSize int
+}
+
+// Enabled tells whether the gRPC server is started on GRPC_SERVER_ADDR, e.g. ":9090".
+func (g GRPCServer) Enabled() bool {
+	return g.Addr != ""
+}
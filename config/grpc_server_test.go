@@ -0,0 +1,41 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewGRPCServer(t *testing.T) {
+	tests := []struct {
+		name    string
+		envVars map[string]string
+		want    GRPCServer
+	}{
+		{
+			name: "no env vars",
+			want: GRPCServer{BufferSize: deftGRPCServerBufferSize},
+		},
+		{
+			name: "with env vars",
+			envVars: map[string]string{
+				"GRPC_SERVER_ADDR":        ":9090",
+				"GRPC_SERVER_BUFFER_SIZE": "16",
+			},
+			want: GRPCServer{Addr: ":9090", BufferSize: 16},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				for k, v := range tt.envVars {
+					t.Setenv(k, v)
+				}
+				got := NewGRPCServer()
+				assert.Equal(t, tt.want, got)
+				assert.Equal(t, tt.want.Addr != "", got.Enabled())
+			},
+		)
+	}
+}
@@ -0,0 +1,49 @@
+package config
+
+import (
+	"time"
+
+	"github.com/aprln/vwap-engine/internal/env"
+)
+
+const (
+	deftHTTPStaleAfter       = 10 * time.Second
+	deftHTTPStreamReplaySize = 1000
+	deftHTTPStreamBufferSize = 256
+	deftHTTPStreamKeepAlive  = 15 * time.Second
+)
+
+func NewHTTPServer() HTTPServer {
+	return HTTPServer{
+		Addr:             env.LoadEnvString("HTTP_SERVER_ADDR", ""),
+		StaleAfter:       env.MustLoadEnvNonNegativeDuration("HTTP_STALE_AFTER", deftHTTPStaleAfter),
+		StreamReplaySize: env.MustLoadEnvNonNegativeInt("HTTP_STREAM_REPLAY_SIZE", deftHTTPStreamReplaySize),
+		StreamBufferSize: env.MustLoadEnvPositiveInt("HTTP_STREAM_BUFFER_SIZE", deftHTTPStreamBufferSize),
+		StreamKeepAlive:  env.MustLoadEnvNonNegativeDuration("HTTP_STREAM_KEEPALIVE", deftHTTPStreamKeepAlive),
+		// the admin endpoints are disabled by default
+		AdminToken: env.LoadEnvString("HTTP_ADMIN_TOKEN", ""),
+	}
+}
+
+type HTTPServer struct {
+	// Addr is the TCP address the HTTP server listens on.
+	Addr string
+	// StaleAfter is the age of the latest VWAP of a trading pair above which it is reported as stale.
+	// Zero never reports it as stale.
+	StaleAfter time.Duration
+	// StreamReplaySize is the number of latest events kept to be replayed to the clients of the event stream
+	// reconnecting with a Last-Event-ID. Zero never replays.
+	StreamReplaySize int
+	// StreamBufferSize is the number of events queued for a client before it is disconnected for lagging.
+	StreamBufferSize int
+	// StreamKeepAlive is the time between two keepalive comments of the event stream. Zero disables them.
+	StreamKeepAlive time.Duration
+	// AdminToken is the bearer token authorizing the admin endpoints adding and removing trading pairs.
+	// Empty disables them.
+	AdminToken string
+}
+
+// Enabled tells whether the HTTP server is started, i.e. whether HTTP_SERVER_ADDR (e.g. ":8080") is set.
+func (h HTTPServer) Enabled() bool {
+	return h.Addr != ""
+}
@@ -0,0 +1,58 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewHTTPServer(t *testing.T) {
+	tests := []struct {
+		name    string
+		envVars map[string]string
+		want    HTTPServer
+	}{
+		{
+			name: "no env vars",
+			want: HTTPServer{
+				StaleAfter:       deftHTTPStaleAfter,
+				StreamReplaySize: deftHTTPStreamReplaySize,
+				StreamBufferSize: deftHTTPStreamBufferSize,
+				StreamKeepAlive:  deftHTTPStreamKeepAlive,
+			},
+		},
+		{
+			name: "with env vars",
+			envVars: map[string]string{
+				"HTTP_SERVER_ADDR":        ":8080",
+				"HTTP_STALE_AFTER":        "1m",
+				"HTTP_STREAM_REPLAY_SIZE": "0",
+				"HTTP_STREAM_BUFFER_SIZE": "16",
+				"HTTP_STREAM_KEEPALIVE":   "30s",
+				"HTTP_ADMIN_TOKEN":        "token",
+			},
+			want: HTTPServer{
+				Addr:             ":8080",
+				StaleAfter:       time.Minute,
+				StreamReplaySize: 0,
+				StreamBufferSize: 16,
+				StreamKeepAlive:  30 * time.Second,
+				AdminToken:       "token",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				for k, v := range tt.envVars {
+					t.Setenv(k, v)
+				}
+				got := NewHTTPServer()
+				assert.Equal(t, tt.want, got)
+				assert.Equal(t, tt.want.Addr != "", got.Enabled())
+			},
+		)
+	}
+}
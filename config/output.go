@@ -0,0 +1,40 @@
+package config
+
+import (
+	"github.com/aprln/vwap-engine/internal/env"
+)
+
+type Encoding string
+
+const (
+	EncodingJSON Encoding = "json"
+	// EncodingJSONNumbers is JSON with the decimals rendered as numbers rather than strings.
+	EncodingJSONNumbers Encoding = "json_numbers"
+	EncodingCSV         Encoding = "csv"
+	EncodingMsgpack     Encoding = "msgpack"
+	// EncodingProtobuf is the vwap.v1.VWAP message prefixed with its varint encoded length.
+	EncodingProtobuf Encoding = "protobuf"
+)
+
+const deftOutputEncoding = EncodingJSON
+
+func NewOutput() Output {
+	return Output{
+		Encoding: Encoding(env.LoadEnvString("OUTPUT_ENCODING", string(deftOutputEncoding))),
+	}
+}
+
+type Output struct {
+	// Encoding is the encoding of the VWAP results written to stdout.
+	Encoding Encoding
+}
+
+// IsJSON returns whether the VWAP results are JSON, like the bars, cross rates and alerts written along with them.
+func (e Encoding) IsJSON() bool {
+	return e == EncodingJSON || e == EncodingJSONNumbers
+}
+
+// IsBinary returns whether the encoded VWAP results are binary, and delimit themselves rather than being lines.
+func (e Encoding) IsBinary() bool {
+	return e == EncodingMsgpack || e == EncodingProtobuf
+}
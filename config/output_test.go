@@ -0,0 +1,36 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewOutput(t *testing.T) {
+	tests := []struct {
+		name    string
+		envVars map[string]string
+		want    Output
+	}{
+		{
+			name: "no env vars",
+			want: Output{Encoding: deftOutputEncoding},
+		},
+		{
+			name:    "with env vars",
+			envVars: map[string]string{"OUTPUT_ENCODING": "msgpack"},
+			want:    Output{Encoding: EncodingMsgpack},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				for k, v := range tt.envVars {
+					t.Setenv(k, v)
+				}
+				assert.Equal(t, tt.want, NewOutput())
+			},
+		)
+	}
+}
@@ -0,0 +1,52 @@
+package config
+
+import (
+	"time"
+
+	"github.com/aprln/vwap-engine/internal/env"
+)
+
+const (
+	deftSinkQueueSize         = 1024
+	deftSinkMaxRetries        = 3
+	deftSinkRetryBackoff      = 100 * time.Millisecond
+	deftSinkRetryMaxBackoff   = 5 * time.Second
+	deftSinkDeadLetterMaxSize = 100 << 20
+)
+
+func NewSink() Sink {
+	return Sink{
+		QueueSize:         env.MustLoadEnvPositiveInt("SINK_QUEUE_SIZE", deftSinkQueueSize),
+		MaxRetries:        env.MustLoadEnvNonNegativeInt("SINK_MAX_RETRIES", deftSinkMaxRetries),
+		RetryBackoff:      env.MustLoadEnvNonNegativeDuration("SINK_RETRY_BACKOFF", deftSinkRetryBackoff),
+		RetryMaxBackoff:   env.MustLoadEnvNonNegativeDuration("SINK_RETRY_MAX_BACKOFF", deftSinkRetryMaxBackoff),
+		DeadLetterFile:    env.LoadEnvString("SINK_DEAD_LETTER_FILE", ""),
+		DeadLetterMaxSize: env.MustLoadEnvNonNegativeInt("SINK_DEAD_LETTER_MAX_SIZE", deftSinkDeadLetterMaxSize),
+	}
+}
+
+// Sink holds the settings of the sinks the VWAP results are fanned out to, i.e. the websocket, HTTP stream,
+// gRPC, file and webhook sinks. Each sink has its own queue, so a slow or broken sink never holds back the others.
+type Sink struct {
+	// QueueSize is the number of messages queued for a sink. The messages sent to a full queue are dead-lettered.
+	QueueSize int
+	// MaxRetries is the number of retries of a failed send before the message is dead-lettered.
+	MaxRetries int
+	// RetryBackoff is the wait before the first retry, doubled for every next retry up to RetryMaxBackoff.
+	RetryBackoff    time.Duration
+	RetryMaxBackoff time.Duration
+	// DeadLetterFile is the JSON lines file the messages that could not be sent are appended to.
+	// They are only logged while it is empty.
+	DeadLetterFile string
+	// DeadLetterMaxSize is the size in bytes the dead letter file is rotated at, keeping a single rotated file.
+	// 0 disables the rotation.
+	DeadLetterMaxSize int
+}
+
+// WithoutRetries returns the settings of a sink whose sender retries on its own, e.g. the webhook sender,
+// so a failed send is not retried twice.
+func (s Sink) WithoutRetries() Sink {
+	s.MaxRetries = 0
+
+	return s
+}
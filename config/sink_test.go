@@ -0,0 +1,63 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewSink(t *testing.T) {
+	tests := []struct {
+		name    string
+		envVars map[string]string
+		want    Sink
+	}{
+		{
+			name: "no env vars",
+			want: Sink{
+				QueueSize:         deftSinkQueueSize,
+				MaxRetries:        deftSinkMaxRetries,
+				RetryBackoff:      deftSinkRetryBackoff,
+				RetryMaxBackoff:   deftSinkRetryMaxBackoff,
+				DeadLetterMaxSize: deftSinkDeadLetterMaxSize,
+			},
+		},
+		{
+			name: "with env vars",
+			envVars: map[string]string{
+				"SINK_QUEUE_SIZE":           "16",
+				"SINK_MAX_RETRIES":          "0",
+				"SINK_RETRY_BACKOFF":        "1s",
+				"SINK_RETRY_MAX_BACKOFF":    "1m",
+				"SINK_DEAD_LETTER_FILE":     "/var/lib/vwap/dead_letters.jsonl",
+				"SINK_DEAD_LETTER_MAX_SIZE": "1048576",
+			},
+			want: Sink{
+				QueueSize:         16,
+				MaxRetries:        0,
+				RetryBackoff:      time.Second,
+				RetryMaxBackoff:   time.Minute,
+				DeadLetterFile:    "/var/lib/vwap/dead_letters.jsonl",
+				DeadLetterMaxSize: 1048576,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				for k, v := range tt.envVars {
+					t.Setenv(k, v)
+				}
+				assert.Equal(t, tt.want, NewSink())
+			},
+		)
+	}
+}
+
+func TestSink_WithoutRetries(t *testing.T) {
+	s := Sink{QueueSize: 16, MaxRetries: 3, RetryBackoff: time.Second}
+	assert.Equal(t, Sink{QueueSize: 16, RetryBackoff: time.Second}, s.WithoutRetries())
+	assert.Equal(t, 3, s.MaxRetries, "the settings are copied")
+}
@@ -0,0 +1,34 @@
+package config
+
+import (
+	"time"
+
+	"github.com/aprln/vwap-engine/internal/env"
+)
+
+const (
+	deftSnapshotInterval = time.Minute
+	deftSnapshotMaxAge   = 5 * time.Minute
+)
+
+func NewSnapshot() Snapshot {
+	return Snapshot{
+		Dir:      env.LoadEnvString("SNAPSHOT_DIR", ""),
+		Interval: env.MustLoadEnvNonNegativeDuration("SNAPSHOT_INTERVAL", deftSnapshotInterval),
+		MaxAge:   env.MustLoadEnvNonNegativeDuration("SNAPSHOT_MAX_AGE", deftSnapshotMaxAge),
+	}
+}
+
+type Snapshot struct {
+	// Dir is the directory the calculator snapshots are written to, one file per trading pair.
+	// No snapshot is taken nor restored while it is empty.
+	Dir string
+	// Interval is the time between two periodic snapshots. Zero only snapshots on shutdown.
+	Interval time.Duration
+	// MaxAge is the age above which a snapshot is not restored at startup. Zero never restores.
+	MaxAge time.Duration
+}
+
+func (s Snapshot) Enabled() bool {
+	return s.Dir != ""
+}
@@ -0,0 +1,50 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewSnapshot(t *testing.T) {
+	tests := []struct {
+		name    string
+		envVars map[string]string
+		want    Snapshot
+	}{
+		{
+			name: "no env vars",
+			want: Snapshot{
+				Interval: deftSnapshotInterval,
+				MaxAge:   deftSnapshotMaxAge,
+			},
+		},
+		{
+			name: "with env vars",
+			envVars: map[string]string{
+				"SNAPSHOT_DIR":      "/tmp/snapshots",
+				"SNAPSHOT_INTERVAL": "30s",
+				"SNAPSHOT_MAX_AGE":  "1h",
+			},
+			want: Snapshot{
+				Dir:      "/tmp/snapshots",
+				Interval: 30 * time.Second,
+				MaxAge:   time.Hour,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				for k, v := range tt.envVars {
+					t.Setenv(k, v)
+				}
+				got := NewSnapshot()
+				assert.Equal(t, tt.want, got)
+				assert.Equal(t, tt.want.Dir != "", got.Enabled())
+			},
+		)
+	}
+}
@@ -0,0 +1,79 @@
+package config
+
+import (
+	"time"
+
+	"github.com/aprln/vwap-engine/internal/env"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	deftValidationRequirePositive = true
+	deftValidationMaxClockSkew    = 5 * time.Second
+)
+
+// NewValidation loads the trade validation rules, along with the overrides of the trading pairs.
+// A zero limit disables its rule.
+func NewValidation(tradingPairs []string) Validation {
+	deftPair := newPairValidation(
+		"", PairValidation{
+			// the limits are in the units of each product, so there are no sensible defaults for all trading pairs
+			MaxSize:           decimal.Zero,
+			MaxPriceDeviation: decimal.Zero,
+		},
+	)
+
+	perPair := make(map[string]PairValidation, len(tradingPairs))
+	for _, tradingPair := range tradingPairs {
+		perPair[tradingPair] = newPairValidation(tradingPair, deftPair)
+	}
+
+	return Validation{
+		RequirePositive: env.MustLoadEnvBool("VALIDATION_REQUIRE_POSITIVE", deftValidationRequirePositive),
+		MaxClockSkew:    env.MustLoadEnvNonNegativeDuration("VALIDATION_MAX_CLOCK_SKEW", deftValidationMaxClockSkew),
+		MaxTradeAge:     env.MustLoadEnvNonNegativeDuration("VALIDATION_MAX_TRADE_AGE", 0),
+		PairValidation:  deftPair,
+		PerPair:         perPair,
+	}
+}
+
+type Validation struct {
+	// RequirePositive rejects trades whose price or size is not positive.
+	RequirePositive bool
+	// MaxClockSkew rejects trades whose time is later than now plus it.
+	MaxClockSkew time.Duration
+	// MaxTradeAge rejects trades whose time is earlier than now minus it.
+	MaxTradeAge time.Duration
+	// PairValidation holds the default limits for trading pairs without overrides.
+	PairValidation
+	PerPair map[string]PairValidation
+}
+
+// ForPair returns a copy of the config with the limits of the trading pair in place of the defaults.
+func (v Validation) ForPair(tradingPair string) Validation {
+	if p, ok := v.PerPair[tradingPair]; ok {
+		v.PairValidation = p
+	}
+
+	return v
+}
+
+// PairValidation holds the limits that can be overridden per trading pair, as they depend on the product.
+// An override is an env var suffixed with the trading pair, e.g. VALIDATION_MAX_SIZE_BTC_USD overrides
+// VALIDATION_MAX_SIZE.
+type PairValidation struct {
+	// MaxSize rejects trades bigger than it.
+	MaxSize decimal.Decimal
+	// MaxPriceDeviation rejects trades whose price deviates from both the current VWAP and the previous trade's
+	// price by more than this percentage.
+	MaxPriceDeviation decimal.Decimal
+}
+
+func newPairValidation(tradingPair string, deft PairValidation) PairValidation {
+	return PairValidation{
+		MaxSize: env.MustLoadEnvDecimal(pairEnvKey("VALIDATION_MAX_SIZE", tradingPair), deft.MaxSize),
+		MaxPriceDeviation: env.MustLoadEnvDecimal(
+			pairEnvKey("VALIDATION_MAX_PRICE_DEVIATION", tradingPair), deft.MaxPriceDeviation,
+		),
+	}
+}
@@ -0,0 +1,73 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		envVars map[string]string
+		want    Validation
+	}{
+		{
+			name: "no env vars",
+			want: Validation{
+				RequirePositive: deftValidationRequirePositive,
+				MaxClockSkew:    deftValidationMaxClockSkew,
+				PairValidation:  PairValidation{MaxSize: decimal.Zero, MaxPriceDeviation: decimal.Zero},
+				PerPair: map[string]PairValidation{
+					"BTC-USD": {MaxSize: decimal.Zero, MaxPriceDeviation: decimal.Zero},
+				},
+			},
+		},
+		{
+			name: "with env vars",
+			envVars: map[string]string{
+				"VALIDATION_REQUIRE_POSITIVE":            "false",
+				"VALIDATION_MAX_SIZE":                    "1000",
+				"VALIDATION_MAX_PRICE_DEVIATION":         "5",
+				"VALIDATION_MAX_CLOCK_SKEW":              "1s",
+				"VALIDATION_MAX_TRADE_AGE":               "1h",
+				"VALIDATION_MAX_SIZE_BTC_USD":            "10",
+				"VALIDATION_MAX_PRICE_DEVIATION_BTC_USD": "1",
+			},
+			want: Validation{
+				MaxClockSkew:   time.Second,
+				MaxTradeAge:    time.Hour,
+				PairValidation: PairValidation{MaxSize: decimal.NewFromInt(1000), MaxPriceDeviation: decimal.NewFromInt(5)},
+				PerPair: map[string]PairValidation{
+					"BTC-USD": {MaxSize: decimal.NewFromInt(10), MaxPriceDeviation: decimal.NewFromInt(1)},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				for k, v := range tt.envVars {
+					t.Setenv(k, v)
+				}
+				got := NewValidation([]string{"BTC-USD"})
+				assert.Equal(t, tt.want, got)
+			},
+		)
+	}
+}
+
+func TestValidation_ForPair(t *testing.T) {
+	v := Validation{
+		MaxClockSkew:   time.Second,
+		PairValidation: PairValidation{MaxSize: decimal.NewFromInt(1000)},
+		PerPair:        map[string]PairValidation{"BTC-USD": {MaxSize: decimal.NewFromInt(10)}},
+	}
+
+	assert.Equal(t, "10", v.ForPair("BTC-USD").MaxSize.String())
+	assert.Equal(t, time.Second, v.ForPair("BTC-USD").MaxClockSkew)
+	assert.Equal(t, "1000", v.ForPair("ETH-USD").MaxSize.String())
+}
@@ -4,21 +4,172 @@ import (
 	"strings"
 
 	"github.com/aprln/vwap-engine/internal/env"
+	"github.com/shopspring/decimal"
+)
+
+type CalculatorName string
+
+const (
+	CalculatorNameWindow   CalculatorName = "window"
+	CalculatorNameAnchored CalculatorName = "anchored"
+	CalculatorNameVolume   CalculatorName = "volume"
+	CalculatorNameEWMA     CalculatorName = "ewma"
+	CalculatorNameFixed    CalculatorName = "fixed"
 )
 
 const (
 	deftTradingPairs = "BTC-USD|ETH-USD|ETH-BTC"
 	deftWindowSize   = 200
+	deftCalculator   = CalculatorNameWindow
+	deftAnchor       = "midnight"
+	deftEWMAHalfLife = "100"
+	deftVerifyEvery  = 0
+	deftEmit         = "every"
+	// results are published before the window is full by default
+	deftRequireFullWindow = false
+	deftRounding          = "half_even"
 )
 
 func NewVWAP() VWAP {
+	tradingPairs := env.LoadEnvStringSlice("VWAP_TRADING_PAIRS", strings.Split(deftTradingPairs, "|"))
+	deftPair := newPairVWAP(
+		"", PairVWAP{
+			Calculator: deftCalculator,
+			Anchor:     deftAnchor,
+			// there is no sensible default for all trading pairs, it must be set to use the volume calculator
+			WindowVolume: decimal.Zero,
+			EWMAHalfLife: deftEWMAHalfLife,
+			// the increments depend on the product, they must be set to use the fixed calculator
+			PriceIncrement:    decimal.Zero,
+			SizeIncrement:     decimal.Zero,
+			Emit:              deftEmit,
+			RequireFullWindow: deftRequireFullWindow,
+			// no minimum volume by default
+			MinWindowVolume: decimal.Zero,
+			// the published VWAP is not rounded by default
+			OutputPrecision: "",
+			Rounding:        deftRounding,
+		},
+	)
+
+	perPair := make(map[string]PairVWAP, len(tradingPairs))
+	for _, tradingPair := range tradingPairs {
+		perPair[tradingPair] = newPairVWAP(tradingPair, deftPair)
+	}
+
 	return VWAP{
-		TradingPairs: env.LoadEnvStringSlice("VWAP_TRADING_PAIRS", strings.Split(deftTradingPairs, "|")),
+		TradingPairs: tradingPairs,
 		WindowSize:   env.MustLoadEnvPositiveInt("VWAP_WINDOW_SIZE", deftWindowSize),
+		VerifyEvery:  env.MustLoadEnvNonNegativeInt("VWAP_VERIFY_EVERY", deftVerifyEvery),
+		// no bands are published by default
+		BandMultipliers: env.MustLoadEnvDecimalSlice("VWAP_BAND_MULTIPLIERS", nil),
+		PairVWAP:        deftPair,
+		PerPair:         perPair,
 	}
 }
 
 type VWAP struct {
 	TradingPairs []string
 	WindowSize   int
+	// VerifyEvery is the number of updates between two verifications of the calculator's running totals.
+	// Zero disables periodic verification.
+	VerifyEvery int
+	// BandMultipliers are the k values of the published VWAP ± k·σ bands.
+	BandMultipliers []decimal.Decimal
+	// PairVWAP holds the default settings for trading pairs without overrides.
+	PairVWAP
+	PerPair map[string]PairVWAP
+}
+
+// ForPair returns a copy of the config with the settings of the trading pair in place of the defaults.
+func (v VWAP) ForPair(tradingPair string) VWAP {
+	if p, ok := v.PerPair[tradingPair]; ok {
+		v.PairVWAP = p
+	}
+
+	return v
+}
+
+// PairVWAP holds the settings that can be overridden per trading pair.
+// An override is an env var suffixed with the trading pair, e.g. VWAP_INDICATORS_BTC_USD overrides VWAP_INDICATORS.
+type PairVWAP struct {
+	Calculator CalculatorName
+	// Anchor is only used by the anchored calculator.
+	// It is one of "midnight", "daily@HH:MM" (UTC session open time) or "manual".
+	Anchor string
+	// WindowVolume is the total base volume held by the volume calculator's window.
+	WindowVolume decimal.Decimal
+	// EWMAHalfLife is only used by the ewma calculator.
+	// It is either a number of trades (e.g. "100") or a trade-time duration (e.g. "30s").
+	EWMAHalfLife string
+	// PriceIncrement and SizeIncrement are the product's quote and base increments, e.g. "0.01" and "0.00000001".
+	// The fixed calculator derives its fixed-point scales from them.
+	PriceIncrement decimal.Decimal
+	SizeIncrement  decimal.Decimal
+	// Indicators are computed over the same window as VWAP and published alongside it.
+	// They require a calculator windowed by trade count, window or fixed.
+	Indicators []string
+	// Emit is the emit policy deciding which VWAP results are published.
+	// It is one of "every", "interval@<duration>", "move@<ratio>" or "trades@<n>".
+	Emit string
+	// RequireFullWindow and MinWindowVolume suppress the results until the window is full
+	// or holds the minimum base volume, whichever is configured and comes first.
+	RequireFullWindow bool
+	MinWindowVolume   decimal.Decimal
+	// OutputPrecision is the number of decimal places of the published VWAP, bands and price indicators,
+	// or "increment" to use the decimal places of PriceIncrement. Empty does not round.
+	OutputPrecision string
+	// Rounding is one of "half_even", "half_up", "up", "down", "ceil" or "floor".
+	Rounding string
+}
+
+// WarmUpEnabled returns whether the results are suppressed while the window warms up.
+func (p PairVWAP) WarmUpEnabled() bool {
+	return p.RequireFullWindow || p.MinWindowVolume.IsPositive()
+}
+
+func newPairVWAP(tradingPair string, deft PairVWAP) PairVWAP {
+	return PairVWAP{
+		Calculator: CalculatorName(
+			env.LoadEnvString(pairEnvKey("VWAP_CALCULATOR", tradingPair), string(deft.Calculator)),
+		),
+		Anchor:       env.LoadEnvString(pairEnvKey("VWAP_ANCHOR", tradingPair), deft.Anchor),
+		WindowVolume: env.MustLoadEnvDecimal(pairEnvKey("VWAP_WINDOW_VOLUME", tradingPair), deft.WindowVolume),
+		EWMAHalfLife: env.LoadEnvString(pairEnvKey("VWAP_EWMA_HALF_LIFE", tradingPair), deft.EWMAHalfLife),
+		PriceIncrement: env.MustLoadEnvDecimal(
+			pairEnvKey("VWAP_PRICE_INCREMENT", tradingPair), deft.PriceIncrement,
+		),
+		SizeIncrement: env.MustLoadEnvDecimal(pairEnvKey("VWAP_SIZE_INCREMENT", tradingPair), deft.SizeIncrement),
+		Indicators:    env.LoadEnvStringSlice(pairEnvKey("VWAP_INDICATORS", tradingPair), deft.Indicators),
+		Emit:          env.LoadEnvString(pairEnvKey("VWAP_EMIT", tradingPair), deft.Emit),
+		RequireFullWindow: env.MustLoadEnvBool(
+			pairEnvKey("VWAP_REQUIRE_FULL_WINDOW", tradingPair), deft.RequireFullWindow,
+		),
+		MinWindowVolume: env.MustLoadEnvDecimal(
+			pairEnvKey("VWAP_MIN_WINDOW_VOLUME", tradingPair), deft.MinWindowVolume,
+		),
+		OutputPrecision: env.LoadEnvString(pairEnvKey("VWAP_OUTPUT_PRECISION", tradingPair), deft.OutputPrecision),
+		Rounding:        env.LoadEnvString(pairEnvKey("VWAP_ROUNDING", tradingPair), deft.Rounding),
+	}
+}
+
+// pairEnvKey returns key suffixed with the upper-cased trading pair, non-alphanumerics being replaced by "_".
+// An empty trading pair returns key as is.
+func pairEnvKey(key, tradingPair string) string {
+	if tradingPair == "" {
+		return key
+	}
+
+	suffix := strings.Map(
+		func(r rune) rune {
+			if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+				return r
+			}
+
+			return '_'
+		},
+		strings.ToUpper(tradingPair),
+	)
+
+	return key + "_" + suffix
 }
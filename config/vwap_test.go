@@ -4,10 +4,43 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestNewVWAP(t *testing.T) {
+	deftPair := PairVWAP{
+		Calculator:      deftCalculator,
+		Anchor:          deftAnchor,
+		WindowVolume:    decimal.Zero,
+		EWMAHalfLife:    deftEWMAHalfLife,
+		PriceIncrement:  decimal.Zero,
+		SizeIncrement:   decimal.Zero,
+		Emit:            deftEmit,
+		MinWindowVolume: decimal.Zero,
+		Rounding:        deftRounding,
+	}
+	pair := PairVWAP{
+		Calculator:        CalculatorNameAnchored,
+		Anchor:            "daily@13:30",
+		WindowVolume:      decimal.NewFromInt(50),
+		EWMAHalfLife:      "30s",
+		PriceIncrement:    decimal.RequireFromString("0.01"),
+		SizeIncrement:     decimal.RequireFromString("0.00000001"),
+		Indicators:        []string{"twap", "median"},
+		Emit:              "interval@250ms",
+		RequireFullWindow: true,
+		MinWindowVolume:   decimal.NewFromInt(10),
+		OutputPrecision:   "increment",
+		Rounding:          "floor",
+	}
+	overriddenPair := pair
+	overriddenPair.Calculator = CalculatorNameEWMA
+	overriddenPair.Indicators = []string{"notional"}
+	overriddenPair.Emit = "every"
+	overriddenPair.RequireFullWindow = false
+	overriddenPair.OutputPrecision = "4"
+
 	tests := []struct {
 		name    string
 		envVars map[string]string
@@ -18,17 +51,50 @@ func TestNewVWAP(t *testing.T) {
 			want: VWAP{
 				TradingPairs: strings.Split(deftTradingPairs, "|"),
 				WindowSize:   deftWindowSize,
+				VerifyEvery:  deftVerifyEvery,
+				PairVWAP:     deftPair,
+				PerPair: map[string]PairVWAP{
+					"BTC-USD": deftPair,
+					"ETH-USD": deftPair,
+					"ETH-BTC": deftPair,
+				},
 			},
 		},
 		{
 			name: "with env vars",
 			envVars: map[string]string{
-				"VWAP_WINDOW_SIZE":   "2",
-				"VWAP_TRADING_PAIRS": "ABC|DEF",
+				"VWAP_WINDOW_SIZE":             "2",
+				"VWAP_VERIFY_EVERY":            "1000",
+				"VWAP_TRADING_PAIRS":           "ABC|DEF",
+				"VWAP_CALCULATOR":              "anchored",
+				"VWAP_CALCULATOR_DEF":          "ewma",
+				"VWAP_ANCHOR":                  "daily@13:30",
+				"VWAP_WINDOW_VOLUME":           "50",
+				"VWAP_EWMA_HALF_LIFE":          "30s",
+				"VWAP_PRICE_INCREMENT":         "0.01",
+				"VWAP_SIZE_INCREMENT":          "0.00000001",
+				"VWAP_BAND_MULTIPLIERS":        "1|2",
+				"VWAP_INDICATORS":              "twap|median",
+				"VWAP_INDICATORS_DEF":          "notional",
+				"VWAP_EMIT":                    "interval@250ms",
+				"VWAP_EMIT_DEF":                "every",
+				"VWAP_REQUIRE_FULL_WINDOW":     "true",
+				"VWAP_REQUIRE_FULL_WINDOW_DEF": "false",
+				"VWAP_MIN_WINDOW_VOLUME":       "10",
+				"VWAP_OUTPUT_PRECISION":        "increment",
+				"VWAP_OUTPUT_PRECISION_DEF":    "4",
+				"VWAP_ROUNDING":                "floor",
 			},
 			want: VWAP{
-				TradingPairs: strings.Split("ABC|DEF", "|"),
-				WindowSize:   2,
+				TradingPairs:    strings.Split("ABC|DEF", "|"),
+				WindowSize:      2,
+				VerifyEvery:     1000,
+				BandMultipliers: []decimal.Decimal{decimal.NewFromInt(1), decimal.NewFromInt(2)},
+				PairVWAP:        pair,
+				PerPair: map[string]PairVWAP{
+					"ABC": pair,
+					"DEF": overriddenPair,
+				},
 			},
 		},
 	}
@@ -45,3 +111,22 @@ func TestNewVWAP(t *testing.T) {
 		)
 	}
 }
+
+func TestVWAP_ForPair(t *testing.T) {
+	v := VWAP{
+		WindowSize: 3,
+		PairVWAP:   PairVWAP{Indicators: []string{"twap"}},
+		PerPair: map[string]PairVWAP{
+			"BTC-USD": {Indicators: []string{"median"}},
+		},
+	}
+
+	assert.Equal(t, []string{"median"}, v.ForPair("BTC-USD").Indicators)
+	assert.Equal(t, 3, v.ForPair("BTC-USD").WindowSize)
+	assert.Equal(t, []string{"twap"}, v.ForPair("ETH-USD").Indicators)
+}
+
+func TestPairEnvKey(t *testing.T) {
+	assert.Equal(t, "VWAP_INDICATORS", pairEnvKey("VWAP_INDICATORS", ""))
+	assert.Equal(t, "VWAP_INDICATORS_BTC_USD", pairEnvKey("VWAP_INDICATORS", "btc-usd"))
+}
@@ -0,0 +1,53 @@
+package config
+
+import (
+	"time"
+
+	"github.com/aprln/vwap-engine/internal/env"
+)
+
+const (
+	deftWebhookBatchSize       = 1
+	deftWebhookBatchInterval   = time.Second
+	deftWebhookTimeout         = 5 * time.Second
+	deftWebhookMaxRetries      = 3
+	deftWebhookRetryBackoff    = 100 * time.Millisecond
+	deftWebhookRetryMaxBackoff = 5 * time.Second
+)
+
+func NewWebhook() Webhook {
+	return Webhook{
+		URLs:            env.LoadEnvStringSlice("WEBHOOK_URLS", nil),
+		Secret:          env.LoadEnvString("WEBHOOK_SECRET", ""),
+		BatchSize:       env.MustLoadEnvPositiveInt("WEBHOOK_BATCH_SIZE", deftWebhookBatchSize),
+		BatchInterval:   env.MustLoadEnvNonNegativeDuration("WEBHOOK_BATCH_INTERVAL", deftWebhookBatchInterval),
+		Timeout:         env.MustLoadEnvNonNegativeDuration("WEBHOOK_TIMEOUT", deftWebhookTimeout),
+		MaxRetries:      env.MustLoadEnvNonNegativeInt("WEBHOOK_MAX_RETRIES", deftWebhookMaxRetries),
+		RetryBackoff:    env.MustLoadEnvNonNegativeDuration("WEBHOOK_RETRY_BACKOFF", deftWebhookRetryBackoff),
+		RetryMaxBackoff: env.MustLoadEnvNonNegativeDuration("WEBHOOK_RETRY_MAX_BACKOFF", deftWebhookRetryMaxBackoff),
+	}
+}
+
+type Webhook struct {
+	// URLs are the endpoints every VWAP result is posted to. Posting also requires a Secret.
+	URLs []string
+	// Secret is the key of the HMAC-SHA256 signature of every request.
+	Secret string
+	// BatchSize is the number of VWAP results posted together as a JSON array, 1 posting each result on its own.
+	BatchSize int
+	// BatchInterval is the longest a VWAP result waits for its batch to be full before the batch is posted anyway.
+	// It is required with a BatchSize above 1.
+	BatchInterval time.Duration
+	// Timeout is the timeout of a request, 0 for none.
+	Timeout time.Duration
+	// MaxRetries is the number of retries of a failed request before its VWAP results are dead-lettered.
+	// The webhook is retried with these settings only, not with the Sink ones.
+	MaxRetries int
+	// RetryBackoff is the wait before the first retry, doubled for every next retry up to RetryMaxBackoff.
+	RetryBackoff    time.Duration
+	RetryMaxBackoff time.Duration
+}
+
+func (w Webhook) Enabled() bool {
+	return len(w.URLs) > 0
+}
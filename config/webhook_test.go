@@ -0,0 +1,64 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewWebhook(t *testing.T) {
+	tests := []struct {
+		name    string
+		envVars map[string]string
+		want    Webhook
+	}{
+		{
+			name: "no env vars",
+			want: Webhook{
+				BatchSize:       deftWebhookBatchSize,
+				BatchInterval:   deftWebhookBatchInterval,
+				Timeout:         deftWebhookTimeout,
+				MaxRetries:      deftWebhookMaxRetries,
+				RetryBackoff:    deftWebhookRetryBackoff,
+				RetryMaxBackoff: deftWebhookRetryMaxBackoff,
+			},
+		},
+		{
+			name: "with env vars",
+			envVars: map[string]string{
+				"WEBHOOK_URLS":              "https://a.example.com/vwap|https://b.example.com/vwap",
+				"WEBHOOK_SECRET":            "secret",
+				"WEBHOOK_BATCH_SIZE":        "100",
+				"WEBHOOK_BATCH_INTERVAL":    "500ms",
+				"WEBHOOK_TIMEOUT":           "10s",
+				"WEBHOOK_MAX_RETRIES":       "0",
+				"WEBHOOK_RETRY_BACKOFF":     "1s",
+				"WEBHOOK_RETRY_MAX_BACKOFF": "1m",
+			},
+			want: Webhook{
+				URLs:            []string{"https://a.example.com/vwap", "https://b.example.com/vwap"},
+				Secret:          "secret",
+				BatchSize:       100,
+				BatchInterval:   500 * time.Millisecond,
+				Timeout:         10 * time.Second,
+				MaxRetries:      0,
+				RetryBackoff:    time.Second,
+				RetryMaxBackoff: time.Minute,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				for k, v := range tt.envVars {
+					t.Setenv(k, v)
+				}
+				got := NewWebhook()
+				assert.Equal(t, tt.want, got)
+				assert.Equal(t, len(tt.want.URLs) > 0, got.Enabled())
+			},
+		)
+	}
+}
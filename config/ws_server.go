@@ -0,0 +1,26 @@
+package config
+
+import (
+	"github.com/aprln/vwap-engine/internal/env"
+)
+
+const deftWSServerBufferSize = 256
+
+func NewWSServer() WSServer {
+	return WSServer{
+		Addr:       env.LoadEnvString("WS_SERVER_ADDR", ""),
+		BufferSize: env.MustLoadEnvPositiveInt("WS_SERVER_BUFFER_SIZE", deftWSServerBufferSize),
+	}
+}
+
+type WSServer struct {
+	// Addr is the TCP address the websocket server listens on.
+	Addr string
+	// BufferSize is the number of messages queued for a client before it is disconnected for lagging.
+	BufferSize int
+}
+
+// Enabled tells whether the websocket server is started, which takes an address such as ":8081".
+func (w WSServer) Enabled() bool {
+	return w.Addr != ""
+}
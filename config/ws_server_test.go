@@ -0,0 +1,41 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewWSServer(t *testing.T) {
+	tests := []struct {
+		name    string
+		envVars map[string]string
+		want    WSServer
+	}{
+		{
+			name: "no env vars",
+			want: WSServer{BufferSize: deftWSServerBufferSize},
+		},
+		{
+			name: "with env vars",
+			envVars: map[string]string{
+				"WS_SERVER_ADDR":        ":8081",
+				"WS_SERVER_BUFFER_SIZE": "16",
+			},
+			want: WSServer{Addr: ":8081", BufferSize: 16},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				for k, v := range tt.envVars {
+					t.Setenv(k, v)
+				}
+				got := NewWSServer()
+				assert.Equal(t, tt.want, got)
+				assert.Equal(t, tt.want.Addr != "", got.Enabled())
+			},
+		)
+	}
+}
@@ -0,0 +1,147 @@
+package cross
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/aprln/vwap-engine/config"
+	"github.com/aprln/vwap-engine/model"
+	"github.com/shopspring/decimal"
+)
+
+var basisPoints = decimal.NewFromInt(10_000)
+
+// SetUp parses the configured triangles, whose trading pairs must all be streamed.
+func SetUp(crossCfg config.Cross, vwapCfg config.VWAP) (Deriver, error) {
+	tradingPairs := make(map[string]bool, len(vwapCfg.TradingPairs))
+	for _, tradingPair := range vwapCfg.TradingPairs {
+		tradingPairs[tradingPair] = true
+	}
+
+	triangles := make([]Triangle, 0, len(crossCfg.Triangles))
+	for _, s := range crossCfg.Triangles {
+		t, err := ParseTriangle(s)
+		if err != nil {
+			return Deriver{}, err
+		}
+
+		for _, tradingPair := range []string{t.Observed, t.Numerator, t.Denominator} {
+			if !tradingPairs[tradingPair] {
+				return Deriver{}, fmt.Errorf(`triangle "%s" uses trading pair "%s" which is not streamed`, s, tradingPair)
+			}
+		}
+
+		triangles = append(triangles, t)
+	}
+
+	return New(triangles), nil
+}
+
+func New(triangles []Triangle) Deriver {
+	return Deriver{triangles: triangles}
+}
+
+// Deriver derives cross rates from the VWAP results of several trading pairs.
+// It keeps the latest VWAP of every trading pair, so all the pipelines must be merged into its input.
+type Deriver struct {
+	triangles []Triangle
+}
+
+// Update is an input of the deriver: either a VWAP result, or the removal of the trading pair of the VWAP
+// once its pipeline is done, so the triangles using it stop deriving from its last VWAP.
+type Update struct {
+	VWAP    model.VWAP
+	Removed bool
+}
+
+// GoUpdates copies the VWAP results of a trading pair read from ch as updates, followed by the removal
+// of the trading pair once ch is closed. Being sent on the same channel, the removal always comes last.
+func GoUpdates(tradingPair string, ch <-chan model.VWAP) chan Update {
+	out := make(chan Update, 1)
+
+	go func() {
+		defer close(out)
+
+		for vwap := range ch {
+			out <- Update{VWAP: vwap}
+		}
+		out <- Update{VWAP: model.VWAP{TradingPair: tradingPair}, Removed: true}
+	}()
+
+	return out
+}
+
+func (d Deriver) GoDerive(in <-chan Update) chan model.CrossRate {
+	out := make(chan model.CrossRate, 1)
+
+	go d.deriveForever(in, out)
+
+	return out
+}
+
+// deriveForever publishes the cross rates of every triangle involving the updated trading pair,
+// once the VWAPs of all three trading pairs of the triangle are known.
+func (d Deriver) deriveForever(in <-chan Update, out chan<- model.CrossRate) {
+	defer close(out)
+
+	latest := make(map[string]model.VWAP)
+	for {
+		update, more := <-in
+		if !more {
+			log.Println("no more to read from the vwap channel")
+
+			break
+		}
+
+		vwap := update.VWAP
+		if update.Removed {
+			delete(latest, vwap.TradingPair)
+
+			continue
+		}
+
+		latest[vwap.TradingPair] = vwap
+
+		for _, t := range d.triangles {
+			if !t.involves(vwap.TradingPair) {
+				continue
+			}
+
+			if rate, ok := t.derive(latest, vwap.LastTradeAt); ok {
+				out <- rate
+			}
+		}
+	}
+}
+
+func (t Triangle) derive(latest map[string]model.VWAP, lastTradeAt time.Time) (model.CrossRate, bool) {
+	observed, ok := latest[t.Observed]
+	if !ok {
+		return model.CrossRate{}, false
+	}
+
+	numerator, ok := latest[t.Numerator]
+	if !ok {
+		return model.CrossRate{}, false
+	}
+
+	denominator, ok := latest[t.Denominator]
+	if !ok || denominator.VWAP.IsZero() {
+		return model.CrossRate{}, false
+	}
+
+	implied := numerator.VWAP.Div(denominator.VWAP)
+	if implied.IsZero() {
+		return model.CrossRate{}, false
+	}
+
+	return model.CrossRate{
+		TradingPair:  t.Observed,
+		ImpliedFrom:  [2]string{t.Numerator, t.Denominator},
+		LastTradeAt:  lastTradeAt,
+		Implied:      implied,
+		Observed:     observed.VWAP,
+		DeviationBps: observed.VWAP.Sub(implied).Div(implied).Mul(basisPoints),
+	}, true
+}
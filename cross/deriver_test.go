@@ -0,0 +1,88 @@
+package cross
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aprln/vwap-engine/config"
+	"github.com/aprln/vwap-engine/model"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetUp(t *testing.T) {
+	vwapCfg := config.VWAP{TradingPairs: []string{"BTC-USD", "ETH-USD", "ETH-BTC"}}
+
+	d, err := SetUp(config.Cross{Triangles: []string{"ETH-BTC=ETH-USD/BTC-USD"}}, vwapCfg)
+	require.NoError(t, err)
+	assert.Equal(t, []Triangle{{Observed: "ETH-BTC", Numerator: "ETH-USD", Denominator: "BTC-USD"}}, d.triangles)
+
+	_, err = SetUp(config.Cross{Triangles: []string{"SOL-ETH=SOL-USD/ETH-USD"}}, vwapCfg)
+	require.Error(t, err, "SOL-USD is not streamed")
+
+	_, err = SetUp(config.Cross{Triangles: []string{"banana"}}, vwapCfg)
+	require.Error(t, err)
+}
+
+func TestDeriver_GoDerive(t *testing.T) {
+	d := New([]Triangle{{Observed: "ETH-BTC", Numerator: "ETH-USD", Denominator: "BTC-USD"}})
+	at := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	in := make(chan Update)
+	out := d.GoDerive(in)
+
+	// no cross rate until every trading pair of the triangle is known
+	in <- Update{VWAP: model.VWAP{TradingPair: "ETH-USD", LastTradeAt: at, VWAP: decimal.NewFromInt(1500)}}
+	in <- Update{VWAP: model.VWAP{TradingPair: "BTC-USD", LastTradeAt: at, VWAP: decimal.NewFromInt(20000)}}
+	in <- Update{VWAP: model.VWAP{TradingPair: "ETH-BTC", LastTradeAt: at, VWAP: decimal.RequireFromString("0.0751")}}
+
+	rate := <-out
+	assert.Equal(t, "ETH-BTC", rate.TradingPair)
+	assert.Equal(t, [2]string{"ETH-USD", "BTC-USD"}, rate.ImpliedFrom)
+	assert.Equal(t, at, rate.LastTradeAt)
+	assert.Equal(t, "0.075", rate.Implied.String())
+	assert.Equal(t, "0.0751", rate.Observed.String())
+	assert.Equal(t, "13.333333333333", rate.DeviationBps.String())
+
+	// a leg update publishes a new cross rate
+	in <- Update{VWAP: model.VWAP{TradingPair: "BTC-USD", LastTradeAt: at.Add(time.Second), VWAP: decimal.NewFromInt(15000)}}
+
+	rate = <-out
+	assert.Equal(t, at.Add(time.Second), rate.LastTradeAt)
+	assert.Equal(t, "0.1", rate.Implied.String())
+	assert.Equal(t, "-2490", rate.DeviationBps.String())
+
+	// a removed leg stops the cross rates until it is added again
+	in <- Update{VWAP: model.VWAP{TradingPair: "BTC-USD"}, Removed: true}
+	in <- Update{VWAP: model.VWAP{TradingPair: "ETH-USD", LastTradeAt: at, VWAP: decimal.NewFromInt(1500)}}
+	in <- Update{VWAP: model.VWAP{TradingPair: "BTC-USD", LastTradeAt: at, VWAP: decimal.NewFromInt(20000)}}
+
+	rate = <-out
+	assert.Equal(t, "0.075", rate.Implied.String(), "no cross rate is derived from the removed leg")
+
+	// trading pairs outside of the triangle are ignored
+	in <- Update{VWAP: model.VWAP{TradingPair: "SOL-USD", LastTradeAt: at, VWAP: decimal.NewFromInt(30)}}
+	close(in)
+
+	_, more := <-out
+	assert.False(t, more)
+}
+
+func TestGoUpdates(t *testing.T) {
+	ch := make(chan model.VWAP, 1)
+	ch <- model.VWAP{TradingPair: "BTC-USD", VWAP: decimal.NewFromInt(1)}
+	close(ch)
+
+	var got []Update
+	for update := range GoUpdates("BTC-USD", ch) {
+		got = append(got, update)
+	}
+
+	assert.Equal(
+		t, []Update{
+			{VWAP: model.VWAP{TradingPair: "BTC-USD", VWAP: decimal.NewFromInt(1)}},
+			{VWAP: model.VWAP{TradingPair: "BTC-USD"}, Removed: true},
+		}, got,
+	)
+}
@@ -0,0 +1,50 @@
+package cross
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Triangle implies the rate of the observed trading pair from two legs, e.g. ETH-BTC from ETH-USD / BTC-USD.
+type Triangle struct {
+	Observed    string
+	Numerator   string
+	Denominator string
+}
+
+// ParseTriangle parses "OBSERVED=NUMERATOR/DENOMINATOR", e.g. "ETH-BTC=ETH-USD/BTC-USD".
+func ParseTriangle(s string) (Triangle, error) {
+	observed, legs, ok := strings.Cut(s, "=")
+	if !ok {
+		return Triangle{}, fmt.Errorf(`invalid triangle "%s", want OBSERVED=NUMERATOR/DENOMINATOR`, s)
+	}
+
+	numerator, denominator, ok := strings.Cut(legs, "/")
+	if !ok {
+		return Triangle{}, fmt.Errorf(`invalid triangle "%s", want OBSERVED=NUMERATOR/DENOMINATOR`, s)
+	}
+
+	t := Triangle{
+		Observed:    strings.TrimSpace(observed),
+		Numerator:   strings.TrimSpace(numerator),
+		Denominator: strings.TrimSpace(denominator),
+	}
+
+	if t.Observed == "" || t.Numerator == "" || t.Denominator == "" {
+		return Triangle{}, fmt.Errorf(`invalid triangle "%s", a trading pair is empty`, s)
+	}
+
+	if t.Observed == t.Numerator || t.Observed == t.Denominator || t.Numerator == t.Denominator {
+		return Triangle{}, fmt.Errorf(`invalid triangle "%s", its trading pairs must be different`, s)
+	}
+
+	return t, nil
+}
+
+func (t Triangle) involves(tradingPair string) bool {
+	return tradingPair == t.Observed || tradingPair == t.Numerator || tradingPair == t.Denominator
+}
+
+func (t Triangle) String() string {
+	return fmt.Sprintf("%s=%s/%s", t.Observed, t.Numerator, t.Denominator)
+}
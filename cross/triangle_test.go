@@ -0,0 +1,47 @@
+package cross
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTriangle(t *testing.T) {
+	tests := []struct {
+		s       string
+		want    Triangle
+		wantErr bool
+	}{
+		{
+			s:    "ETH-BTC=ETH-USD/BTC-USD",
+			want: Triangle{Observed: "ETH-BTC", Numerator: "ETH-USD", Denominator: "BTC-USD"},
+		},
+		{
+			s:    " ETH-BTC = ETH-USD / BTC-USD ",
+			want: Triangle{Observed: "ETH-BTC", Numerator: "ETH-USD", Denominator: "BTC-USD"},
+		},
+		{s: "ETH-BTC", wantErr: true},
+		{s: "ETH-BTC=ETH-USD", wantErr: true},
+		{s: "ETH-BTC=/BTC-USD", wantErr: true},
+		{s: "ETH-BTC=ETH-BTC/BTC-USD", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.s, func(t *testing.T) {
+				got, err := ParseTriangle(tt.s)
+
+				if tt.wantErr {
+					require.Error(t, err)
+
+					return
+				}
+
+				require.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+				assert.Equal(t, "ETH-BTC=ETH-USD/BTC-USD", got.String())
+			},
+		)
+	}
+}
@@ -58,6 +58,11 @@ type Feed struct {
 	tradingPair string
 }
 
+// Close closes the feed's connection, which ends the feed and drains the pipeline behind it.
+func (f Feed) Close() error {
+	return f.wsClient.Close()
+}
+
 func (f Feed) GoFeed() chan model.Trade {
 	out := make(chan model.Trade, 1)
 
@@ -81,6 +86,7 @@ func (f Feed) feedForever(out chan<- model.Trade) {
 		}
 
 		out <- model.Trade{
+			ID:          resp.TradeID,
 			TradingPair: resp.TradingPair,
 			Price:       resp.Price,
 			Size:        resp.Size,
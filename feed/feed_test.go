@@ -24,6 +24,7 @@ func TestFeed_GoFeed(t *testing.T) {
 	assert.Equal(
 		t,
 		model.Trade{
+			ID:          mockResp.TradeID,
 			TradingPair: mockResp.TradingPair,
 			Size:        mockResp.Size,
 			Price:       mockResp.Price,
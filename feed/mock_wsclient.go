@@ -35,6 +35,7 @@ func (m *MockWSClient) ReadTrade() (wsclient.TradeResponse, bool, error) {
 
 func (m *MockWSClient) GetTradeResponse() wsclient.TradeResponse {
 	return wsclient.TradeResponse{
+		TradeID:     1,
 		TradingPair: "BTC-USD",
 		Size:        decimal.NewFromFloat(1.1),
 		Price:       decimal.NewFromFloat(2.2),
@@ -4,6 +4,9 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
 )
 
 const delimiter = "|"
@@ -43,3 +46,117 @@ func MustLoadEnvPositiveInt(key string, defVal int) int {
 
 	return intVal
 }
+
+func MustLoadEnvNonNegativeInt(key string, defVal int) int {
+	val, found := os.LookupEnv(key)
+	if !found {
+		return defVal
+	}
+
+	intVal, err := strconv.Atoi(val)
+	if err != nil {
+		panic("invalid int value: " + val)
+	}
+
+	if intVal < 0 {
+		panic("invalid non-negative int value: " + val)
+	}
+
+	return intVal
+}
+
+func MustLoadEnvBool(key string, defVal bool) bool {
+	val, found := os.LookupEnv(key)
+	if !found {
+		return defVal
+	}
+
+	boolVal, err := strconv.ParseBool(val)
+	if err != nil {
+		panic("invalid bool value: " + val)
+	}
+
+	return boolVal
+}
+
+func MustLoadEnvNonNegativeDuration(key string, defVal time.Duration) time.Duration {
+	val, found := os.LookupEnv(key)
+	if !found {
+		return defVal
+	}
+
+	d, err := time.ParseDuration(val)
+	if err != nil {
+		panic("invalid duration value: " + val)
+	}
+
+	if d < 0 {
+		panic("invalid non-negative duration value: " + val)
+	}
+
+	return d
+}
+
+func MustLoadEnvDurationSlice(key string, defVal []time.Duration) []time.Duration {
+	val, found := os.LookupEnv(key)
+	if !found {
+		return defVal
+	}
+
+	var durations []time.Duration
+	for _, s := range strings.Split(val, delimiter) {
+		if s == "" {
+			continue
+		}
+
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			panic("invalid duration value: " + s)
+		}
+
+		if d <= 0 {
+			panic("invalid positive duration value: " + s)
+		}
+
+		durations = append(durations, d)
+	}
+
+	return durations
+}
+
+func MustLoadEnvDecimalSlice(key string, defVal []decimal.Decimal) []decimal.Decimal {
+	val, found := os.LookupEnv(key)
+	if !found {
+		return defVal
+	}
+
+	var decimals []decimal.Decimal
+	for _, s := range strings.Split(val, delimiter) {
+		if s == "" {
+			continue
+		}
+
+		d, err := decimal.NewFromString(s)
+		if err != nil {
+			panic("invalid decimal value: " + s)
+		}
+
+		decimals = append(decimals, d)
+	}
+
+	return decimals
+}
+
+func MustLoadEnvDecimal(key string, defVal decimal.Decimal) decimal.Decimal {
+	val, found := os.LookupEnv(key)
+	if !found {
+		return defVal
+	}
+
+	d, err := decimal.NewFromString(val)
+	if err != nil {
+		panic("invalid decimal value: " + val)
+	}
+
+	return d
+}
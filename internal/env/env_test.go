@@ -2,7 +2,9 @@ package env
 
 import (
 	"testing"
+	"time"
 
+	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -144,3 +146,340 @@ func TestMustLoadEnvPositiveInt(t *testing.T) {
 		)
 	}
 }
+
+func TestMustLoadEnvNonNegativeInt(t *testing.T) {
+	testCases := []struct {
+		name      string
+		key       string
+		defVal    int
+		envVal    string
+		want      int
+		wantPanic bool
+	}{
+		{
+			name:      "invalid with string env var",
+			key:       "BANANA",
+			defVal:    10,
+			envVal:    "monkey",
+			wantPanic: true,
+		},
+		{
+			name:      "invalid with negative env var",
+			key:       "BANANA",
+			defVal:    10,
+			envVal:    "-2",
+			wantPanic: true,
+		},
+		{
+			name:   "valid with zero env var",
+			key:    "BANANA",
+			defVal: 10,
+			envVal: "0",
+			want:   0,
+		},
+		{
+			name:   "valid with no env var",
+			key:    "BANANA",
+			defVal: 10,
+			want:   10,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(
+			tc.name, func(t *testing.T) {
+				if tc.envVal != "" {
+					t.Setenv(tc.key, tc.envVal)
+				}
+
+				if tc.wantPanic {
+					require.Panics(
+						t, func() {
+							MustLoadEnvNonNegativeInt(tc.key, tc.defVal)
+						},
+					)
+
+					return
+				}
+
+				got := MustLoadEnvNonNegativeInt(tc.key, tc.defVal)
+				assert.Equal(t, tc.want, got)
+			},
+		)
+	}
+}
+
+func TestMustLoadEnvBool(t *testing.T) {
+	testCases := []struct {
+		name      string
+		key       string
+		defVal    bool
+		envVal    string
+		want      bool
+		wantPanic bool
+	}{
+		{
+			name:      "invalid with string env var",
+			key:       "BANANA",
+			envVal:    "monkey",
+			wantPanic: true,
+		},
+		{
+			name:   "valid with env var",
+			key:    "BANANA",
+			envVal: "true",
+			want:   true,
+		},
+		{
+			name:   "valid with no env var",
+			key:    "BANANA",
+			defVal: true,
+			want:   true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(
+			tc.name, func(t *testing.T) {
+				if tc.envVal != "" {
+					t.Setenv(tc.key, tc.envVal)
+				}
+
+				if tc.wantPanic {
+					require.Panics(
+						t, func() {
+							MustLoadEnvBool(tc.key, tc.defVal)
+						},
+					)
+
+					return
+				}
+
+				got := MustLoadEnvBool(tc.key, tc.defVal)
+				assert.Equal(t, tc.want, got)
+			},
+		)
+	}
+}
+
+func TestMustLoadEnvNonNegativeDuration(t *testing.T) {
+	testCases := []struct {
+		name      string
+		key       string
+		defVal    time.Duration
+		envVal    string
+		want      time.Duration
+		wantPanic bool
+	}{
+		{
+			name:      "invalid with string env var",
+			key:       "BANANA",
+			envVal:    "monkey",
+			wantPanic: true,
+		},
+		{
+			name:      "invalid with negative env var",
+			key:       "BANANA",
+			envVal:    "-1s",
+			wantPanic: true,
+		},
+		{
+			name:   "valid with env var",
+			key:    "BANANA",
+			envVal: "1m30s",
+			want:   90 * time.Second,
+		},
+		{
+			name:   "valid with no env var",
+			key:    "BANANA",
+			defVal: time.Hour,
+			want:   time.Hour,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(
+			tc.name, func(t *testing.T) {
+				if tc.envVal != "" {
+					t.Setenv(tc.key, tc.envVal)
+				}
+
+				if tc.wantPanic {
+					require.Panics(
+						t, func() {
+							MustLoadEnvNonNegativeDuration(tc.key, tc.defVal)
+						},
+					)
+
+					return
+				}
+
+				got := MustLoadEnvNonNegativeDuration(tc.key, tc.defVal)
+				assert.Equal(t, tc.want, got)
+			},
+		)
+	}
+}
+
+func TestMustLoadEnvDurationSlice(t *testing.T) {
+	testCases := []struct {
+		name      string
+		key       string
+		defVal    []time.Duration
+		envVal    string
+		want      []time.Duration
+		wantPanic bool
+	}{
+		{
+			name:      "invalid with string env var",
+			key:       "BANANA",
+			envVal:    "monkey",
+			wantPanic: true,
+		},
+		{
+			name:      "invalid with negative env var",
+			key:       "BANANA",
+			envVal:    "1m|-1s",
+			wantPanic: true,
+		},
+		{
+			name:   "valid with env var",
+			key:    "BANANA",
+			envVal: "1s|5m|",
+			want:   []time.Duration{time.Second, 5 * time.Minute},
+		},
+		{
+			name:   "valid with no env var",
+			key:    "BANANA",
+			defVal: []time.Duration{time.Hour},
+			want:   []time.Duration{time.Hour},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(
+			tc.name, func(t *testing.T) {
+				if tc.envVal != "" {
+					t.Setenv(tc.key, tc.envVal)
+				}
+
+				if tc.wantPanic {
+					require.Panics(
+						t, func() {
+							MustLoadEnvDurationSlice(tc.key, tc.defVal)
+						},
+					)
+
+					return
+				}
+
+				got := MustLoadEnvDurationSlice(tc.key, tc.defVal)
+				assert.Equal(t, tc.want, got)
+			},
+		)
+	}
+}
+
+func TestMustLoadEnvDecimalSlice(t *testing.T) {
+	testCases := []struct {
+		name      string
+		key       string
+		defVal    []decimal.Decimal
+		envVal    string
+		want      []string
+		wantPanic bool
+	}{
+		{
+			name:      "invalid with string env var",
+			key:       "BANANA",
+			envVal:    "1|monkey",
+			wantPanic: true,
+		},
+		{
+			name:   "valid with env var",
+			key:    "BANANA",
+			envVal: "1|2.5|",
+			want:   []string{"1", "2.5"},
+		},
+		{
+			name:   "valid with no env var",
+			key:    "BANANA",
+			defVal: []decimal.Decimal{decimal.NewFromInt(3)},
+			want:   []string{"3"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(
+			tc.name, func(t *testing.T) {
+				if tc.envVal != "" {
+					t.Setenv(tc.key, tc.envVal)
+				}
+
+				if tc.wantPanic {
+					require.Panics(
+						t, func() {
+							MustLoadEnvDecimalSlice(tc.key, tc.defVal)
+						},
+					)
+
+					return
+				}
+
+				got := MustLoadEnvDecimalSlice(tc.key, tc.defVal)
+				gotStrings := make([]string, len(got))
+				for i, d := range got {
+					gotStrings[i] = d.String()
+				}
+				assert.Equal(t, tc.want, gotStrings)
+			},
+		)
+	}
+}
+
+func TestMustLoadEnvDecimal(t *testing.T) {
+	testCases := []struct {
+		name      string
+		key       string
+		defVal    decimal.Decimal
+		envVal    string
+		want      string
+		wantPanic bool
+	}{
+		{
+			name:      "invalid with string env var",
+			key:       "BANANA",
+			envVal:    "monkey",
+			wantPanic: true,
+		},
+		{
+			name:   "valid with env var",
+			key:    "BANANA",
+			envVal: "50.5",
+			want:   "50.5",
+		},
+		{
+			name:   "valid with no env var",
+			key:    "BANANA",
+			defVal: decimal.NewFromInt(3),
+			want:   "3",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(
+			tc.name, func(t *testing.T) {
+				if tc.envVal != "" {
+					t.Setenv(tc.key, tc.envVal)
+				}
+
+				if tc.wantPanic {
+					require.Panics(
+						t, func() {
+							MustLoadEnvDecimal(tc.key, tc.defVal)
+						},
+					)
+
+					return
+				}
+
+				got := MustLoadEnvDecimal(tc.key, tc.defVal)
+				assert.Equal(t, tc.want, got.String())
+			},
+		)
+	}
+}
@@ -0,0 +1,165 @@
+package pipe
+
+import (
+	"fmt"
+	"log"
+	"sync/atomic"
+)
+
+// Overflow is what a Buffer does with a value once it is full.
+type Overflow int
+
+const (
+	// OverflowBlock waits for the reader to make room, holding back the writer. No value is dropped.
+	OverflowBlock Overflow = iota
+	// OverflowDropOldest drops the oldest buffered value to make room for the new one.
+	OverflowDropOldest
+	// OverflowDropNewest drops the new value.
+	OverflowDropNewest
+	// OverflowConflate keeps at most one value per key: a new value replaces the buffered value of its key, if any,
+	// and otherwise the oldest buffered value is dropped once the buffer is full.
+	OverflowConflate
+)
+
+var overflows = map[string]Overflow{
+	"block":       OverflowBlock,
+	"drop_oldest": OverflowDropOldest,
+	"drop_newest": OverflowDropNewest,
+	"conflate":    OverflowConflate,
+}
+
+// ParseOverflow parses "block", "drop_oldest", "drop_newest" or "conflate". An empty policy is the same as "block".
+func ParseOverflow(s string) (Overflow, error) {
+	if s == "" {
+		return OverflowBlock, nil
+	}
+
+	overflow, ok := overflows[s]
+	if !ok {
+		return OverflowBlock, fmt.Errorf(`overflow policy "%s" is unsupported`, s)
+	}
+
+	return overflow, nil
+}
+
+// NewBuffer creates a Buffer of the given capacity. key is only used by OverflowConflate.
+func NewBuffer[T any](name string, capacity int, overflow Overflow, key func(T) string) *Buffer[T] {
+	return &Buffer[T]{
+		name:     name,
+		capacity: capacity,
+		overflow: overflow,
+		key:      key,
+	}
+}
+
+// Buffer sits between two stages, so that a slow reader does not hold back the writer unless the overflow policy
+// is OverflowBlock. It counts the values it drops.
+type Buffer[T any] struct {
+	name     string
+	capacity int
+	overflow Overflow
+	key      func(T) string
+	dropped  atomic.Uint64
+}
+
+// Dropped returns the number of values dropped so far. It is safe to call while the buffer is running.
+func (b *Buffer[T]) Dropped() uint64 {
+	return b.dropped.Load()
+}
+
+// GoBuffer copies every value read from in to the returned channel, buffering up to the capacity in between.
+// The returned channel is closed once in is closed and the buffered values have been read.
+func (b *Buffer[T]) GoBuffer(in <-chan T) chan T {
+	// the output is unbuffered, so the values waiting for the reader are all in the buffer and subject to its policy
+	out := make(chan T)
+
+	go b.bufferForever(in, out)
+
+	return out
+}
+
+func (b *Buffer[T]) bufferForever(in <-chan T, out chan<- T) {
+	defer close(out)
+
+	queue := make([]T, 0, b.capacity)
+	// overflowing is true from a value being dropped until the reader has caught up, to log it once
+	overflowing := false
+	for {
+		var send chan<- T
+		var next T
+		if len(queue) > 0 {
+			send, next = out, queue[0]
+		}
+
+		select {
+		case v, more := <-in:
+			if !more {
+				for _, v := range queue {
+					out <- v
+				}
+				if dropped := b.Dropped(); dropped > 0 {
+					log.Printf("%s buffer dropped %d values", b.name, dropped)
+				}
+
+				return
+			}
+
+			var dropped bool
+			queue, dropped = b.push(queue, v, out)
+			if dropped && !overflowing {
+				log.Printf("%s buffer overflowed, dropping values until the reader catches up", b.name)
+				overflowing = true
+			}
+
+		case send <- next:
+			queue = pop(queue)
+			if len(queue) == 0 {
+				overflowing = false
+			}
+		}
+	}
+}
+
+// push adds the value to the queue according to the overflow policy, and tells whether a value was dropped.
+func (b *Buffer[T]) push(queue []T, v T, out chan<- T) ([]T, bool) {
+	if b.overflow == OverflowConflate {
+		k := b.key(v)
+		for i := range queue {
+			if b.key(queue[i]) == k {
+				queue[i] = v
+				b.dropped.Add(1)
+
+				return queue, true
+			}
+		}
+	}
+
+	if len(queue) < b.capacity {
+		return append(queue, v), false
+	}
+
+	switch b.overflow {
+	case OverflowBlock:
+		out <- queue[0]
+
+		return append(pop(queue), v), false
+
+	case OverflowDropNewest:
+		b.dropped.Add(1)
+
+		return queue, true
+
+	default:
+		b.dropped.Add(1)
+
+		return append(pop(queue), v), true
+	}
+}
+
+// pop removes the first value of the queue, without keeping a reference to it.
+func pop[T any](queue []T) []T {
+	var zero T
+	queue[0] = zero
+
+	return queue[1:]
+}
@@ -0,0 +1,131 @@
+package pipe
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseOverflow(t *testing.T) {
+	tests := []struct {
+		s       string
+		want    Overflow
+		wantErr bool
+	}{
+		{s: "", want: OverflowBlock},
+		{s: "block", want: OverflowBlock},
+		{s: "drop_oldest", want: OverflowDropOldest},
+		{s: "drop_newest", want: OverflowDropNewest},
+		{s: "conflate", want: OverflowConflate},
+		{s: "drop", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.s, func(t *testing.T) {
+				got, err := ParseOverflow(tt.s)
+				if tt.wantErr {
+					assert.Error(t, err)
+
+					return
+				}
+				require.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			},
+		)
+	}
+}
+
+func TestBuffer_GoBuffer(t *testing.T) {
+	tests := []struct {
+		name        string
+		overflow    Overflow
+		in          []string
+		want        []string
+		wantDropped uint64
+	}{
+		{
+			name:     "no overflow",
+			overflow: OverflowDropOldest,
+			in:       []string{"BTC:1", "ETH:1"},
+			want:     []string{"BTC:1", "ETH:1"},
+		},
+		{
+			name:        "drop oldest",
+			overflow:    OverflowDropOldest,
+			in:          []string{"BTC:1", "BTC:2", "BTC:3", "BTC:4"},
+			want:        []string{"BTC:3", "BTC:4"},
+			wantDropped: 2,
+		},
+		{
+			name:        "drop newest",
+			overflow:    OverflowDropNewest,
+			in:          []string{"BTC:1", "BTC:2", "BTC:3", "BTC:4"},
+			want:        []string{"BTC:1", "BTC:2"},
+			wantDropped: 2,
+		},
+		{
+			name:        "conflate",
+			overflow:    OverflowConflate,
+			in:          []string{"BTC:1", "ETH:1", "BTC:2", "SOL:1"},
+			want:        []string{"ETH:1", "SOL:1"},
+			wantDropped: 2,
+		},
+		{
+			name:        "conflate in place",
+			overflow:    OverflowConflate,
+			in:          []string{"BTC:1", "ETH:1", "BTC:2"},
+			want:        []string{"BTC:2", "ETH:1"},
+			wantDropped: 1,
+		},
+	}
+
+	pair := func(v string) string { return strings.Split(v, ":")[0] }
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				b := NewBuffer("test", 2, tt.overflow, pair)
+				// in is unbuffered, so every value has been pushed to the buffer before the next one is sent
+				in := make(chan string)
+				out := b.GoBuffer(in)
+
+				for _, v := range tt.in {
+					in <- v
+				}
+				close(in)
+
+				var got []string
+				for v := range out {
+					got = append(got, v)
+				}
+				assert.Equal(t, tt.want, got)
+				assert.Equal(t, tt.wantDropped, b.Dropped())
+			},
+		)
+	}
+}
+
+func TestBuffer_GoBuffer_block(t *testing.T) {
+	b := NewBuffer[int]("test", 2, OverflowBlock, nil)
+	in := make(chan int)
+	out := b.GoBuffer(in)
+
+	go func() {
+		for i := 0; i < 100; i++ {
+			in <- i
+		}
+		close(in)
+	}()
+
+	var got []int
+	for v := range out {
+		got = append(got, v)
+	}
+	require.Len(t, got, 100, "no value is dropped")
+	for i, v := range got {
+		assert.Equal(t, i, v)
+	}
+	assert.Zero(t, b.Dropped())
+}
@@ -0,0 +1,66 @@
+package pipe
+
+import "sync"
+
+// GoMerge copies every value read from the inputs to the returned channel, which is closed once all inputs are.
+// Values of the same input keep their order, values of different inputs are interleaved as they arrive.
+func GoMerge[T any](ins ...<-chan T) chan T {
+	out := make(chan T, 1)
+
+	var wg sync.WaitGroup
+	wg.Add(len(ins))
+	for _, in := range ins {
+		go func(in <-chan T) {
+			defer wg.Done()
+
+			for v := range in {
+				out <- v
+			}
+		}(in)
+	}
+
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+
+	return out
+}
+
+// NewMerger creates a Merger, for inputs that come and go while the merged output is read.
+func NewMerger[T any]() *Merger[T] {
+	return &Merger[T]{out: make(chan T, 1)}
+}
+
+// Merger copies every value read from its inputs to its output, like GoMerge, but inputs can be added at any time.
+// The output is closed once Close has been called and all the inputs are closed.
+type Merger[T any] struct {
+	out chan T
+	wg  sync.WaitGroup
+}
+
+// Add starts copying the values read from in to the output. It must not be called after Close.
+func (m *Merger[T]) Add(in <-chan T) {
+	m.wg.Add(1)
+
+	go func() {
+		defer m.wg.Done()
+
+		for v := range in {
+			m.out <- v
+		}
+	}()
+}
+
+// Out returns the merged output.
+func (m *Merger[T]) Out() <-chan T {
+	return m.out
+}
+
+// Close closes the output once all the inputs added so far are closed.
+func (m *Merger[T]) Close() {
+	go func() {
+		m.wg.Wait()
+		close(m.out)
+	}()
+}
@@ -0,0 +1,65 @@
+package pipe
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGoMerge(t *testing.T) {
+	in1 := make(chan int, 2)
+	in1 <- 1
+	in1 <- 2
+	close(in1)
+
+	in2 := make(chan int, 1)
+	in2 <- 3
+	close(in2)
+
+	var got []int
+	for v := range GoMerge[int](in1, in2) {
+		got = append(got, v)
+	}
+
+	sort.Ints(got)
+	assert.Equal(t, []int{1, 2, 3}, got)
+}
+
+func TestGoMerge_noInput(t *testing.T) {
+	_, more := <-GoMerge[int]()
+	assert.False(t, more)
+}
+
+func TestMerger(t *testing.T) {
+	m := NewMerger[int]()
+
+	in1 := make(chan int, 1)
+	in1 <- 1
+	close(in1)
+	m.Add(in1)
+
+	in2 := make(chan int)
+	m.Add(in2)
+
+	assert.Equal(t, 1, <-m.Out())
+
+	// an input added while the output is read
+	in3 := make(chan int, 1)
+	in3 <- 3
+	m.Add(in3)
+	assert.Equal(t, 3, <-m.Out())
+
+	m.Close()
+	close(in3)
+
+	select {
+	case <-m.Out():
+		t.Fatal("the output is closed before all the inputs are")
+	default:
+	}
+
+	close(in2)
+	_, more := <-m.Out()
+	assert.False(t, more)
+}
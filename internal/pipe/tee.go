@@ -0,0 +1,26 @@
+package pipe
+
+// GoTee copies every value read from in to each of the n returned channels.
+// A slow reader on one of the outputs holds back the others, the same way a slow stage holds back a pipeline.
+func GoTee[T any](in <-chan T, n int) []chan T {
+	outs := make([]chan T, n)
+	for i := range outs {
+		outs[i] = make(chan T, 1)
+	}
+
+	go func() {
+		defer func() {
+			for _, out := range outs {
+				close(out)
+			}
+		}()
+
+		for v := range in {
+			for _, out := range outs {
+				out <- v
+			}
+		}
+	}()
+
+	return outs
+}
@@ -0,0 +1,34 @@
+package pipe
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGoTee(t *testing.T) {
+	in := make(chan int, 3)
+	in <- 1
+	in <- 2
+	in <- 3
+	close(in)
+
+	outs := GoTee(in, 2)
+	require.Len(t, outs, 2)
+
+	var got [2][]int
+	for i := 0; i < 3; i++ {
+		for j, out := range outs {
+			got[j] = append(got[j], <-out)
+		}
+	}
+
+	assert.Equal(t, []int{1, 2, 3}, got[0])
+	assert.Equal(t, []int{1, 2, 3}, got[1])
+
+	for _, out := range outs {
+		_, more := <-out
+		assert.False(t, more)
+	}
+}
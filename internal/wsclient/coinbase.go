@@ -36,6 +36,7 @@ type CoinbaseRequest struct {
 
 type CoinbaseMatchesResponse struct {
 	Type      CoinbaseResponseType `json:"type"`
+	TradeID   int64                `json:"trade_id"`
 	ProductID CoinbaseProductID    `json:"product_id"`
 	Size      decimal.Decimal      `json:"size"`
 	Price     decimal.Decimal      `json:"price"`
@@ -102,6 +103,7 @@ func (c *Coinbase) ReadTrade() (TradeResponse, bool, error) {
 	}
 
 	return TradeResponse{
+		TradeID:     resp.TradeID,
 		TradingPair: string(resp.ProductID),
 		Size:        resp.Size,
 		Price:       resp.Price,
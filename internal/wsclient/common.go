@@ -7,6 +7,7 @@ import (
 )
 
 type TradeResponse struct {
+	TradeID     int64
 	TradingPair string
 	Size        decimal.Decimal
 	Price       decimal.Decimal
@@ -1,58 +1,579 @@
 package main
 
 import (
+	"errors"
+	"fmt"
+	"io/fs"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
+	"reflect"
+	"strings"
 	"sync"
+	"syscall"
+	"time"
 
+	"github.com/aprln/vwap-engine/alert"
+	"github.com/aprln/vwap-engine/api"
+	"github.com/aprln/vwap-engine/bar"
 	"github.com/aprln/vwap-engine/config"
+	"github.com/aprln/vwap-engine/cross"
 	"github.com/aprln/vwap-engine/feed"
+	"github.com/aprln/vwap-engine/internal/pipe"
+	"github.com/aprln/vwap-engine/model"
 	"github.com/aprln/vwap-engine/processor"
 	"github.com/aprln/vwap-engine/publisher"
+	"github.com/aprln/vwap-engine/snapshot"
+	"github.com/aprln/vwap-engine/supervisor"
+	"github.com/joho/godotenv"
 	_ "github.com/joho/godotenv/autoload"
 )
 
 func main() {
 	interrupt := make(chan os.Signal, 1)
 	signal.Notify(interrupt, os.Interrupt)
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+
+	var wg sync.WaitGroup
+
+	sup, shared := start(&wg)
+
+	go func() {
+		for range reload {
+			reloadConfig(sup, shared)
+		}
+	}()
+
 	go func() {
+		<-interrupt
+		// closing the feeds drains the pipelines, so the processors snapshot their state before exiting
+		sup.Shutdown()
+
+		// a second interrupt exits right away
 		<-interrupt
 		os.Exit(0)
 	}()
 
-	var wg sync.WaitGroup
+	sup.Wait()
+	shared.close()
+	wg.Wait()
+}
 
-	start(&wg)
+// sharedStages are the stages and stores shared by the pipelines of every trading pair.
+type sharedStages struct {
+	// cfg is the config the shared stages were set up with, which a reload does not change
+	cfg           config.Config
+	snapshotStore processor.SnapshotStore
+	// crossIns merges the VWAP results of every trading pair into the cross rate stage
+	crossIns *pipe.Merger[cross.Update]
+	alerts   *alert.Engine
+	wsServer *publisher.WSServerSender
+	// latest keeps the latest VWAP of every trading pair for the HTTP server, and stats their counters
+	latest     *api.Store
+	stats      *api.Stats
+	stream     *publisher.SSESender
+	apiServer  *api.Server
+	grpcServer *publisher.GRPCSender
+	fileSink   *publisher.FileSender
+	webhook    *publisher.WebhookSender
+	// sinks wrap the websocket, SSE, gRPC, file and webhook senders, each with its own queue and retries
+	sinks      []*publisher.Sink
+	deadLetter *publisher.DeadLetter
+}
 
-	wg.Wait()
+// close ends the shared stages, once the supervisor has no pipeline left.
+func (s sharedStages) close() {
+	if s.crossIns != nil {
+		s.crossIns.Close()
+	}
+
+	// the sinks send their queued messages before their servers and files are closed
+	for _, sink := range s.sinks {
+		sink.Close()
+	}
+
+	if s.wsServer != nil {
+		if err := s.wsServer.Close(); err != nil {
+			log.Printf("failed to close the websocket server: %v", err)
+		}
+	}
+
+	if s.stream != nil {
+		s.stream.Close()
+	}
+
+	if s.apiServer != nil {
+		if err := s.apiServer.Close(); err != nil {
+			log.Printf("failed to close the HTTP server: %v", err)
+		}
+	}
+
+	if s.grpcServer != nil {
+		s.grpcServer.Close()
+	}
+
+	if s.fileSink != nil {
+		if err := s.fileSink.Close(); err != nil {
+			log.Printf("failed to close the file sink: %v", err)
+		}
+	}
+
+	if s.webhook != nil {
+		s.webhook.Close()
+	}
+
+	if s.deadLetter != nil {
+		if err := s.deadLetter.Close(); err != nil {
+			log.Printf("failed to close the dead letter file: %v", err)
+		}
+	}
 }
 
-func start(wg *sync.WaitGroup) {
+// start starts the pipeline of every configured trading pair under a supervisor, and the shared stages.
+func start(wg *sync.WaitGroup) (*supervisor.Supervisor, sharedStages) {
 	cfg := config.NewConfig()
 
-	wg.Add(len(cfg.VWAP.TradingPairs))
+	shared := sharedStages{cfg: cfg}
+	if cfg.Snapshot.Enabled() {
+		shared.snapshotStore = setupSnapshot(cfg)
+	}
+
+	if cfg.Cross.Enabled() {
+		shared.crossIns = pipe.NewMerger[cross.Update]()
+		deriver := setupCross(cfg)
+		wg.Add(1)
+		setupPublisher(cfg).GoPublishCrossRates(deriver.GoDerive(shared.crossIns.Out()), wg)
+	}
+
+	if cfg.Alert.Enabled() {
+		engine := setupAlert(cfg)
+		shared.alerts = &engine
+	}
+
+	deadLetter, err := publisher.SetUpDeadLetter(cfg.Sink)
+	if err != nil {
+		log.Fatalf("failed to set up the dead letter file: %v", err)
+	}
+	shared.deadLetter = deadLetter
+	addSink := func(name string, s publisher.Sender, sinkCfg config.Sink) {
+		shared.sinks = append(shared.sinks, publisher.NewSink(name, s, sinkCfg, deadLetter))
+	}
+
+	if cfg.WSServer.Enabled() {
+		shared.wsServer = setupWSServer(cfg)
+		addSink("websocket", shared.wsServer, cfg.Sink)
+	}
+
+	if cfg.HTTPServer.Enabled() {
+		shared.latest = api.NewStore(time.Now)
+		shared.stats = api.NewStats()
+		shared.stream = publisher.SetUpSSE(cfg.HTTPServer)
+		addSink("sse", shared.stream, cfg.Sink)
+	}
+
+	if cfg.GRPCServer.Enabled() {
+		shared.grpcServer = setupGRPCServer(cfg)
+		addSink("grpc", shared.grpcServer, cfg.Sink)
+	}
+
+	if cfg.FileSink.Enabled() {
+		shared.fileSink = setupFileSink(cfg)
+		addSink("file", shared.fileSink, cfg.Sink)
+	}
+
+	if cfg.Webhook.Enabled() {
+		shared.webhook = setupWebhook(cfg, deadLetter)
+		// the webhook retries every URL on its own, see WEBHOOK_MAX_RETRIES
+		addSink("webhook", shared.webhook, cfg.Sink.WithoutRetries())
+	}
+
+	// the pipelines only use the stages set up so far, so the HTTP server can be set up afterwards
+	pipelineStages := shared
+	sup := supervisor.New(
+		cfg, func(cfg config.Config, tradingPair string) (supervisor.Pipeline, error) {
+			return startPipeline(cfg, tradingPair, pipelineStages)
+		},
+	)
+
+	// the admin endpoints add and remove trading pairs with the supervisor
+	if cfg.HTTPServer.Enabled() {
+		shared.apiServer = setupAPI(cfg, shared.latest, shared.stats, shared.stream, sup)
+	}
 
 	for _, tradingPair := range cfg.VWAP.TradingPairs {
-		fd, proc, pub := setup(cfg, tradingPair)
-		go func() {
-			pub.GoPublish(proc.GoProcess(fd.GoFeed()), wg)
-		}()
+		if err := sup.Add(tradingPair); err != nil {
+			log.Fatalf("failed to start a pipeline: %v", err)
+		}
+	}
+
+	return sup, shared
+}
+
+// startPipeline starts the feed → processor → publisher chain of the trading pair.
+// The pipeline is done once all its publishers have returned.
+func startPipeline(cfg config.Config, tradingPair string, shared sharedStages) (supervisor.Pipeline, error) {
+	tradesBuf, vwapsBuf, err := setupBuffers(cfg, tradingPair)
+	if err != nil {
+		return supervisor.Pipeline{}, err
+	}
+
+	// the results are only rounded when encoded, so the cross rates, alerts and emit policy use the full precision
+	rounder, err := processor.SetUpRounder(cfg.VWAP.ForPair(tradingPair))
+	if err != nil {
+		return supervisor.Pipeline{}, err
+	}
+
+	fd, proc, pub, err := setup(cfg, tradingPair, shared.snapshotStore)
+	if err != nil {
+		return supervisor.Pipeline{}, err
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+
+	var trades <-chan model.Trade = tradesBuf.GoBuffer(fd.GoFeed())
+	if cfg.Bar.Enabled() {
+		bb, err := bar.SetUp(cfg.Bar)
+		if err != nil {
+			_ = fd.Close()
+
+			return supervisor.Pipeline{}, fmt.Errorf("failed to create a bar builder: %w", err)
+		}
+
+		teed := pipe.GoTee(trades, 2)
+		trades = teed[0]
+		wg.Add(1)
+		pub.GoPublishBars(bb.GoBuild(teed[1]), &wg)
+	}
+
+	// the cross rates and alerts are tapped before the emit policy, which could hold back a level crossing,
+	// so they are not behind the VWAP results buffer and hold back the processor while they are busy
+	var vwaps, tap <-chan model.VWAP
+	if shared.crossIns != nil || shared.alerts != nil {
+		out, tapped := proc.GoProcessTapped(trades)
+		vwaps, tap = vwapsBuf.GoBuffer(out), tapped
+	} else {
+		vwaps = vwapsBuf.GoBuffer(proc.GoProcess(trades))
+	}
+	if shared.crossIns != nil {
+		updates := tap
+		if shared.alerts != nil {
+			teed := pipe.GoTee(tap, 2)
+			tap, updates = teed[0], teed[1]
+		}
+		shared.crossIns.Add(cross.GoUpdates(tradingPair, updates))
+	}
+	// the rules only depend on the trading pair's own VWAP, so each pipeline evaluates its rules
+	if shared.alerts != nil {
+		wg.Add(1)
+		pub.GoPublishAlerts(shared.alerts.GoEvaluate(tap), &wg)
+	}
+	if shared.latest != nil {
+		teed := pipe.GoTee(vwaps, 2)
+		vwaps = teed[0]
+		wg.Add(1)
+		shared.latest.GoStore(tradingPair, teed[1], rounder.Round, &wg)
 	}
+	// the sinks queue the results without blocking, so a slow sink never holds back the pipeline
+	if len(shared.sinks) > 0 {
+		senders := make([]publisher.Sender, len(shared.sinks))
+		for i, sink := range shared.sinks {
+			senders[i] = sink
+		}
+		teed := pipe.GoTee(vwaps, 2)
+		vwaps = teed[0]
+		wg.Add(1)
+		publisher.New(senders...).WithRounding(rounder.Round).GoPublish(teed[1], &wg)
+	}
+
+	pub.WithRounding(rounder.Round).GoPublish(vwaps, &wg)
+
+	if shared.stats != nil {
+		verifyStats := proc.VerifyStats()
+		shared.stats.Register(tradingPair, "calculator_resyncs", verifyStats.Resyncs.Load)
+		shared.stats.Register(tradingPair, "calculator_verify_failures", verifyStats.Failures.Load)
+		shared.stats.Register(tradingPair, "trades_dropped", tradesBuf.Dropped)
+		shared.stats.Register(tradingPair, "vwaps_dropped", vwapsBuf.Dropped)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		if shared.stats != nil {
+			shared.stats.Forget(tradingPair)
+		}
+		// the websocket and gRPC servers stop serving the latest VWAP of the trading pair
+		for _, sink := range shared.sinks {
+			sink.Forget(tradingPair)
+		}
+		close(done)
+	}()
+
+	return supervisor.Pipeline{
+		Stop:         fd.Close,
+		Done:         done,
+		ResetSession: proc.ResetSession,
+		Verify:       proc.RequestVerify,
+	}, nil
 }
 
-func setup(cfg config.Config, tradingPair string) (feed.Feed, processor.Processor, publisher.Publisher) {
+// reloadConfig reloads the .env file and the env vars, then applies the config to the running pipelines.
+// An invalid config is logged and leaves the pipelines as they are.
+func reloadConfig(sup *supervisor.Supervisor, shared sharedStages) {
+	if err := godotenv.Overload(); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		log.Printf("failed to reload .env: %v", err)
+
+		return
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		log.Printf("failed to reload config: %v", err)
+
+		return
+	}
+
+	if err := checkReload(shared, cfg); err != nil {
+		log.Printf("failed to reload config: %v", err)
+
+		return
+	}
+
+	if changed := unreloadedSettings(shared, cfg); len(changed) > 0 {
+		log.Printf("warning: the %s settings cannot be reloaded, restart to apply them", strings.Join(changed, ", "))
+	}
+
+	log.Printf("reloading config for trading pairs %v", cfg.VWAP.TradingPairs)
+	if err := sup.Reload(cfg); err != nil {
+		log.Printf("config reloaded with errors: %v", err)
+	}
+}
+
+// checkReload checks the reloaded config keeps the cross rate triangles, as the cross rate stage is not rebuilt,
+// and still streams all their trading pairs.
+func checkReload(shared sharedStages, cfg config.Config) error {
+	if strings.Join(cfg.Cross.Triangles, "|") != strings.Join(shared.cfg.Cross.Triangles, "|") {
+		return fmt.Errorf("cross rate triangles cannot be reloaded, restart to change CROSS_TRIANGLES")
+	}
+
+	if shared.crossIns == nil {
+		return nil
+	}
+
+	if _, err := cross.SetUp(cfg.Cross, cfg.VWAP); err != nil {
+		return fmt.Errorf("invalid cross rate triangles: %w", err)
+	}
+
+	return nil
+}
+
+// unreloadedSettings returns the config sections of the shared stages that differ from the reloaded config,
+// as the shared stages are not rebuilt. The pipeline settings are applied by restarting the pipelines.
+func unreloadedSettings(shared sharedStages, cfg config.Config) []string {
+	sections := []struct {
+		name     string
+		was, now any
+	}{
+		{"snapshot", shared.cfg.Snapshot, cfg.Snapshot},
+		{"alert", shared.cfg.Alert, cfg.Alert},
+		{"websocket server", shared.cfg.WSServer, cfg.WSServer},
+		{"HTTP server", shared.cfg.HTTPServer, cfg.HTTPServer},
+		{"gRPC server", shared.cfg.GRPCServer, cfg.GRPCServer},
+		{"file sink", shared.cfg.FileSink, cfg.FileSink},
+		{"sink", shared.cfg.Sink, cfg.Sink},
+		{"webhook", shared.cfg.Webhook, cfg.Webhook},
+	}
+
+	var changed []string
+	for _, s := range sections {
+		if !reflect.DeepEqual(s.was, s.now) {
+			changed = append(changed, s.name)
+		}
+	}
+
+	return changed
+}
+
+// loadConfig is config.NewConfig returning the panics of invalid env vars as errors.
+func loadConfig() (cfg config.Config, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+
+	return config.NewConfig(), nil
+}
+
+func setup(
+	cfg config.Config,
+	tradingPair string,
+	snapshotStore processor.SnapshotStore,
+) (feed.Feed, processor.Processor, publisher.Publisher, error) {
+	if err := checkOutput(cfg); err != nil {
+		return feed.Feed{}, processor.Processor{}, publisher.Publisher{}, err
+	}
+
+	// the processor is set up first, so an invalid config does not leave a connection open
+	proc, err := processor.SetUp(
+		cfg.VWAP.ForPair(tradingPair), cfg.Validation.ForPair(tradingPair), publisher.SetUpQuarantine(),
+	)
+	if err != nil {
+		return feed.Feed{}, processor.Processor{}, publisher.Publisher{}, fmt.Errorf("failed to create a processor: %w", err)
+	}
+
+	if snapshotStore != nil {
+		proc = proc.WithSnapshots(tradingPair, snapshotStore, cfg.Snapshot)
+	}
+
 	fd, err := feed.SetUp(cfg.Feed, cfg.VWAP, tradingPair)
 	if err != nil {
-		log.Fatalf("failed to create a feed: %v", err)
+		return feed.Feed{}, processor.Processor{}, publisher.Publisher{}, fmt.Errorf("failed to create a feed: %w", err)
 	}
 
-	proc, err := processor.SetUp(cfg.VWAP)
+	pub, err := publisher.SetUp(cfg.Output)
 	if err != nil {
-		log.Fatalf("failed to create a processor: %v", err)
+		_ = fd.Close()
+
+		return feed.Feed{}, processor.Processor{}, publisher.Publisher{}, fmt.Errorf("failed to create a publisher: %w", err)
 	}
 
-	pub := publisher.SetUp()
+	return fd, proc, pub, nil
+}
+
+// setupBuffers creates the buffers of the trades read by the processor and of the VWAP results it writes,
+// so a slow stage only holds back the ones before it when the policy blocks.
+func setupBuffers(cfg config.Config, tradingPair string) (*pipe.Buffer[model.Trade], *pipe.Buffer[model.VWAP], error) {
+	tradesOverflow, err := pipe.ParseOverflow(cfg.Backpressure.TradesPolicy)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid trades backpressure policy: %w", err)
+	}
+
+	// the VWAP is computed from every trade, which only a blocking buffer guarantees
+	switch tradesOverflow {
+	case pipe.OverflowConflate:
+		return nil, nil, fmt.Errorf(
+			`trades backpressure policy "%s" would leave all but the latest trade out of the VWAP`,
+			cfg.Backpressure.TradesPolicy,
+		)
+	case pipe.OverflowDropOldest, pipe.OverflowDropNewest:
+		log.Printf(
+			"warning: the %s trades buffer drops trades once full, leaving them out of the VWAP, "+
+				"see the trades_dropped counter", tradingPair,
+		)
+	}
+
+	vwapsOverflow, err := pipe.ParseOverflow(cfg.Backpressure.VWAPsPolicy)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid VWAP results backpressure policy: %w", err)
+	}
+
+	tradesBuf := pipe.NewBuffer(
+		tradingPair+" trades", cfg.Backpressure.TradesCapacity, tradesOverflow,
+		func(t model.Trade) string { return t.TradingPair },
+	)
+	vwapsBuf := pipe.NewBuffer(
+		tradingPair+" VWAP results", cfg.Backpressure.VWAPsCapacity, vwapsOverflow,
+		func(v model.VWAP) string { return v.TradingPair },
+	)
+
+	return tradesBuf, vwapsBuf, nil
+}
+
+// checkOutput checks the VWAP results are not mixed with the bars, cross rates and alerts on stdout,
+// which are only published as JSON, unless the results are JSON too.
+func checkOutput(cfg config.Config) error {
+	if cfg.Output.Encoding.IsJSON() || !(cfg.Bar.Enabled() || cfg.Cross.Enabled() || cfg.Alert.Enabled()) {
+		return nil
+	}
+
+	return fmt.Errorf(
+		`output encoding "%s" cannot be mixed with the JSON bars, cross rates and alerts on stdout`,
+		cfg.Output.Encoding,
+	)
+}
+
+func setupPublisher(cfg config.Config) publisher.Publisher {
+	pub, err := publisher.SetUp(cfg.Output)
+	if err != nil {
+		log.Fatalf("failed to create a publisher: %v", err)
+	}
+
+	return pub
+}
+
+func setupSnapshot(cfg config.Config) snapshot.FileStore {
+	store, err := snapshot.SetUp(cfg.Snapshot)
+	if err != nil {
+		log.Fatalf("failed to create a snapshot store: %v", err)
+	}
+
+	return store
+}
+
+func setupAlert(cfg config.Config) alert.Engine {
+	engine, err := alert.SetUp(cfg.Alert, cfg.VWAP)
+	if err != nil {
+		log.Fatalf("failed to create an alert engine: %v", err)
+	}
+
+	return engine
+}
+
+func setupWSServer(cfg config.Config) *publisher.WSServerSender {
+	wsServer, err := publisher.SetUpWSServer(cfg.WSServer)
+	if err != nil {
+		log.Fatalf("failed to start the websocket server: %v", err)
+	}
+
+	return wsServer
+}
+
+func setupAPI(
+	cfg config.Config, store *api.Store, stats *api.Stats, stream http.Handler, admin api.PairAdmin,
+) *api.Server {
+	server, err := api.SetUp(cfg.HTTPServer, store, stats, stream, admin)
+	if err != nil {
+		log.Fatalf("failed to start the HTTP server: %v", err)
+	}
+
+	return server
+}
+
+func setupGRPCServer(cfg config.Config) *publisher.GRPCSender {
+	grpcServer, err := publisher.SetUpGRPCServer(cfg.GRPCServer)
+	if err != nil {
+		log.Fatalf("failed to start the gRPC server: %v", err)
+	}
+
+	return grpcServer
+}
+
+func setupFileSink(cfg config.Config) *publisher.FileSender {
+	fileSink, err := publisher.SetUpFileSender(cfg.FileSink)
+	if err != nil {
+		log.Fatalf("failed to create the file sink: %v", err)
+	}
+
+	return fileSink
+}
+
+func setupWebhook(cfg config.Config, deadLetter *publisher.DeadLetter) *publisher.WebhookSender {
+	webhook, err := publisher.SetUpWebhookSender(cfg.Webhook, deadLetter)
+	if err != nil {
+		log.Fatalf("failed to create the webhook sender: %v", err)
+	}
+
+	return webhook
+}
+
+func setupCross(cfg config.Config) cross.Deriver {
+	deriver, err := cross.SetUp(cfg.Cross, cfg.VWAP)
+	if err != nil {
+		log.Fatalf("failed to create a cross rate deriver: %v", err)
+	}
 
-	return fd, proc, pub
+	return deriver
 }
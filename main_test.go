@@ -11,6 +11,9 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/aprln/vwap-engine/config"
+	"github.com/aprln/vwap-engine/cross"
+	"github.com/aprln/vwap-engine/internal/pipe"
 	"github.com/gorilla/websocket"
 	"github.com/stretchr/testify/assert"
 )
@@ -32,16 +35,16 @@ func Test_main(t *testing.T) {
 
 	// set expectation
 	wantMsgsBTCUSD := []string{
-		getVWAPMsg("BTC-USD", "20433.31"),
-		getVWAPMsg("BTC-USD", "19427.7342170096256965"),
-		getVWAPMsg("BTC-USD", "19786.8530027760498389"),
-		getVWAPMsg("BTC-USD", "20016.3010161061277987"),
+		getVWAPMsg("BTC-USD", "20433.31", getWindowMsg(1, false, "0.0043007", "87.877536317")),
+		getVWAPMsg("BTC-USD", "19427.7342170096256965", getWindowMsg(2, false, "0.20101818", "3905.327773827")),
+		getVWAPMsg("BTC-USD", "19786.8530027760498389", getWindowMsg(3, true, "0.31773565", "6286.9886002915")),
+		getVWAPMsg("BTC-USD", "20016.3010161061277987", getWindowMsg(3, true, "0.44015235", "8810.2219305465")),
 	}
 	wantMsgsETHUSD := []string{
-		getVWAPMsg("ETH-USD", "20433.31"),
-		getVWAPMsg("ETH-USD", "19427.7342170096256965"),
-		getVWAPMsg("ETH-USD", "19786.8530027760498389"),
-		getVWAPMsg("ETH-USD", "20016.3010161061277987"),
+		getVWAPMsg("ETH-USD", "20433.31", getWindowMsg(1, false, "0.0043007", "87.877536317")),
+		getVWAPMsg("ETH-USD", "19427.7342170096256965", getWindowMsg(2, false, "0.20101818", "3905.327773827")),
+		getVWAPMsg("ETH-USD", "19786.8530027760498389", getWindowMsg(3, true, "0.31773565", "6286.9886002915")),
+		getVWAPMsg("ETH-USD", "20016.3010161061277987", getWindowMsg(3, true, "0.44015235", "8810.2219305465")),
 	}
 
 	// now run the app
@@ -116,10 +119,18 @@ func getMatchResponse(tradingPair, msgType, price, size string) string {
 	)
 }
 
-func getVWAPMsg(tradingPair, vwap string) string {
+func getVWAPMsg(tradingPair, vwap, window string) string {
 	return fmt.Sprintf(
-		`{"trading_pair":"%s","last_trade_at":"2022-11-02T14:27:48.932205Z","vwap":"%s"}`,
-		tradingPair, vwap,
+		`{"trading_pair":"%s","last_trade_at":"2022-11-02T14:27:48.932205Z","vwap":"%s","window":%s}`,
+		tradingPair, vwap, window,
+	)
+}
+
+func getWindowMsg(tradeCount int, full bool, volume, notional string) string {
+	return fmt.Sprintf(
+		`{"trade_count":%d,"full":%t,"volume":"%s","notional":"%s",`+
+			`"oldest_trade_at":"2022-11-02T14:27:48.932205Z","newest_trade_at":"2022-11-02T14:27:48.932205Z"}`,
+		tradeCount, full, volume, notional,
 	)
 }
 
@@ -150,3 +161,70 @@ func filterMsgsContain(msgs []string, containingStr string) []string {
 
 	return res
 }
+
+func Test_checkReload(t *testing.T) {
+	triangles := []string{"ETH-BTC=ETH-USD/BTC-USD"}
+	shared := sharedStages{
+		cfg:      config.Config{Cross: config.Cross{Triangles: triangles}},
+		crossIns: pipe.NewMerger[cross.Update](),
+	}
+	defer shared.crossIns.Close()
+
+	cfg := config.Config{
+		Cross: config.Cross{Triangles: triangles},
+		VWAP:  config.VWAP{TradingPairs: []string{"BTC-USD", "ETH-USD", "ETH-BTC", "SOL-USD"}},
+	}
+	assert.NoError(t, checkReload(shared, cfg))
+
+	cfg.VWAP.TradingPairs = []string{"BTC-USD", "ETH-USD"}
+	assert.Error(t, checkReload(shared, cfg), "a leg is not streamed anymore")
+
+	cfg.Cross.Triangles = nil
+	assert.Error(t, checkReload(shared, cfg), "the triangles changed")
+	assert.NoError(t, checkReload(sharedStages{}, cfg), "no cross rates")
+}
+
+func Test_unreloadedSettings(t *testing.T) {
+	shared := sharedStages{cfg: config.Config{Sink: config.Sink{QueueSize: 10}}}
+
+	cfg := shared.cfg
+	cfg.VWAP.TradingPairs = []string{"SOL-USD"}
+	cfg.Backpressure.TradesCapacity = 10
+	assert.Empty(t, unreloadedSettings(shared, cfg), "the pipelines are restarted")
+
+	cfg.Sink.QueueSize = 20
+	cfg.Webhook.URLs = []string{"http://localhost"}
+	assert.Equal(t, []string{"sink", "webhook"}, unreloadedSettings(shared, cfg))
+}
+
+func Test_setupBuffers(t *testing.T) {
+	tests := []struct {
+		tradesPolicy string
+		vwapsPolicy  string
+		wantErr      bool
+	}{
+		{tradesPolicy: "block", vwapsPolicy: "conflate"},
+		{tradesPolicy: "drop_oldest", vwapsPolicy: "drop_newest"},
+		{tradesPolicy: "conflate", vwapsPolicy: "block", wantErr: true},
+		{tradesPolicy: "block", vwapsPolicy: "sometimes", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.tradesPolicy+"/"+tt.vwapsPolicy, func(t *testing.T) {
+				cfg := config.Config{
+					Backpressure: config.Backpressure{
+						TradesCapacity: 1, TradesPolicy: tt.tradesPolicy, VWAPsCapacity: 1, VWAPsPolicy: tt.vwapsPolicy,
+					},
+				}
+				_, _, err := setupBuffers(cfg, "BTC-USD")
+				if tt.wantErr {
+					assert.Error(t, err)
+
+					return
+				}
+				assert.NoError(t, err)
+			},
+		)
+	}
+}
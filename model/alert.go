@@ -0,0 +1,19 @@
+package model
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Alert is raised when a VWAP update of a trading pair meets an alerting rule.
+type Alert struct {
+	TradingPair string `json:"trading_pair"`
+	Rule        string `json:"rule"`
+	Message     string `json:"message"`
+	// Value is what the rule compares with its threshold: the VWAP for level rules, a percentage otherwise
+	Value       decimal.Decimal `json:"value"`
+	Threshold   decimal.Decimal `json:"threshold"`
+	VWAP        decimal.Decimal `json:"vwap"`
+	LastTradeAt time.Time       `json:"last_trade_at"`
+}
@@ -0,0 +1,23 @@
+package model
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+type Bar struct {
+	TradingPair string          `json:"trading_pair"`
+	Interval    string          `json:"interval"`
+	OpenTime    time.Time       `json:"open_time"`
+	CloseTime   time.Time       `json:"close_time"`
+	Open        decimal.Decimal `json:"open"`
+	High        decimal.Decimal `json:"high"`
+	Low         decimal.Decimal `json:"low"`
+	Close       decimal.Decimal `json:"close"`
+	Volume      decimal.Decimal `json:"volume"`
+	TradeCount  int             `json:"trade_count"`
+	VWAP        decimal.Decimal `json:"vwap"`
+	// Partial is true for a bar emitted before its close time, because its trade stream ended
+	Partial bool `json:"partial,omitempty"`
+}
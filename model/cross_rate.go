@@ -0,0 +1,20 @@
+package model
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// CrossRate compares the observed VWAP of a trading pair with the rate implied by two other trading pairs.
+type CrossRate struct {
+	TradingPair string `json:"trading_pair"`
+	// ImpliedFrom are the numerator and denominator trading pairs of the implied rate
+	ImpliedFrom [2]string `json:"implied_from"`
+	// LastTradeAt is the last trade time of the trading pair whose update triggered the cross rate
+	LastTradeAt time.Time       `json:"last_trade_at"`
+	Implied     decimal.Decimal `json:"implied"`
+	Observed    decimal.Decimal `json:"observed"`
+	// DeviationBps is (observed - implied) / implied in basis points
+	DeviationBps decimal.Decimal `json:"deviation_bps"`
+}
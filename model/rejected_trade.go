@@ -0,0 +1,17 @@
+package model
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// RejectedTrade is a trade that was quarantined instead of being processed.
+type RejectedTrade struct {
+	TradingPair string          `json:"trading_pair"`
+	Size        decimal.Decimal `json:"size"`
+	Price       decimal.Decimal `json:"price"`
+	Time        time.Time       `json:"time"`
+	Reason      string          `json:"reason"`
+	RejectedAt  time.Time       `json:"rejected_at"`
+}
@@ -7,6 +7,8 @@ import (
 )
 
 type Trade struct {
+	// ID is the exchange's trade ID, increasing per trading pair. Zero means unknown.
+	ID          int64
 	TradingPair string
 	Size        decimal.Decimal
 	Price       decimal.Decimal
@@ -10,4 +10,33 @@ type VWAP struct {
 	TradingPair string          `json:"trading_pair"`
 	LastTradeAt time.Time       `json:"last_trade_at"`
 	VWAP        decimal.Decimal `json:"vwap"`
+	// LastPrice is the price of the last trade. It is not published, the alerting stage compares it with VWAP.
+	LastPrice decimal.Decimal `json:"-"`
+	// SessionStart is only set by anchored calculators
+	SessionStart *time.Time `json:"session_start,omitempty"`
+	// Bands are only set when band multipliers are configured
+	Bands []Band `json:"bands,omitempty"`
+	// Indicators holds the values of the extra indicators configured for the trading pair, keyed by name
+	Indicators map[string]decimal.Decimal `json:"indicators,omitempty"`
+	// Window is only set by calculators with a trade window, i.e. the window, fixed and volume calculators
+	Window *Window `json:"window,omitempty"`
+}
+
+// Window describes the trades the VWAP is calculated over.
+type Window struct {
+	TradeCount int  `json:"trade_count"`
+	Full       bool `json:"full"`
+	// Volume is the total base volume in the window.
+	Volume decimal.Decimal `json:"volume"`
+	// Notional is the total price·size in the window.
+	Notional      decimal.Decimal `json:"notional"`
+	OldestTradeAt time.Time       `json:"oldest_trade_at"`
+	NewestTradeAt time.Time       `json:"newest_trade_at"`
+}
+
+// Band is the VWAP ± Multiplier·σ band, where σ is the volume-weighted standard deviation of price.
+type Band struct {
+	Multiplier decimal.Decimal `json:"multiplier"`
+	Upper      decimal.Decimal `json:"upper"`
+	Lower      decimal.Decimal `json:"lower"`
 }
@@ -0,0 +1,155 @@
+package processor
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+type anchorKind int
+
+const (
+	anchorKindDaily anchorKind = iota
+	anchorKindManual
+)
+
+const (
+	anchorMidnight     = "midnight"
+	anchorManual       = "manual"
+	anchorDailyPrefix  = "daily@"
+	anchorDailyLayout  = "15:04"
+	anchorSessionEvery = 24 * time.Hour
+)
+
+// Anchor decides when an anchored VWAP session starts.
+type Anchor struct {
+	kind anchorKind
+	// offset is the session open time from UTC midnight, only used by daily anchors
+	offset time.Duration
+}
+
+// ParseAnchor parses "midnight", "daily@HH:MM" (UTC) or "manual".
+func ParseAnchor(s string) (Anchor, error) {
+	switch {
+	case s == anchorMidnight:
+		return Anchor{kind: anchorKindDaily}, nil
+
+	case s == anchorManual:
+		return Anchor{kind: anchorKindManual}, nil
+
+	case strings.HasPrefix(s, anchorDailyPrefix):
+		openTime, err := time.Parse(anchorDailyLayout, strings.TrimPrefix(s, anchorDailyPrefix))
+		if err != nil {
+			return Anchor{}, fmt.Errorf(`invalid session open time in anchor "%s": %v`, s, err)
+		}
+
+		return Anchor{
+			kind:   anchorKindDaily,
+			offset: time.Duration(openTime.Hour())*time.Hour + time.Duration(openTime.Minute())*time.Minute,
+		}, nil
+
+	default:
+		return Anchor{}, fmt.Errorf(`anchor "%s" is unsupported`, s)
+	}
+}
+
+// sessionStart returns the start of the daily session that t belongs to.
+func (a Anchor) sessionStart(t time.Time) time.Time {
+	start := t.UTC().Truncate(anchorSessionEvery).Add(a.offset)
+	if t.Before(start) {
+		start = start.Add(-anchorSessionEvery)
+	}
+
+	return start
+}
+
+func NewAnchoredVWAPCalc(anchor Anchor) *AnchoredVWAPCalc {
+	return &AnchoredVWAPCalc{
+		anchor:            anchor,
+		totalValue:        decimal.Zero,
+		totalSquaredValue: decimal.Zero,
+		totalSize:         decimal.Zero,
+		vwap:              decimal.Zero,
+	}
+}
+
+// AnchoredVWAPCalc calculates the cumulative VWAP since the start of the current session.
+// Daily sessions roll over based on trade time. Manual sessions only roll over after Reset is called.
+type AnchoredVWAPCalc struct {
+	anchor            Anchor
+	started           bool
+	sessionStart      time.Time
+	resetRequested    atomic.Bool
+	totalValue        decimal.Decimal
+	totalSquaredValue decimal.Decimal
+	totalSize         decimal.Decimal
+	vwap              decimal.Decimal
+}
+
+func (c *AnchoredVWAPCalc) VWAP() decimal.Decimal {
+	return c.vwap
+}
+
+// StdDev returns the volume-weighted standard deviation of price around the session VWAP.
+func (c *AnchoredVWAPCalc) StdDev() decimal.Decimal {
+	return volumeWeightedStdDev(c.totalSquaredValue, c.totalSize, c.vwap)
+}
+
+func (c *AnchoredVWAPCalc) AddDataPoint(price, size decimal.Decimal) error {
+	dp := VWAPCalcDataPoint{Price: price, Size: size}
+	c.totalValue = c.totalValue.Add(dp.Value())
+	c.totalSquaredValue = c.totalSquaredValue.Add(dp.SquaredValue())
+	c.totalSize = c.totalSize.Add(size)
+
+	if c.totalSize.IsZero() {
+		c.vwap = decimal.Zero
+
+		return nil
+	}
+
+	c.vwap = c.totalValue.Div(c.totalSize)
+
+	return nil
+}
+
+// AdvanceTo starts a new session if the trade time t crosses the anchor boundary
+// or if a manual reset has been requested.
+func (c *AnchoredVWAPCalc) AdvanceTo(t time.Time) {
+	switch {
+	case c.resetRequested.Swap(false):
+		c.startSession(t)
+
+	case !c.started && c.anchor.kind == anchorKindManual:
+		c.startSession(t)
+
+	case !c.started:
+		c.startSession(c.anchor.sessionStart(t))
+
+	case c.anchor.kind == anchorKindDaily:
+		if start := c.anchor.sessionStart(t); start.After(c.sessionStart) {
+			c.startSession(start)
+		}
+	}
+}
+
+// Reset requests a new session to start with the next trade.
+// It is safe to call from any goroutine.
+func (c *AnchoredVWAPCalc) Reset() {
+	c.resetRequested.Store(true)
+}
+
+func (c *AnchoredVWAPCalc) SessionStart() time.Time {
+	return c.sessionStart
+}
+
+func (c *AnchoredVWAPCalc) startSession(start time.Time) {
+	c.started = true
+	c.sessionStart = start
+	c.totalValue = decimal.Zero
+	c.totalSquaredValue = decimal.Zero
+	c.totalSize = decimal.Zero
+	c.vwap = decimal.Zero
+}
@@ -0,0 +1,136 @@
+package processor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAnchor(t *testing.T) {
+	tests := []struct {
+		name    string
+		anchor  string
+		want    Anchor
+		wantErr bool
+	}{
+		{
+			name:   "midnight",
+			anchor: "midnight",
+			want:   Anchor{kind: anchorKindDaily},
+		},
+		{
+			name:   "daily session open time",
+			anchor: "daily@13:30",
+			want:   Anchor{kind: anchorKindDaily, offset: 13*time.Hour + 30*time.Minute},
+		},
+		{
+			name:   "manual",
+			anchor: "manual",
+			want:   Anchor{kind: anchorKindManual},
+		},
+		{
+			name:    "invalid session open time",
+			anchor:  "daily@25:00",
+			wantErr: true,
+		},
+		{
+			name:    "unsupported",
+			anchor:  "weekly",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				got, err := ParseAnchor(tt.anchor)
+
+				if tt.wantErr {
+					require.Error(t, err)
+
+					return
+				}
+
+				require.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			},
+		)
+	}
+}
+
+func TestAnchoredVWAPCalc_dailyFlow(t *testing.T) {
+	anchor, err := ParseAnchor("daily@13:30")
+	require.NoError(t, err)
+	c := NewAnchoredVWAPCalc(anchor)
+
+	sequence := []struct {
+		at               time.Time
+		price            float64
+		size             float64
+		wantVWAP         string
+		wantSessionStart time.Time
+	}{
+		{
+			at:               time.Date(2022, 11, 2, 9, 0, 0, 0, time.UTC),
+			price:            1,
+			size:             1,
+			wantVWAP:         "1",
+			wantSessionStart: time.Date(2022, 11, 1, 13, 30, 0, 0, time.UTC),
+		},
+		{
+			at:               time.Date(2022, 11, 2, 13, 29, 59, 0, time.UTC),
+			price:            2,
+			size:             3,
+			wantVWAP:         "1.75",
+			wantSessionStart: time.Date(2022, 11, 1, 13, 30, 0, 0, time.UTC),
+		},
+		{
+			at:               time.Date(2022, 11, 2, 13, 30, 0, 0, time.UTC),
+			price:            4,
+			size:             1,
+			wantVWAP:         "4",
+			wantSessionStart: time.Date(2022, 11, 2, 13, 30, 0, 0, time.UTC),
+		},
+		{
+			at:               time.Date(2022, 11, 3, 1, 0, 0, 0, time.UTC),
+			price:            6,
+			size:             1,
+			wantVWAP:         "5",
+			wantSessionStart: time.Date(2022, 11, 2, 13, 30, 0, 0, time.UTC),
+		},
+	}
+
+	for i, s := range sequence {
+		c.AdvanceTo(s.at)
+		err = c.AddDataPoint(decimal.NewFromFloat(s.price), decimal.NewFromFloat(s.size))
+		require.NoError(t, err)
+		require.Equalf(t, s.wantVWAP, c.VWAP().String(), "failed at step %d", i)
+		require.Equalf(t, s.wantSessionStart, c.SessionStart(), "failed at step %d", i)
+	}
+}
+
+func TestAnchoredVWAPCalc_manualFlow(t *testing.T) {
+	anchor, err := ParseAnchor("manual")
+	require.NoError(t, err)
+	c := NewAnchoredVWAPCalc(anchor)
+
+	first := time.Date(2022, 11, 2, 9, 0, 0, 0, time.UTC)
+	c.AdvanceTo(first)
+	require.NoError(t, c.AddDataPoint(decimal.NewFromInt(1), decimal.NewFromInt(1)))
+
+	// manual sessions never roll over on their own
+	c.AdvanceTo(first.Add(48 * time.Hour))
+	require.NoError(t, c.AddDataPoint(decimal.NewFromInt(3), decimal.NewFromInt(1)))
+	assert.Equal(t, "2", c.VWAP().String())
+	assert.Equal(t, first, c.SessionStart())
+
+	c.Reset()
+	second := first.Add(72 * time.Hour)
+	c.AdvanceTo(second)
+	require.NoError(t, c.AddDataPoint(decimal.NewFromInt(5), decimal.NewFromInt(1)))
+	assert.Equal(t, "5", c.VWAP().String())
+	assert.Equal(t, second, c.SessionStart())
+}
@@ -0,0 +1,36 @@
+package processor
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"github.com/shopspring/decimal"
+)
+
+// VerifyStats counts the outcomes of the verifications of a calculator's running totals.
+// It is safe to read while the processor is running.
+type VerifyStats struct {
+	// Resyncs is the number of verifications that found a drift and resynchronized the running totals.
+	Resyncs atomic.Uint64
+	// Failures is the number of verifications that failed. The processor carries on with the running totals
+	// as they were.
+	Failures atomic.Uint64
+}
+
+// Drift is the difference between a calculator's running totals and the totals recomputed from its data points.
+type Drift struct {
+	TotalValue        decimal.Decimal
+	TotalSquaredValue decimal.Decimal
+	TotalSize         decimal.Decimal
+}
+
+func (d Drift) IsZero() bool {
+	return d.TotalValue.IsZero() && d.TotalSquaredValue.IsZero() && d.TotalSize.IsZero()
+}
+
+func (d Drift) String() string {
+	return fmt.Sprintf(
+		"total value %s, total squared value %s, total size %s",
+		d.TotalValue, d.TotalSquaredValue, d.TotalSize,
+	)
+}
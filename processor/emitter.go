@@ -0,0 +1,184 @@
+package processor
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aprln/vwap-engine/model"
+	"github.com/shopspring/decimal"
+)
+
+type emitKind int
+
+const (
+	emitKindEvery emitKind = iota
+	emitKindInterval
+	emitKindMove
+	emitKindTrades
+)
+
+const (
+	emitEvery          = "every"
+	emitIntervalPrefix = "interval@"
+	emitMovePrefix     = "move@"
+	emitTradesPrefix   = "trades@"
+)
+
+// EmitPolicy decides which VWAP results are published. Every trade is processed regardless of the policy.
+type EmitPolicy struct {
+	kind     emitKind
+	interval time.Duration
+	// move is the relative VWAP change from the last published result, e.g. 0.001 for 0.1%
+	move   decimal.Decimal
+	trades int
+}
+
+// ParseEmitPolicy parses "every" (every trade), "interval@<duration>" (at most once per duration,
+// the latest result winning), "move@<ratio>" (when VWAP moves by more than the ratio from the last published
+// result) or "trades@<n>" (every n trades). An empty policy is the same as "every".
+func ParseEmitPolicy(s string) (EmitPolicy, error) {
+	switch {
+	case s == "" || s == emitEvery:
+		return EmitPolicy{kind: emitKindEvery}, nil
+
+	case strings.HasPrefix(s, emitIntervalPrefix):
+		interval, err := time.ParseDuration(strings.TrimPrefix(s, emitIntervalPrefix))
+		if err != nil || interval <= 0 {
+			return EmitPolicy{}, fmt.Errorf(`invalid interval in emit policy "%s"`, s)
+		}
+
+		return EmitPolicy{kind: emitKindInterval, interval: interval}, nil
+
+	case strings.HasPrefix(s, emitMovePrefix):
+		move, err := decimal.NewFromString(strings.TrimPrefix(s, emitMovePrefix))
+		if err != nil || !move.IsPositive() {
+			return EmitPolicy{}, fmt.Errorf(`invalid move threshold in emit policy "%s"`, s)
+		}
+
+		return EmitPolicy{kind: emitKindMove, move: move}, nil
+
+	case strings.HasPrefix(s, emitTradesPrefix):
+		trades, err := strconv.Atoi(strings.TrimPrefix(s, emitTradesPrefix))
+		if err != nil || trades <= 0 {
+			return EmitPolicy{}, fmt.Errorf(`invalid trade count in emit policy "%s"`, s)
+		}
+
+		return EmitPolicy{kind: emitKindTrades, trades: trades}, nil
+
+	default:
+		return EmitPolicy{}, fmt.Errorf(`emit policy "%s" is unsupported`, s)
+	}
+}
+
+func newEmitter(policy EmitPolicy, now func() time.Time) *emitter {
+	return &emitter{
+		policy: policy,
+		now:    now,
+	}
+}
+
+// emitter applies an EmitPolicy to the stream of VWAP results of a processor.
+// It is not safe for concurrent use.
+type emitter struct {
+	policy        EmitPolicy
+	now           func() time.Time
+	lastEmitted   *model.VWAP
+	lastEmittedAt time.Time
+	// pending is the latest result held back by the interval policy
+	pending *model.VWAP
+	offered int
+}
+
+// offer returns whether the result must be published now.
+// Results held back by the interval policy are kept as pending, see flushIn and flush.
+func (e *emitter) offer(vwap model.VWAP) bool {
+	e.offered++
+
+	var emit bool
+	switch e.policy.kind {
+	case emitKindInterval:
+		emit = e.lastEmitted == nil || e.now().Sub(e.lastEmittedAt) >= e.policy.interval
+		if !emit {
+			e.pending = &vwap
+		}
+
+	case emitKindMove:
+		emit = e.lastEmitted == nil || e.moved(vwap.VWAP)
+
+	case emitKindTrades:
+		emit = e.offered%e.policy.trades == 0
+
+	default:
+		emit = true
+	}
+
+	if emit {
+		e.emitted(vwap)
+	}
+
+	return emit
+}
+
+// flushIn returns how long until the pending result may be published, or false if there is none.
+func (e *emitter) flushIn() (time.Duration, bool) {
+	if e.pending == nil {
+		return 0, false
+	}
+
+	return e.policy.interval - e.now().Sub(e.lastEmittedAt), true
+}
+
+// flush returns the pending result, if any, and marks it as published.
+func (e *emitter) flush() (model.VWAP, bool) {
+	if e.pending == nil {
+		return model.VWAP{}, false
+	}
+
+	vwap := *e.pending
+	e.emitted(vwap)
+
+	return vwap, true
+}
+
+func (e *emitter) emitted(vwap model.VWAP) {
+	e.lastEmitted = &vwap
+	e.lastEmittedAt = e.now()
+	e.pending = nil
+}
+
+// emitterState is the state of an emitter persisted in snapshots. A pending result is not persisted,
+// it is flushed before the final snapshot.
+type emitterState struct {
+	LastEmitted   *model.VWAP `json:"last_emitted,omitempty"`
+	LastEmittedAt time.Time   `json:"last_emitted_at"`
+	Offered       int         `json:"offered"`
+}
+
+func (e *emitter) marshalState() (json.RawMessage, error) {
+	return json.Marshal(emitterState{LastEmitted: e.lastEmitted, LastEmittedAt: e.lastEmittedAt, Offered: e.offered})
+}
+
+func (e *emitter) restoreState(state json.RawMessage) error {
+	var s emitterState
+	if err := json.Unmarshal(state, &s); err != nil {
+		return fmt.Errorf("invalid emitter state: %v", err)
+	}
+
+	e.lastEmitted = s.LastEmitted
+	e.lastEmittedAt = s.LastEmittedAt
+	e.offered = s.Offered
+
+	return nil
+}
+
+func (e *emitter) moved(vwap decimal.Decimal) bool {
+	last := e.lastEmitted.VWAP
+	if last.IsZero() {
+		return !vwap.IsZero()
+	}
+
+	return vwap.Sub(last).Abs().Div(last.Abs()).GreaterThan(e.policy.move)
+}
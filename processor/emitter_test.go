@@ -0,0 +1,136 @@
+package processor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aprln/vwap-engine/config"
+	"github.com/aprln/vwap-engine/model"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseEmitPolicy(t *testing.T) {
+	tests := []struct {
+		s       string
+		want    EmitPolicy
+		wantErr bool
+	}{
+		{s: "", want: EmitPolicy{kind: emitKindEvery}},
+		{s: "every", want: EmitPolicy{kind: emitKindEvery}},
+		{s: "interval@250ms", want: EmitPolicy{kind: emitKindInterval, interval: 250 * time.Millisecond}},
+		{s: "interval@0s", wantErr: true},
+		{s: "interval@banana", wantErr: true},
+		{s: "move@0.001", want: EmitPolicy{kind: emitKindMove, move: decimal.RequireFromString("0.001")}},
+		{s: "move@-0.001", wantErr: true},
+		{s: "trades@10", want: EmitPolicy{kind: emitKindTrades, trades: 10}},
+		{s: "trades@0", wantErr: true},
+		{s: "banana", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.s, func(t *testing.T) {
+				got, err := ParseEmitPolicy(tt.s)
+
+				if tt.wantErr {
+					require.Error(t, err)
+
+					return
+				}
+
+				require.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			},
+		)
+	}
+}
+
+func TestEmitter_offer(t *testing.T) {
+	tests := []struct {
+		policy string
+		vwaps  []string
+		want   []bool
+	}{
+		{
+			policy: "every",
+			vwaps:  []string{"1", "1", "2"},
+			want:   []bool{true, true, true},
+		},
+		{
+			policy: "move@0.1",
+			vwaps:  []string{"100", "105", "110", "111", "122.1", "122.2"},
+			want:   []bool{true, false, false, true, false, true},
+		},
+		{
+			policy: "trades@3",
+			vwaps:  []string{"1", "2", "3", "4", "5", "6", "7"},
+			want:   []bool{false, false, true, false, false, true, false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.policy, func(t *testing.T) {
+				policy, err := ParseEmitPolicy(tt.policy)
+				require.NoError(t, err)
+				em := newEmitter(policy, time.Now)
+
+				for i, v := range tt.vwaps {
+					got := em.offer(model.VWAP{VWAP: decimal.RequireFromString(v)})
+					assert.Equalf(t, tt.want[i], got, "failed at step %d", i)
+				}
+
+				_, ok := em.flush()
+				assert.False(t, ok, "nothing is pending")
+			},
+		)
+	}
+}
+
+func TestEmitter_offer_interval(t *testing.T) {
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	em := newEmitter(EmitPolicy{kind: emitKindInterval, interval: time.Second}, func() time.Time { return now })
+
+	assert.True(t, em.offer(model.VWAP{VWAP: decimal.NewFromInt(1)}), "the first result is published")
+	_, ok := em.flushIn()
+	assert.False(t, ok)
+
+	now = now.Add(300 * time.Millisecond)
+	assert.False(t, em.offer(model.VWAP{VWAP: decimal.NewFromInt(2)}))
+	assert.False(t, em.offer(model.VWAP{VWAP: decimal.NewFromInt(3)}))
+
+	d, ok := em.flushIn()
+	require.True(t, ok)
+	assert.Equal(t, 700*time.Millisecond, d)
+
+	now = now.Add(700 * time.Millisecond)
+	vwap, ok := em.flush()
+	require.True(t, ok)
+	assert.Equal(t, "3", vwap.VWAP.String(), "the latest result wins")
+	_, ok = em.flush()
+	assert.False(t, ok)
+
+	now = now.Add(time.Second)
+	assert.True(t, em.offer(model.VWAP{VWAP: decimal.NewFromInt(4)}))
+}
+
+func TestProcessor_GoProcess_interval(t *testing.T) {
+	calc, err := NewVWAPCalc(1)
+	require.NoError(t, err)
+	proc := New(config.VWAP{}, calc).WithEmitPolicy(EmitPolicy{kind: emitKindInterval, interval: time.Hour})
+
+	in := make(chan model.Trade, 3)
+	for i := int64(1); i <= 3; i++ {
+		in <- model.Trade{TradingPair: "BTC-USD", Price: decimal.NewFromInt(i), Size: decimal.NewFromInt(1)}
+	}
+	close(in)
+
+	out := proc.GoProcess(in)
+
+	assert.Equal(t, "1", (<-out).VWAP.String())
+	assert.Equal(t, "3", (<-out).VWAP.String(), "the pending result is published when the trades run out")
+	_, more := <-out
+	assert.False(t, more)
+}
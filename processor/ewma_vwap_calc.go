@@ -0,0 +1,121 @@
+package processor
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// ewmaPrecision is the number of decimal places the decayed totals are rounded to.
+// Without rounding, every decay would add digits to the totals forever.
+const ewmaPrecision = 24
+
+// HalfLife is the EWMA half-life, measured either in trades or in trade-time duration.
+type HalfLife struct {
+	trades   float64
+	duration time.Duration
+}
+
+// ParseHalfLife parses a positive number of trades (e.g. "100") or a positive duration (e.g. "30s").
+func ParseHalfLife(s string) (HalfLife, error) {
+	if trades, err := strconv.ParseFloat(s, 64); err == nil {
+		if trades <= 0 || math.IsInf(trades, 0) || math.IsNaN(trades) {
+			return HalfLife{}, fmt.Errorf(`invalid half-life "%s"`, s)
+		}
+
+		return HalfLife{trades: trades}, nil
+	}
+
+	duration, err := time.ParseDuration(s)
+	if err != nil || duration <= 0 {
+		return HalfLife{}, fmt.Errorf(`invalid half-life "%s"`, s)
+	}
+
+	return HalfLife{duration: duration}, nil
+}
+
+func NewEWMAVWAPCalc(halfLife HalfLife) *EWMAVWAPCalc {
+	c := &EWMAVWAPCalc{
+		halfLife:          halfLife,
+		totalValue:        decimal.Zero,
+		totalSquaredValue: decimal.Zero,
+		totalSize:         decimal.Zero,
+		vwap:              decimal.Zero,
+	}
+
+	if halfLife.trades > 0 {
+		c.tradeDecay = decimal.NewFromFloat(math.Pow(0.5, 1/halfLife.trades))
+	}
+
+	return c
+}
+
+// EWMAVWAPCalc calculates an exponentially weighted VWAP.
+// Both price·size and size decay with the half-life, so old trades fade out smoothly
+// instead of dropping out of a window all at once.
+type EWMAVWAPCalc struct {
+	halfLife HalfLife
+	// tradeDecay is the decay factor applied on every trade when the half-life is measured in trades
+	tradeDecay        decimal.Decimal
+	lastTradeAt       time.Time
+	totalValue        decimal.Decimal
+	totalSquaredValue decimal.Decimal
+	totalSize         decimal.Decimal
+	vwap              decimal.Decimal
+}
+
+func (c *EWMAVWAPCalc) VWAP() decimal.Decimal {
+	return c.vwap
+}
+
+// StdDev returns the exponentially weighted standard deviation of price around VWAP.
+func (c *EWMAVWAPCalc) StdDev() decimal.Decimal {
+	return volumeWeightedStdDev(c.totalSquaredValue, c.totalSize, c.vwap)
+}
+
+// AdvanceTo decays the totals by the trade time elapsed since the last trade
+// when the half-life is measured in duration. Out of order trades do not decay the totals.
+func (c *EWMAVWAPCalc) AdvanceTo(t time.Time) {
+	if c.halfLife.duration == 0 {
+		return
+	}
+
+	if !c.lastTradeAt.IsZero() && t.After(c.lastTradeAt) {
+		elapsed := t.Sub(c.lastTradeAt)
+		c.decay(decimal.NewFromFloat(math.Pow(0.5, float64(elapsed)/float64(c.halfLife.duration))))
+	}
+
+	if t.After(c.lastTradeAt) {
+		c.lastTradeAt = t
+	}
+}
+
+func (c *EWMAVWAPCalc) AddDataPoint(price, size decimal.Decimal) error {
+	if c.halfLife.trades > 0 {
+		c.decay(c.tradeDecay)
+	}
+
+	dp := VWAPCalcDataPoint{Price: price, Size: size}
+	c.totalValue = c.totalValue.Add(dp.Value())
+	c.totalSquaredValue = c.totalSquaredValue.Add(dp.SquaredValue())
+	c.totalSize = c.totalSize.Add(size)
+
+	if c.totalSize.IsZero() {
+		c.vwap = decimal.Zero
+
+		return nil
+	}
+
+	c.vwap = c.totalValue.Div(c.totalSize)
+
+	return nil
+}
+
+func (c *EWMAVWAPCalc) decay(factor decimal.Decimal) {
+	c.totalValue = c.totalValue.Mul(factor).Round(ewmaPrecision)
+	c.totalSquaredValue = c.totalSquaredValue.Mul(factor).Round(ewmaPrecision)
+	c.totalSize = c.totalSize.Mul(factor).Round(ewmaPrecision)
+}
@@ -0,0 +1,98 @@
+package processor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseHalfLife(t *testing.T) {
+	tests := []struct {
+		halfLife string
+		want     HalfLife
+		wantErr  bool
+	}{
+		{halfLife: "100", want: HalfLife{trades: 100}},
+		{halfLife: "0.5", want: HalfLife{trades: 0.5}},
+		{halfLife: "30s", want: HalfLife{duration: 30 * time.Second}},
+		{halfLife: "0", wantErr: true},
+		{halfLife: "-2", wantErr: true},
+		{halfLife: "-1m", wantErr: true},
+		{halfLife: "banana", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.halfLife, func(t *testing.T) {
+				got, err := ParseHalfLife(tt.halfLife)
+
+				if tt.wantErr {
+					require.Error(t, err)
+
+					return
+				}
+
+				require.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			},
+		)
+	}
+}
+
+func TestEWMAVWAPCalc_tradeHalfLifeFlow(t *testing.T) {
+	halfLife, err := ParseHalfLife("1")
+	require.NoError(t, err)
+	c := NewEWMAVWAPCalc(halfLife)
+	require.Equal(t, "0", c.VWAP().String(), "failed at init")
+
+	sequence := []struct {
+		price    float64
+		size     float64
+		wantVWAP string
+	}{
+		{1, 1, "1"},
+		// (1·0.5 + 4·1) / (0.5 + 1)
+		{4, 1, "3"},
+		// (4.5·0.5 + 6·2) / (1.5·0.5 + 2)
+		{6, 2, "5.1818181818181818"},
+	}
+
+	for i, s := range sequence {
+		c.AdvanceTo(time.Time{})
+		err = c.AddDataPoint(decimal.NewFromFloat(s.price), decimal.NewFromFloat(s.size))
+		require.NoError(t, err)
+		require.Equalf(t, s.wantVWAP, c.VWAP().String(), "failed at step %d", i)
+	}
+}
+
+func TestEWMAVWAPCalc_durationHalfLifeFlow(t *testing.T) {
+	halfLife, err := ParseHalfLife("10s")
+	require.NoError(t, err)
+	c := NewEWMAVWAPCalc(halfLife)
+
+	base := time.Date(2022, 11, 2, 14, 27, 0, 0, time.UTC)
+	sequence := []struct {
+		at       time.Time
+		price    float64
+		size     float64
+		wantVWAP string
+	}{
+		{base, 1, 1, "1"},
+		// same trade time, no decay
+		{base, 3, 1, "2"},
+		// one half-life later: (4·0.5 + 5·1) / (2·0.5 + 1)
+		{base.Add(10 * time.Second), 5, 1, "3.5"},
+		// out of order trades do not decay the totals: (7 + 1·1) / (2 + 1)
+		{base, 1, 1, "2.6666666666666667"},
+	}
+
+	for i, s := range sequence {
+		c.AdvanceTo(s.at)
+		err = c.AddDataPoint(decimal.NewFromFloat(s.price), decimal.NewFromFloat(s.size))
+		require.NoError(t, err)
+		require.Equalf(t, s.wantVWAP, c.VWAP().String(), "failed at step %d", i)
+	}
+}
@@ -0,0 +1,394 @@
+package processor
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"time"
+
+	"github.com/aprln/vwap-engine/model"
+	"github.com/shopspring/decimal"
+)
+
+// FixedVWAPCalcDataPoint is a data point in fixed-point units,
+// i.e. price and size multiplied by 10^priceScale and 10^sizeScale.
+// The units are unsigned as prices and sizes are never negative, which leaves one more bit before an overflow.
+// Negative values are rejected by AddDataPoint.
+type FixedVWAPCalcDataPoint struct {
+	Price uint64
+	Size  uint64
+}
+
+func NewFixedVWAPCalc(windowSize int, priceIncrement, sizeIncrement decimal.Decimal) (*FixedVWAPCalc, error) {
+	priceScale, err := incrementScale(priceIncrement)
+	if err != nil {
+		return nil, fmt.Errorf("invalid price increment: %v", err)
+	}
+
+	sizeScale, err := incrementScale(sizeIncrement)
+	if err != nil {
+		return nil, fmt.Errorf("invalid size increment: %v", err)
+	}
+
+	if windowSize <= 0 {
+		return nil, fmt.Errorf("invalid window size %d", windowSize)
+	}
+
+	return &FixedVWAPCalc{
+		windowSize: windowSize,
+		priceScale: priceScale,
+		sizeScale:  sizeScale,
+		dataPoints: make([]FixedVWAPCalcDataPoint, windowSize),
+		tradeTimes: make([]time.Time, windowSize),
+	}, nil
+}
+
+// FixedVWAPCalc is a fixed size window VWAP calculator like VWAPCalc, backed by fixed-point integers instead of
+// decimal.Decimal. AddScaledDataPoint does not allocate, whereas AddDataPoint allocates a copy of each decimal
+// coefficient, which decimal.Decimal does not expose otherwise. The totals are only converted to decimals
+// when VWAP or StdDev is read, once per change of the totals as they are read several times per trade.
+// Every arithmetic overflow is reported as an error.
+type FixedVWAPCalc struct {
+	windowSize         int
+	priceScale         int32
+	sizeScale          int32
+	dataPoints         []FixedVWAPCalcDataPoint
+	tradeTimes         []time.Time
+	nextTradeAt        time.Time
+	dataPointCount     int
+	oldestDataPointIdx int
+	// totalValue is Σ price·size in 10^-(priceScale+sizeScale) units
+	totalValue uint128
+	// totalSquaredValue is Σ price²·size in 10^-(2·priceScale+sizeScale) units
+	totalSquaredValue uint128
+	totalSize         uint64
+	// vwap and stdDev are the decimals of the totals, computed on the first read after the totals changed
+	vwap        decimal.Decimal
+	stdDev      decimal.Decimal
+	vwapValid   bool
+	stdDevValid bool
+}
+
+func (c *FixedVWAPCalc) VWAP() decimal.Decimal {
+	if c.vwapValid {
+		return c.vwap
+	}
+
+	c.vwap = decimal.Zero
+	if c.totalSize != 0 {
+		c.vwap = c.divTotals()
+	}
+	c.vwapValid = true
+
+	return c.vwap
+}
+
+// divTotals returns totalValue / totalSize to decimal.DivisionPrecision places like decimal.Decimal.Div,
+// in integer arithmetic unless it overflows.
+func (c *FixedVWAPCalc) divTotals() decimal.Decimal {
+	// the quotient of the totals is in 10^-priceScale units
+	shift := int32(decimal.DivisionPrecision) - c.priceScale
+	if shift >= 0 && int(shift) < len(pow10) {
+		if scaled, ok := c.totalValue.mul(pow10[shift]); ok {
+			if q, ok := scaled.divRound(c.totalSize); ok {
+				return fixedToDecimal(q, -int32(decimal.DivisionPrecision))
+			}
+		}
+	}
+
+	return c.totalValueDecimal().Div(c.totalSizeDecimal())
+}
+
+// StdDev returns the volume-weighted standard deviation of price around VWAP.
+func (c *FixedVWAPCalc) StdDev() decimal.Decimal {
+	if !c.stdDevValid {
+		c.stdDev = volumeWeightedStdDev(c.totalSquaredValueDecimal(), c.totalSizeDecimal(), c.VWAP())
+		c.stdDevValid = true
+	}
+
+	return c.stdDev
+}
+
+// AdvanceTo records the trade time of the next data point, which is reported by Window.
+func (c *FixedVWAPCalc) AdvanceTo(t time.Time) {
+	c.nextTradeAt = t
+}
+
+// Window returns the number of data points in the window, their totals and trade times.
+func (c *FixedVWAPCalc) Window() model.Window {
+	return newRingWindow(
+		c.tradeTimes, c.oldestDataPointIdx, c.dataPointCount, c.totalSizeDecimal(), c.totalValueDecimal(),
+	)
+}
+
+func (c *FixedVWAPCalc) CheckDataPoint(price, size decimal.Decimal) error {
+	newDP, err := c.toFixedDataPoint(price, size)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.nextTotals(newDP)
+
+	return err
+}
+
+func (c *FixedVWAPCalc) AddDataPoint(price, size decimal.Decimal) error {
+	newDP, err := c.toFixedDataPoint(price, size)
+	if err != nil {
+		return err
+	}
+
+	return c.AddScaledDataPoint(newDP.Price, newDP.Size)
+}
+
+// AddScaledDataPoint adds a data point already in fixed-point units.
+// The calculator is left unchanged if an overflow is detected.
+func (c *FixedVWAPCalc) AddScaledDataPoint(price, size uint64) error {
+	newDP := FixedVWAPCalcDataPoint{Price: price, Size: size}
+
+	totals, err := c.nextTotals(newDP)
+	if err != nil {
+		return err
+	}
+
+	c.dataPoints[c.oldestDataPointIdx] = newDP
+	c.tradeTimes[c.oldestDataPointIdx] = c.nextTradeAt
+	if c.dataPointCount < c.windowSize {
+		c.dataPointCount++
+	}
+	c.totalSize = totals.size
+	c.totalValue = totals.value
+	c.totalSquaredValue = totals.squaredValue
+	c.vwapValid, c.stdDevValid = false, false
+	c.adjustOldestDataPointIdx()
+
+	return nil
+}
+
+func (c *FixedVWAPCalc) toFixedDataPoint(price, size decimal.Decimal) (FixedVWAPCalcDataPoint, error) {
+	scaledPrice, err := toFixed(price, c.priceScale)
+	if err != nil {
+		return FixedVWAPCalcDataPoint{}, fmt.Errorf("FixedVWAPCalc.AddDataPoint invalid price: %v", err)
+	}
+
+	scaledSize, err := toFixed(size, c.sizeScale)
+	if err != nil {
+		return FixedVWAPCalcDataPoint{}, fmt.Errorf("FixedVWAPCalc.AddDataPoint invalid size: %v", err)
+	}
+
+	return FixedVWAPCalcDataPoint{Price: scaledPrice, Size: scaledSize}, nil
+}
+
+// fixedTotals are the running totals of FixedVWAPCalc.
+type fixedTotals struct {
+	value        uint128
+	squaredValue uint128
+	size         uint64
+}
+
+// nextTotals returns the totals once the new data point replaces the oldest one, or the overflow it would cause.
+func (c *FixedVWAPCalc) nextTotals(newDP FixedVWAPCalcDataPoint) (fixedTotals, error) {
+	oldDP := c.dataPoints[c.oldestDataPointIdx]
+
+	totalSize, err := c.adjustedTotalSize(oldDP, newDP)
+	if err != nil {
+		return fixedTotals{}, err
+	}
+
+	totalValue, err := adjustedTotal(c.totalValue, mul64(oldDP.Price, oldDP.Size), mul64(newDP.Price, newDP.Size))
+	if err != nil {
+		return fixedTotals{}, fmt.Errorf("FixedVWAPCalc.AddScaledDataPoint total value: %v", err)
+	}
+
+	oldSquaredValue, err := oldDP.squaredValue()
+	if err != nil {
+		return fixedTotals{}, err
+	}
+
+	newSquaredValue, err := newDP.squaredValue()
+	if err != nil {
+		return fixedTotals{}, err
+	}
+
+	totalSquaredValue, err := adjustedTotal(c.totalSquaredValue, oldSquaredValue, newSquaredValue)
+	if err != nil {
+		return fixedTotals{}, fmt.Errorf("FixedVWAPCalc.AddScaledDataPoint total squared value: %v", err)
+	}
+
+	return fixedTotals{value: totalValue, squaredValue: totalSquaredValue, size: totalSize}, nil
+}
+
+// Verify recomputes the totals from the data points and returns their drift from the running totals.
+// On drift, the running totals are resynchronized with the recomputed ones.
+func (c *FixedVWAPCalc) Verify() (Drift, error) {
+	if c.windowSize != len(c.dataPoints) || c.oldestDataPointIdx < 0 || c.oldestDataPointIdx >= c.windowSize {
+		return Drift{}, fmt.Errorf("FixedVWAPCalc.Verify failed data integrity test")
+	}
+
+	var (
+		totalValue, totalSquaredValue uint128
+		totalSize                     uint64
+		err                           error
+	)
+	for _, dp := range c.dataPoints {
+		if totalSize > math.MaxUint64-dp.Size {
+			return Drift{}, fmt.Errorf("FixedVWAPCalc.Verify total size overflow")
+		}
+		totalSize += dp.Size
+
+		if totalValue, err = totalValue.add(mul64(dp.Price, dp.Size)); err != nil {
+			return Drift{}, fmt.Errorf("FixedVWAPCalc.Verify total value: %v", err)
+		}
+
+		squaredValue, err := dp.squaredValue()
+		if err != nil {
+			return Drift{}, err
+		}
+
+		if totalSquaredValue, err = totalSquaredValue.add(squaredValue); err != nil {
+			return Drift{}, fmt.Errorf("FixedVWAPCalc.Verify total squared value: %v", err)
+		}
+	}
+
+	recomputed := FixedVWAPCalc{
+		priceScale:        c.priceScale,
+		sizeScale:         c.sizeScale,
+		totalValue:        totalValue,
+		totalSquaredValue: totalSquaredValue,
+		totalSize:         totalSize,
+	}
+	drift := Drift{
+		TotalValue:        c.totalValueDecimal().Sub(recomputed.totalValueDecimal()),
+		TotalSquaredValue: c.totalSquaredValueDecimal().Sub(recomputed.totalSquaredValueDecimal()),
+		TotalSize:         c.totalSizeDecimal().Sub(recomputed.totalSizeDecimal()),
+	}
+
+	c.totalValue = totalValue
+	c.totalSquaredValue = totalSquaredValue
+	c.totalSize = totalSize
+	c.vwapValid, c.stdDevValid = false, false
+
+	return drift, nil
+}
+
+func (c *FixedVWAPCalc) adjustedTotalSize(oldDP, newDP FixedVWAPCalcDataPoint) (uint64, error) {
+	totalSize := c.totalSize - oldDP.Size
+	if totalSize > math.MaxUint64-newDP.Size {
+		return 0, fmt.Errorf("FixedVWAPCalc.AddScaledDataPoint total size overflow")
+	}
+
+	return totalSize + newDP.Size, nil
+}
+
+func adjustedTotal(total, oldVal, newVal uint128) (uint128, error) {
+	total, err := total.sub(oldVal)
+	if err != nil {
+		return uint128{}, err
+	}
+
+	return total.add(newVal)
+}
+
+func (t FixedVWAPCalcDataPoint) squaredValue() (uint128, error) {
+	squaredPrice := mul64(t.Price, t.Price)
+	if squaredPrice.hi != 0 {
+		return uint128{}, fmt.Errorf("FixedVWAPCalc squared price overflow for price %d", t.Price)
+	}
+
+	return mul64(squaredPrice.lo, t.Size), nil
+}
+
+func (c *FixedVWAPCalc) adjustOldestDataPointIdx() {
+	c.oldestDataPointIdx += 1
+	if c.oldestDataPointIdx == c.windowSize {
+		c.oldestDataPointIdx = 0
+	}
+}
+
+func (c *FixedVWAPCalc) totalValueDecimal() decimal.Decimal {
+	return fixedToDecimal(c.totalValue, -(c.priceScale + c.sizeScale))
+}
+
+// fixedToDecimal returns v·10^exp, without big.Int unless v does not fit in an int64.
+func fixedToDecimal(v uint128, exp int32) decimal.Decimal {
+	if v.hi == 0 && v.lo <= math.MaxInt64 {
+		return decimal.New(int64(v.lo), exp)
+	}
+
+	return decimal.NewFromBigInt(v.bigInt(), exp)
+}
+
+func (c *FixedVWAPCalc) totalSquaredValueDecimal() decimal.Decimal {
+	return decimal.NewFromBigInt(c.totalSquaredValue.bigInt(), -(2*c.priceScale + c.sizeScale))
+}
+
+func (c *FixedVWAPCalc) totalSizeDecimal() decimal.Decimal {
+	if c.totalSize <= math.MaxInt64 {
+		return decimal.New(int64(c.totalSize), -c.sizeScale)
+	}
+
+	return decimal.NewFromBigInt(new(big.Int).SetUint64(c.totalSize), -c.sizeScale)
+}
+
+// pow10 holds the powers of ten fitting in an uint64
+var pow10 = func() []uint64 {
+	p := []uint64{1}
+	for p[len(p)-1] <= math.MaxUint64/10 {
+		p = append(p, p[len(p)-1]*10)
+	}
+
+	return p
+}()
+
+// incrementScale returns the number of decimal places of a positive increment, e.g. 2 for "0.01".
+func incrementScale(increment decimal.Decimal) (int32, error) {
+	if !increment.IsPositive() {
+		return 0, fmt.Errorf("increment %s is not positive", increment)
+	}
+
+	coefficient := increment.Coefficient()
+	scale := -increment.Exponent()
+	ten := big.NewInt(10)
+	for scale > 0 && new(big.Int).Mod(coefficient, ten).Sign() == 0 {
+		coefficient.Quo(coefficient, ten)
+		scale--
+	}
+
+	if scale < 0 {
+		scale = 0
+	}
+
+	return scale, nil
+}
+
+// toFixed converts d to fixed-point units with the given scale.
+// It fails if d is negative, has more decimal places than the scale or does not fit in an uint64.
+func toFixed(d decimal.Decimal, scale int32) (uint64, error) {
+	if d.IsNegative() {
+		return 0, fmt.Errorf("%s is negative", d)
+	}
+
+	coefficient := d.Coefficient()
+	if !coefficient.IsUint64() {
+		return 0, fmt.Errorf("%s overflows fixed-point", d)
+	}
+
+	v := coefficient.Uint64()
+	shift := d.Exponent() + scale
+
+	for ; shift < 0; shift++ {
+		if v%10 != 0 {
+			return 0, fmt.Errorf("%s has more than %d decimal places", d, scale)
+		}
+		v /= 10
+	}
+
+	for ; shift > 0; shift-- {
+		if v > math.MaxUint64/10 {
+			return 0, fmt.Errorf("%s overflows fixed-point", d)
+		}
+		v *= 10
+	}
+
+	return v, nil
+}
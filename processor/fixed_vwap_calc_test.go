@@ -0,0 +1,298 @@
+package processor
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewFixedVWAPCalc(t *testing.T) {
+	tests := []struct {
+		name           string
+		windowSize     int
+		priceIncrement string
+		sizeIncrement  string
+		wantPriceScale int32
+		wantSizeScale  int32
+		wantErr        bool
+	}{
+		{
+			name:           "invalid window size",
+			priceIncrement: "0.01",
+			sizeIncrement:  "0.00000001",
+			wantErr:        true,
+		},
+		{
+			name:           "invalid price increment",
+			windowSize:     3,
+			priceIncrement: "0",
+			sizeIncrement:  "0.00000001",
+			wantErr:        true,
+		},
+		{
+			name:           "invalid size increment",
+			windowSize:     3,
+			priceIncrement: "0.01",
+			sizeIncrement:  "-1",
+			wantErr:        true,
+		},
+		{
+			name:           "valid",
+			windowSize:     3,
+			priceIncrement: "0.010",
+			sizeIncrement:  "0.00000001",
+			wantPriceScale: 2,
+			wantSizeScale:  8,
+		},
+		{
+			name:           "valid with integer increments",
+			windowSize:     3,
+			priceIncrement: "5",
+			sizeIncrement:  "100",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				c, err := NewFixedVWAPCalc(
+					tt.windowSize,
+					decimal.RequireFromString(tt.priceIncrement),
+					decimal.RequireFromString(tt.sizeIncrement),
+				)
+
+				if tt.wantErr {
+					require.Error(t, err)
+
+					return
+				}
+
+				require.NoError(t, err)
+				assert.Equal(t, tt.wantPriceScale, c.priceScale)
+				assert.Equal(t, tt.wantSizeScale, c.sizeScale)
+				assert.Equal(t, "0", c.VWAP().String())
+			},
+		)
+	}
+}
+
+func TestToFixed(t *testing.T) {
+	testCases := []struct {
+		d       string
+		scale   int32
+		want    uint64
+		wantErr bool
+	}{
+		{"0", 2, 0, false},
+		{"20433.31", 2, 2043331, false},
+		{"20433.3", 2, 2043330, false},
+		{"20433.310", 2, 2043331, false},
+		{"1e3", 0, 1000, false},
+		{"20433.311", 2, 0, true},
+		{"-1", 2, 0, true},
+		{"18446744073709551615", 0, math.MaxUint64, false},
+		{"18446744073709551616", 0, 0, true},
+		{"18446744073709551615", 1, 0, true},
+	}
+	for _, tc := range testCases {
+		t.Run(
+			fmt.Sprintf("%v", tc), func(t *testing.T) {
+				got, err := toFixed(decimal.RequireFromString(tc.d), tc.scale)
+
+				if tc.wantErr {
+					require.Error(t, err)
+
+					return
+				}
+
+				require.NoError(t, err)
+				assert.Equal(t, tc.want, got)
+			},
+		)
+	}
+}
+
+func TestFixedVWAPCalc_VWAPFlow(t *testing.T) {
+	c, err := NewFixedVWAPCalc(3, decimal.RequireFromString("0.1"), decimal.RequireFromString("0.1"))
+	require.NoError(t, err)
+
+	sequence := []struct {
+		price    float64
+		size     float64
+		wantVWAP string
+	}{
+		{1.1, 1.1, "1.1"},
+		{2.2, 2.2, "1.8333333333333333"},
+		{3.3, 3.3, "2.5666666666666667"},
+		{4.4, 4.4, "3.5444444444444444"},
+		{5.5, 5.5, "4.5833333333333333"},
+	}
+
+	for i, s := range sequence {
+		err = c.AddDataPoint(decimal.NewFromFloat(s.price), decimal.NewFromFloat(s.size))
+		require.NoError(t, err)
+		require.Equalf(t, s.wantVWAP, c.VWAP().String(), "failed at step %d", i)
+	}
+
+	require.Error(t, c.AddDataPoint(decimal.NewFromFloat(1.11), decimal.NewFromFloat(1)), "too many decimal places")
+	require.Error(t, c.AddDataPoint(decimal.NewFromFloat(-1.1), decimal.NewFromFloat(1)), "negative price")
+}
+
+func TestFixedVWAPCalc_overflow(t *testing.T) {
+	c, err := NewFixedVWAPCalc(2, decimal.NewFromInt(1), decimal.NewFromInt(1))
+	require.NoError(t, err)
+
+	require.NoError(t, c.AddScaledDataPoint(1, math.MaxUint64-1))
+	require.Error(t, c.AddScaledDataPoint(1, 2), "total size overflow")
+	require.Error(t, c.AddScaledDataPoint(math.MaxUint32+1, 1), "squared price overflow")
+
+	// the calculator is unchanged after an overflow
+	assert.Equal(t, uint64(math.MaxUint64-1), c.totalSize)
+	assert.Equal(t, "1", c.VWAP().String())
+
+	require.NoError(t, c.AddScaledDataPoint(math.MaxUint32, 1))
+	assert.Equal(t, uint64(math.MaxUint64), c.totalSize)
+}
+
+func TestFixedVWAPCalc_AddScaledDataPoint_allocs(t *testing.T) {
+	c, err := NewFixedVWAPCalc(4, decimal.NewFromInt(1), decimal.NewFromInt(1))
+	require.NoError(t, err)
+
+	price := uint64(0)
+	allocs := testing.AllocsPerRun(
+		100, func() {
+			price++
+			require.NoError(t, c.AddScaledDataPoint(price, 1))
+		},
+	)
+	assert.Zero(t, allocs)
+}
+
+// TestFixedVWAPCalc_differential feeds the same random trades to VWAPCalc and FixedVWAPCalc
+// and expects the exact same results.
+func TestFixedVWAPCalc_differential(t *testing.T) {
+	const (
+		windowSize = 200
+		steps      = 5000
+	)
+
+	priceIncrement := decimal.RequireFromString("0.01")
+	sizeIncrement := decimal.RequireFromString("0.00000001")
+
+	c, err := NewVWAPCalc(windowSize)
+	require.NoError(t, err)
+	fc, err := NewFixedVWAPCalc(windowSize, priceIncrement, sizeIncrement)
+	require.NoError(t, err)
+
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < steps; i++ {
+		price := decimal.New(1_500_000+rnd.Int63n(1_000_000), -2)
+		size := decimal.New(rnd.Int63n(1_000_000_000), -8)
+
+		require.NoError(t, c.AddDataPoint(price, size))
+		require.NoError(t, fc.AddDataPoint(price, size))
+		require.Truef(t, c.VWAP().Equal(fc.VWAP()), "VWAP %s != %s at step %d", c.VWAP(), fc.VWAP(), i)
+		require.Truef(t, c.StdDev().Equal(fc.StdDev()), "StdDev %s != %s at step %d", c.StdDev(), fc.StdDev(), i)
+	}
+}
+
+func BenchmarkVWAPCalc_AddDataPoint(b *testing.B) {
+	c, err := NewVWAPCalc(200)
+	require.NoError(b, err)
+	prices, sizes := benchmarkDataPoints()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = c.AddDataPoint(prices[i%len(prices)], sizes[i%len(sizes)])
+	}
+}
+
+func BenchmarkFixedVWAPCalc_AddDataPoint(b *testing.B) {
+	c, err := NewFixedVWAPCalc(200, decimal.RequireFromString("0.01"), decimal.RequireFromString("0.00000001"))
+	require.NoError(b, err)
+	prices, sizes := benchmarkDataPoints()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = c.AddDataPoint(prices[i%len(prices)], sizes[i%len(sizes)])
+	}
+}
+
+func BenchmarkFixedVWAPCalc_AddScaledDataPoint(b *testing.B) {
+	c, err := NewFixedVWAPCalc(200, decimal.RequireFromString("0.01"), decimal.RequireFromString("0.00000001"))
+	require.NoError(b, err)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = c.AddScaledDataPoint(uint64(2_000_000+i%1000), uint64(10_000_000+i%1000))
+	}
+}
+
+// BenchmarkVWAPCalc_addAndRead adds a data point and reads the results as the processor does for every trade.
+func BenchmarkVWAPCalc_addAndRead(b *testing.B) {
+	c, err := NewVWAPCalc(200)
+	require.NoError(b, err)
+
+	benchmarkAddAndRead(b, c)
+}
+
+func BenchmarkFixedVWAPCalc_addAndRead(b *testing.B) {
+	c, err := NewFixedVWAPCalc(200, decimal.RequireFromString("0.01"), decimal.RequireFromString("0.00000001"))
+	require.NoError(b, err)
+
+	benchmarkAddAndRead(b, c)
+}
+
+// benchmarkAddAndRead reads the VWAP for the price deviation rule and for the result. The bands' standard deviation
+// is left out, as its square root costs the same with both calculators.
+func benchmarkAddAndRead(b *testing.B, c interface {
+	AddDataPoint(price, size decimal.Decimal) error
+	VWAP() decimal.Decimal
+}) {
+	prices, sizes := benchmarkDataPoints()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = c.VWAP()
+		_ = c.AddDataPoint(prices[i%len(prices)], sizes[i%len(sizes)])
+		_ = c.VWAP()
+	}
+}
+
+func benchmarkDataPoints() ([]decimal.Decimal, []decimal.Decimal) {
+	rnd := rand.New(rand.NewSource(1))
+	prices := make([]decimal.Decimal, 1000)
+	sizes := make([]decimal.Decimal, 1000)
+	for i := range prices {
+		prices[i] = decimal.New(1_500_000+rnd.Int63n(1_000_000), -2)
+		sizes[i] = decimal.New(rnd.Int63n(1_000_000_000), -8)
+	}
+
+	return prices, sizes
+}
+
+func TestFixedVWAPCalc_Verify(t *testing.T) {
+	c, err := NewFixedVWAPCalc(2, decimal.RequireFromString("0.1"), decimal.RequireFromString("0.1"))
+	require.NoError(t, err)
+	require.NoError(t, c.AddDataPoint(decimal.NewFromInt(1), decimal.NewFromInt(1)))
+	require.NoError(t, c.AddDataPoint(decimal.NewFromInt(3), decimal.NewFromInt(1)))
+
+	drift, err := c.Verify()
+	require.NoError(t, err)
+	assert.True(t, drift.IsZero())
+
+	c.totalSize += 10
+	drift, err = c.Verify()
+	require.NoError(t, err)
+	assert.Equal(t, "1", drift.TotalSize.String())
+	assert.Equal(t, "2", c.VWAP().String())
+}
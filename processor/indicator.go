@@ -0,0 +1,149 @@
+package processor
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/aprln/vwap-engine/config"
+	"github.com/aprln/vwap-engine/model"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	IndicatorNameVWAP       = "vwap"
+	IndicatorNameTWAP       = "twap"
+	IndicatorNameMedian     = "median"
+	IndicatorNameVWMedian   = "vw_median"
+	IndicatorNameTradeCount = "trade_count"
+	IndicatorNameNotional   = "notional"
+)
+
+// Indicator is a metric that is updated incrementally with every trade.
+type Indicator interface {
+	Update(trade model.Trade) error
+	Value() decimal.Decimal
+}
+
+// TradeChecker is implemented by indicators whose Update may fail. Check returns the error Update would return
+// for the trade without updating the indicator, so a trade is only applied once every indicator accepts it.
+type TradeChecker interface {
+	Check(trade model.Trade) error
+}
+
+// DataPointChecker is implemented by calculators whose AddDataPoint may fail. CheckDataPoint returns the error
+// AddDataPoint would return without adding the data point.
+type DataPointChecker interface {
+	CheckDataPoint(price, size decimal.Decimal) error
+}
+
+// IndicatorFactory creates an indicator for a trading pair from its VWAP config.
+type IndicatorFactory func(vwapCfg config.VWAP) (Indicator, error)
+
+var (
+	indicatorsMu sync.RWMutex
+	indicators   = map[string]IndicatorFactory{
+		IndicatorNameVWAP:       newVWAPIndicator,
+		IndicatorNameTWAP:       newTWAPIndicator,
+		IndicatorNameMedian:     newMedianIndicator,
+		IndicatorNameVWMedian:   newVWMedianIndicator,
+		IndicatorNameTradeCount: newTradeCountIndicator,
+		IndicatorNameNotional:   newNotionalIndicator,
+	}
+)
+
+// RegisterIndicator makes an indicator available by name to the VWAP_INDICATORS config.
+// It panics if an indicator is already registered with the same name.
+func RegisterIndicator(name string, factory IndicatorFactory) {
+	indicatorsMu.Lock()
+	defer indicatorsMu.Unlock()
+
+	if _, ok := indicators[name]; ok {
+		panic("indicator already registered: " + name)
+	}
+
+	indicators[name] = factory
+}
+
+func newIndicator(name string, vwapCfg config.VWAP) (Indicator, error) {
+	indicatorsMu.RLock()
+	factory, ok := indicators[name]
+	indicatorsMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf(`indicator "%s" is unsupported`, name)
+	}
+
+	return factory(vwapCfg)
+}
+
+// NamedIndicator is an indicator published under Name.
+type NamedIndicator struct {
+	Name string
+	Indicator
+}
+
+// newIndicators creates the indicators listed in the config, except VWAP which every processor computes.
+// The indicators keep a window of the last WindowSize trades, which is only the window of VWAP for the calculators
+// windowed by trade count, so they cannot be combined with the other calculators.
+func newIndicators(vwapCfg config.VWAP) ([]NamedIndicator, error) {
+	var named []NamedIndicator
+	for _, name := range vwapCfg.Indicators {
+		if name == "" || name == IndicatorNameVWAP {
+			continue
+		}
+
+		ind, err := newIndicator(name, vwapCfg)
+		if err != nil {
+			return nil, err
+		}
+
+		named = append(named, NamedIndicator{Name: name, Indicator: ind})
+	}
+
+	if len(named) > 0 && vwapCfg.Calculator != config.CalculatorNameWindow &&
+		vwapCfg.Calculator != config.CalculatorNameFixed {
+		return nil, fmt.Errorf(
+			`calculator "%s" has no window of trades for the indicators to be computed over`, vwapCfg.Calculator,
+		)
+	}
+
+	return named, nil
+}
+
+func newVWAPIndicator(vwapCfg config.VWAP) (Indicator, error) {
+	c, err := newCalculator(vwapCfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewCalcIndicator(c), nil
+}
+
+func NewCalcIndicator(calc VWAPCalculator) CalcIndicator {
+	return CalcIndicator{calc: calc}
+}
+
+// CalcIndicator adapts a VWAPCalculator to the Indicator interface.
+type CalcIndicator struct {
+	calc VWAPCalculator
+}
+
+func (i CalcIndicator) Update(trade model.Trade) error {
+	if a, ok := i.calc.(TradeTimeAdvancer); ok {
+		a.AdvanceTo(trade.Time)
+	}
+
+	return i.calc.AddDataPoint(trade.Price, trade.Size)
+}
+
+func (i CalcIndicator) Check(trade model.Trade) error {
+	if c, ok := i.calc.(DataPointChecker); ok {
+		return c.CheckDataPoint(trade.Price, trade.Size)
+	}
+
+	return nil
+}
+
+func (i CalcIndicator) Value() decimal.Decimal {
+	return i.calc.VWAP()
+}
@@ -0,0 +1,74 @@
+package processor
+
+import (
+	"testing"
+
+	"github.com/aprln/vwap-engine/config"
+	"github.com/aprln/vwap-engine/model"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type lastPriceIndicator struct {
+	price decimal.Decimal
+}
+
+func (i *lastPriceIndicator) Update(trade model.Trade) error {
+	i.price = trade.Price
+
+	return nil
+}
+
+func (i *lastPriceIndicator) Value() decimal.Decimal {
+	return i.price
+}
+
+func TestRegisterIndicator(t *testing.T) {
+	RegisterIndicator(
+		"test_last_price", func(vwapCfg config.VWAP) (Indicator, error) {
+			return &lastPriceIndicator{}, nil
+		},
+	)
+
+	require.Panics(
+		t, func() {
+			RegisterIndicator(IndicatorNameTWAP, newTWAPIndicator)
+		},
+	)
+
+	named, err := newIndicators(
+		config.VWAP{
+			WindowSize: 3,
+			PairVWAP: config.PairVWAP{
+				Calculator: config.CalculatorNameWindow,
+				Indicators: []string{IndicatorNameVWAP, "test_last_price"},
+			},
+		},
+	)
+	require.NoError(t, err)
+	require.Len(t, named, 1)
+	assert.Equal(t, "test_last_price", named[0].Name)
+
+	_, err = newIndicators(config.VWAP{PairVWAP: config.PairVWAP{Indicators: []string{"banana"}}})
+	require.Error(t, err)
+
+	_, err = newIndicators(
+		config.VWAP{
+			PairVWAP: config.PairVWAP{Calculator: config.CalculatorNameAnchored, Indicators: []string{"test_last_price"}},
+		},
+	)
+	require.Error(t, err, "the calculator has no window of trades")
+}
+
+func TestCalcIndicator(t *testing.T) {
+	ind, err := newIndicator(
+		IndicatorNameVWAP,
+		config.VWAP{WindowSize: 2, PairVWAP: config.PairVWAP{Calculator: config.CalculatorNameWindow}},
+	)
+	require.NoError(t, err)
+
+	require.NoError(t, ind.Update(model.Trade{Price: decimal.NewFromInt(1), Size: decimal.NewFromInt(1)}))
+	require.NoError(t, ind.Update(model.Trade{Price: decimal.NewFromInt(3), Size: decimal.NewFromInt(3)}))
+	assert.Equal(t, "2.5", ind.Value().String())
+}
@@ -1,7 +1,11 @@
 package processor
 
 import (
+	"encoding/json"
+	"fmt"
 	"log"
+	"sync/atomic"
+	"time"
 
 	"github.com/aprln/vwap-engine/config"
 	"github.com/aprln/vwap-engine/model"
@@ -13,55 +17,430 @@ type VWAPCalculator interface {
 	AddDataPoint(price, size decimal.Decimal) error
 }
 
-func SetUp(vwapCfg config.VWAP) (Processor, error) {
-	c, err := NewVWAPCalc(vwapCfg.WindowSize)
+// TradeTimeAdvancer is implemented by calculators whose state depends on trade time.
+// AdvanceTo is called with the trade time before the trade's data point is added.
+type TradeTimeAdvancer interface {
+	AdvanceTo(t time.Time)
+}
+
+// SessionReporter is implemented by calculators that accumulate data points per session.
+type SessionReporter interface {
+	SessionStart() time.Time
+}
+
+// StdDevReporter is implemented by calculators that track the volume-weighted standard deviation of price.
+type StdDevReporter interface {
+	StdDev() decimal.Decimal
+}
+
+// Verifier is implemented by calculators that can recompute their running totals from their data points.
+// Verify returns the drift of the running totals and resynchronizes them.
+type Verifier interface {
+	Verify() (Drift, error)
+}
+
+// QuarantineSink receives the trades rejected by the processor.
+type QuarantineSink interface {
+	Quarantine(rejected model.RejectedTrade)
+}
+
+// WindowReporter is implemented by calculators that calculate VWAP over a window of trades.
+type WindowReporter interface {
+	Window() model.Window
+}
+
+// SessionResetter is implemented by calculators whose session can be reset on demand.
+type SessionResetter interface {
+	Reset()
+}
+
+func SetUp(vwapCfg config.VWAP, validationCfg config.Validation, quarantine QuarantineSink) (Processor, error) {
+	c, err := newCalculator(vwapCfg)
 	if err != nil {
 		return Processor{}, err
 	}
 
-	return New(vwapCfg, c), nil
+	indicators, err := newIndicators(vwapCfg)
+	if err != nil {
+		return Processor{}, err
+	}
+
+	emitPolicy, err := ParseEmitPolicy(vwapCfg.Emit)
+	if err != nil {
+		return Processor{}, err
+	}
+
+	if _, ok := c.(WindowReporter); !ok && vwapCfg.WarmUpEnabled() {
+		return Processor{}, fmt.Errorf(`calculator "%s" has no window to warm up`, vwapCfg.Calculator)
+	}
+
+	return New(vwapCfg, c, indicators...).
+		WithValidation(NewValidator(validationCfg), quarantine).
+		WithEmitPolicy(emitPolicy), nil
+}
+
+func newCalculator(vwapCfg config.VWAP) (VWAPCalculator, error) {
+	switch vwapCfg.Calculator {
+	case config.CalculatorNameWindow:
+		c, err := NewVWAPCalc(vwapCfg.WindowSize)
+		if err != nil {
+			return nil, err
+		}
+
+		return c, nil
+
+	case config.CalculatorNameAnchored:
+		anchor, err := ParseAnchor(vwapCfg.Anchor)
+		if err != nil {
+			return nil, err
+		}
+
+		return NewAnchoredVWAPCalc(anchor), nil
+
+	case config.CalculatorNameVolume:
+		c, err := NewVolumeVWAPCalc(vwapCfg.WindowVolume)
+		if err != nil {
+			return nil, err
+		}
+
+		return c, nil
+
+	case config.CalculatorNameEWMA:
+		halfLife, err := ParseHalfLife(vwapCfg.EWMAHalfLife)
+		if err != nil {
+			return nil, err
+		}
+
+		return NewEWMAVWAPCalc(halfLife), nil
+
+	case config.CalculatorNameFixed:
+		c, err := NewFixedVWAPCalc(vwapCfg.WindowSize, vwapCfg.PriceIncrement, vwapCfg.SizeIncrement)
+		if err != nil {
+			return nil, err
+		}
+
+		return c, nil
+
+	default:
+		return nil, fmt.Errorf(`calculator "%s" is unsupported`, vwapCfg.Calculator)
+	}
 }
 
-func New(vwapCfg config.VWAP, calc VWAPCalculator) Processor {
+// New creates a processor publishing the VWAP of calc along with the extra indicators.
+// The VWAP calculator is registered as the first indicator, so every indicator is updated the same way.
+func New(vwapCfg config.VWAP, calc VWAPCalculator, indicators ...NamedIndicator) Processor {
 	return Processor{
-		vwapCfg: vwapCfg,
-		calc:    calc,
+		vwapCfg:         vwapCfg,
+		calc:            calc,
+		indicators:      append([]NamedIndicator{{Name: IndicatorNameVWAP, Indicator: NewCalcIndicator(calc)}}, indicators...),
+		verifyRequested: &atomic.Bool{},
+		verifyStats:     &VerifyStats{},
 	}
 }
 
 type Processor struct {
-	calc    VWAPCalculator
-	vwapCfg config.VWAP
+	calc            VWAPCalculator
+	indicators      []NamedIndicator
+	vwapCfg         config.VWAP
+	verifyRequested *atomic.Bool
+	verifyStats     *VerifyStats
+	validator       *Validator
+	quarantine      QuarantineSink
+	emitPolicy      EmitPolicy
+	tradingPair     string
+	snapshotStore   SnapshotStore
+	snapshotCfg     config.Snapshot
+	restoredTradeID int64
+	// indicatorTrades is the window of the extra indicators, only kept to be snapshotted
+	indicatorTrades *tradeWindow
+	restoredEmitter json.RawMessage
+}
+
+// WithEmitPolicy returns a copy of the processor that only publishes the VWAP results allowed by the policy.
+func (p Processor) WithEmitPolicy(policy EmitPolicy) Processor {
+	p.emitPolicy = policy
+
+	return p
+}
+
+// WithValidation returns a copy of the processor that validates every trade before processing it.
+// Rejected trades, as well as trades the calculators fail on, are sent to the quarantine sink
+// instead of stopping the processor.
+func (p Processor) WithValidation(validator Validator, quarantine QuarantineSink) Processor {
+	p.validator = &validator
+	p.quarantine = quarantine
+
+	return p
+}
+
+// ResetSession starts a new session with the next trade if the calculator supports it.
+// It is safe to call while the processor is running.
+func (p Processor) ResetSession() error {
+	r, ok := p.calc.(SessionResetter)
+	if !ok {
+		return fmt.Errorf("calculator does not support session reset")
+	}
+
+	r.Reset()
+
+	return nil
+}
+
+// RequestVerify asks for the calculator's running totals to be verified before the next trade is processed.
+// It is safe to call while the processor is running.
+func (p Processor) RequestVerify() error {
+	if _, ok := p.calc.(Verifier); !ok {
+		return fmt.Errorf("calculator does not support verification")
+	}
+
+	p.verifyRequested.Store(true)
+
+	return nil
+}
+
+// VerifyStats returns the counts of the drifts and failures found by the verifications of the calculator.
+func (p Processor) VerifyStats() *VerifyStats {
+	return p.verifyStats
 }
 
 func (p Processor) GoProcess(in <-chan model.Trade) chan model.VWAP {
 	out := make(chan model.VWAP, 1)
 
-	go p.processForever(in, out)
+	go p.processForever(in, out, nil)
 
 	return out
 }
 
-func (p Processor) processForever(in <-chan model.Trade, out chan<- model.VWAP) {
+// GoProcessTapped is GoProcess also writing every VWAP result to tap before the emit policy, for the stages
+// that must see them all, e.g. the alerts, whose level crossings could be held back by the policy.
+// Both channels must be read, as the processor waits for the slowest.
+func (p Processor) GoProcessTapped(in <-chan model.Trade) (out, tap chan model.VWAP) {
+	out = make(chan model.VWAP, 1)
+	tap = make(chan model.VWAP, 1)
+
+	go p.processForever(in, out, tap)
+
+	return out, tap
+}
+
+func (p Processor) processForever(in <-chan model.Trade, out, tap chan<- model.VWAP) {
 	defer close(out)
+	if tap != nil {
+		defer close(tap)
+	}
+
+	em := newEmitter(p.emitPolicy, time.Now)
+	if p.restoredEmitter != nil {
+		if err := em.restoreState(p.restoredEmitter); err != nil {
+			log.Printf("failed to restore %s emitter: %v", p.tradingPair, err)
+		}
+	}
+	// flush fires when the result held back by the emit policy may be published
+	var flush <-chan time.Time
+
+	var snapshotTick <-chan time.Time
+	if p.snapshotStore != nil && p.snapshotCfg.Interval > 0 {
+		ticker := time.NewTicker(p.snapshotCfg.Interval)
+		defer ticker.Stop()
+		snapshotTick = ticker.C
+	}
+	lastTradeID := p.restoredTradeID
 
+	updates := 0
 	for {
-		trade, more := <-in
-		if !more {
-			log.Println("no more to read from the trade channel")
+		select {
+		case trade, more := <-in:
+			if !more {
+				log.Println("no more to read from the trade channel")
 
-			break
-		}
-		err := p.calc.AddDataPoint(trade.Price, trade.Size)
-		if err != nil {
-			log.Printf("calculator error %v", err)
+				if vwap, ok := em.flush(); ok {
+					out <- vwap
+				}
+				p.snapshot(lastTradeID, em)
+
+				return
+			}
+			if trade.ID != 0 && trade.ID <= p.restoredTradeID {
+				continue
+			}
+			if p.validator != nil {
+				if err := p.validator.Validate(trade, p.calc.VWAP); err != nil {
+					p.reject(trade, err.Error())
+
+					continue
+				}
+			}
+			updates++
+			if p.verifyRequested.Swap(false) || (p.vwapCfg.VerifyEvery > 0 && updates%p.vwapCfg.VerifyEvery == 0) {
+				p.verify(trade.TradingPair)
+			}
+			if err := p.updateIndicators(trade); err != nil {
+				p.reject(trade, fmt.Sprintf("calculator error: %v", err))
+
+				continue
+			}
+			if trade.ID > lastTradeID {
+				lastTradeID = trade.ID
+			}
+			if p.indicatorTrades != nil {
+				p.indicatorTrades.push(trade)
+			}
+
+			vwap := p.newVWAP(trade)
+			if !p.warmedUp(vwap) {
+				continue
+			}
+			if tap != nil {
+				tap <- vwap
+			}
+			if em.offer(vwap) {
+				out <- vwap
+
+				continue
+			}
+			if d, ok := em.flushIn(); ok && flush == nil {
+				flush = time.After(d)
+			}
+
+		case <-snapshotTick:
+			p.snapshot(lastTradeID, em)
 
-			break
+		case <-flush:
+			flush = nil
+			if vwap, ok := em.flush(); ok {
+				out <- vwap
+			}
 		}
-		out <- model.VWAP{
+	}
+}
+
+// reject sends the trade to the quarantine sink, or logs it if there is none.
+func (p Processor) reject(trade model.Trade, reason string) {
+	if p.quarantine == nil {
+		log.Printf("rejected %s trade: %s", trade.TradingPair, reason)
+
+		return
+	}
+
+	p.quarantine.Quarantine(
+		model.RejectedTrade{
 			TradingPair: trade.TradingPair,
-			LastTradeAt: trade.Time,
-			VWAP:        p.calc.VWAP(),
+			Size:        trade.Size,
+			Price:       trade.Price,
+			Time:        trade.Time,
+			Reason:      reason,
+			RejectedAt:  time.Now().UTC(),
+		},
+	)
+}
+
+// verify checks the calculator's running totals and counts any drift, which the calculator resynchronizes.
+// A failed verification is counted and logged, and the trading pair keeps running.
+func (p Processor) verify(tradingPair string) {
+	v, ok := p.calc.(Verifier)
+	if !ok {
+		return
+	}
+
+	drift, err := v.Verify()
+	if err != nil {
+		failures := p.verifyStats.Failures.Add(1)
+		log.Printf("%s calculator verification error (%d so far): %v", tradingPair, failures, err)
+
+		return
+	}
+
+	if !drift.IsZero() {
+		resyncs := p.verifyStats.Resyncs.Add(1)
+		log.Printf("%s calculator drift detected and resynchronized (%d so far): %s", tradingPair, resyncs, drift)
+	}
+}
+
+// updateIndicators checks every indicator accepts the trade before updating any, so a rejected trade leaves
+// all the indicators as they were.
+func (p Processor) updateIndicators(trade model.Trade) error {
+	for _, ind := range p.indicators {
+		if c, ok := ind.Indicator.(TradeChecker); ok {
+			if err := c.Check(trade); err != nil {
+				return fmt.Errorf("indicator %s: %v", ind.Name, err)
+			}
+		}
+	}
+
+	for _, ind := range p.indicators {
+		if err := ind.Update(trade); err != nil {
+			return fmt.Errorf("indicator %s: %v", ind.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func (p Processor) newVWAP(trade model.Trade) model.VWAP {
+	vwap := model.VWAP{
+		TradingPair: trade.TradingPair,
+		LastTradeAt: trade.Time,
+		VWAP:        p.calc.VWAP(),
+		LastPrice:   trade.Price,
+	}
+
+	if r, ok := p.calc.(SessionReporter); ok {
+		sessionStart := r.SessionStart()
+		vwap.SessionStart = &sessionStart
+	}
+
+	if r, ok := p.calc.(WindowReporter); ok {
+		window := r.Window()
+		vwap.Window = &window
+	}
+
+	vwap.Bands = p.bands(vwap.VWAP)
+
+	// the first indicator is VWAP itself
+	if len(p.indicators) > 1 {
+		vwap.Indicators = make(map[string]decimal.Decimal, len(p.indicators)-1)
+		for _, ind := range p.indicators[1:] {
+			vwap.Indicators[ind.Name] = ind.Value()
 		}
 	}
+
+	return vwap
+}
+
+// warmedUp returns whether the result may be published: once the window is full or holds the minimum volume,
+// whichever is configured first. Results are always published when no warm-up is configured.
+func (p Processor) warmedUp(vwap model.VWAP) bool {
+	if !p.vwapCfg.WarmUpEnabled() || vwap.Window == nil {
+		return true
+	}
+
+	if p.vwapCfg.RequireFullWindow && vwap.Window.Full {
+		return true
+	}
+
+	return p.vwapCfg.MinWindowVolume.IsPositive() && vwap.Window.Volume.GreaterThanOrEqual(p.vwapCfg.MinWindowVolume)
+}
+
+func (p Processor) bands(vwap decimal.Decimal) []model.Band {
+	if len(p.vwapCfg.BandMultipliers) == 0 {
+		return nil
+	}
+
+	r, ok := p.calc.(StdDevReporter)
+	if !ok {
+		return nil
+	}
+
+	stdDev := r.StdDev()
+	bands := make([]model.Band, len(p.vwapCfg.BandMultipliers))
+	for i, k := range p.vwapCfg.BandMultipliers {
+		width := stdDev.Mul(k)
+		bands[i] = model.Band{
+			Multiplier: k,
+			Upper:      vwap.Add(width),
+			Lower:      vwap.Sub(width),
+		}
+	}
+
+	return bands
 }
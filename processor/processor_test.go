@@ -1,11 +1,16 @@
 package processor
 
 import (
+	"errors"
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/aprln/vwap-engine/config"
 	"github.com/aprln/vwap-engine/model"
+	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestProcessor_GoProcess(t *testing.T) {
@@ -21,3 +26,404 @@ func TestProcessor_GoProcess(t *testing.T) {
 
 	assert.Equal(t, "1.1", vwap.VWAP.String())
 }
+
+func TestSetUp(t *testing.T) {
+	tests := []struct {
+		name    string
+		pairCfg config.PairVWAP
+		wantErr bool
+	}{
+		{
+			name:    "window",
+			pairCfg: config.PairVWAP{Calculator: config.CalculatorNameWindow},
+		},
+		{
+			name:    "anchored",
+			pairCfg: config.PairVWAP{Calculator: config.CalculatorNameAnchored, Anchor: "midnight"},
+		},
+		{
+			name:    "anchored with invalid anchor",
+			pairCfg: config.PairVWAP{Calculator: config.CalculatorNameAnchored, Anchor: "banana"},
+			wantErr: true,
+		},
+		{
+			name:    "volume",
+			pairCfg: config.PairVWAP{Calculator: config.CalculatorNameVolume, WindowVolume: decimal.NewFromInt(50)},
+		},
+		{
+			name:    "volume without window volume",
+			pairCfg: config.PairVWAP{Calculator: config.CalculatorNameVolume},
+			wantErr: true,
+		},
+		{
+			name:    "ewma with half-life in trades",
+			pairCfg: config.PairVWAP{Calculator: config.CalculatorNameEWMA, EWMAHalfLife: "100"},
+		},
+		{
+			name:    "ewma with half-life in duration",
+			pairCfg: config.PairVWAP{Calculator: config.CalculatorNameEWMA, EWMAHalfLife: "30s"},
+		},
+		{
+			name:    "ewma with invalid half-life",
+			pairCfg: config.PairVWAP{Calculator: config.CalculatorNameEWMA, EWMAHalfLife: "-1"},
+			wantErr: true,
+		},
+		{
+			name:    "unsupported",
+			pairCfg: config.PairVWAP{Calculator: "banana"},
+			wantErr: true,
+		},
+		{
+			name:    "throttled",
+			pairCfg: config.PairVWAP{Calculator: config.CalculatorNameWindow, Emit: "interval@250ms"},
+		},
+		{
+			name:    "unsupported emit policy",
+			pairCfg: config.PairVWAP{Calculator: config.CalculatorNameWindow, Emit: "banana"},
+			wantErr: true,
+		},
+		{
+			name:    "warm-up",
+			pairCfg: config.PairVWAP{Calculator: config.CalculatorNameWindow, RequireFullWindow: true},
+		},
+		{
+			name:    "warm-up without window",
+			pairCfg: config.PairVWAP{Calculator: config.CalculatorNameEWMA, EWMAHalfLife: "100", RequireFullWindow: true},
+			wantErr: true,
+		},
+		{
+			name: "indicators without window",
+			pairCfg: config.PairVWAP{
+				Calculator: config.CalculatorNameEWMA, EWMAHalfLife: "100", Indicators: []string{IndicatorNameTWAP},
+			},
+			wantErr: true,
+		},
+		{
+			name:    "unsupported indicator",
+			pairCfg: config.PairVWAP{Calculator: config.CalculatorNameWindow, Indicators: []string{"banana"}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				_, err := SetUp(config.VWAP{WindowSize: 3, PairVWAP: tt.pairCfg}, config.Validation{}, nil)
+
+				if tt.wantErr {
+					require.Error(t, err)
+
+					return
+				}
+
+				require.NoError(t, err)
+			},
+		)
+	}
+}
+
+func TestProcessor_GoProcess_session(t *testing.T) {
+	anchor, err := ParseAnchor("midnight")
+	require.NoError(t, err)
+
+	in := make(chan model.Trade, 1)
+	in <- model.Trade{
+		TradingPair: "BTC-USD",
+		Price:       decimal.NewFromInt(2),
+		Size:        decimal.NewFromInt(1),
+		Time:        time.Date(2022, 11, 2, 14, 27, 48, 0, time.UTC),
+	}
+
+	proc := New(config.VWAP{}, NewAnchoredVWAPCalc(anchor))
+	out := proc.GoProcess(in)
+
+	vwap := <-out
+
+	close(in)
+
+	assert.Equal(t, "2", vwap.VWAP.String())
+	require.NotNil(t, vwap.SessionStart)
+	assert.Equal(t, time.Date(2022, 11, 2, 0, 0, 0, 0, time.UTC), *vwap.SessionStart)
+	require.NoError(t, proc.ResetSession())
+	require.Error(t, New(config.VWAP{}, NewMockVWAPCalc()).ResetSession())
+}
+
+func TestProcessor_GoProcess_bands(t *testing.T) {
+	calc, err := NewVWAPCalc(2)
+	require.NoError(t, err)
+
+	in := make(chan model.Trade, 2)
+	in <- model.Trade{TradingPair: "BTC-USD", Price: decimal.NewFromInt(1), Size: decimal.NewFromInt(1)}
+	in <- model.Trade{TradingPair: "BTC-USD", Price: decimal.NewFromInt(3), Size: decimal.NewFromInt(1)}
+
+	vwapCfg := config.VWAP{BandMultipliers: []decimal.Decimal{decimal.NewFromInt(1), decimal.NewFromInt(2)}}
+	out := New(vwapCfg, calc).GoProcess(in)
+
+	<-out
+	vwap := <-out
+
+	close(in)
+
+	require.Len(t, vwap.Bands, 2)
+	assert.Equal(t, "1", vwap.Bands[0].Multiplier.String())
+	assert.Equal(t, "3", vwap.Bands[0].Upper.String())
+	assert.Equal(t, "1", vwap.Bands[0].Lower.String())
+	assert.Equal(t, "2", vwap.Bands[1].Multiplier.String())
+	assert.Equal(t, "4", vwap.Bands[1].Upper.String())
+	assert.Equal(t, "0", vwap.Bands[1].Lower.String())
+}
+
+func TestProcessor_GoProcess_indicators(t *testing.T) {
+	vwapCfg := config.VWAP{
+		WindowSize: 2,
+		PairVWAP: config.PairVWAP{
+			Calculator: config.CalculatorNameWindow,
+			Indicators: []string{IndicatorNameTradeCount, IndicatorNameNotional},
+		},
+	}
+	proc, err := SetUp(vwapCfg, config.Validation{}, nil)
+	require.NoError(t, err)
+
+	in := make(chan model.Trade, 2)
+	in <- model.Trade{TradingPair: "BTC-USD", Price: decimal.NewFromInt(1), Size: decimal.NewFromInt(1)}
+	in <- model.Trade{TradingPair: "BTC-USD", Price: decimal.NewFromInt(3), Size: decimal.NewFromInt(3)}
+
+	out := proc.GoProcess(in)
+
+	<-out
+	vwap := <-out
+
+	close(in)
+
+	assert.Equal(t, "2.5", vwap.VWAP.String())
+	require.Len(t, vwap.Indicators, 2)
+	assert.Equal(t, "2", vwap.Indicators[IndicatorNameTradeCount].String())
+	assert.Equal(t, "10", vwap.Indicators[IndicatorNameNotional].String())
+}
+
+// rejectingIndicator rejects the trades over a maximum size.
+type rejectingIndicator struct {
+	lastPriceIndicator
+	maxSize decimal.Decimal
+}
+
+func (i *rejectingIndicator) Check(trade model.Trade) error {
+	if trade.Size.GreaterThan(i.maxSize) {
+		return fmt.Errorf("size %s over %s", trade.Size, i.maxSize)
+	}
+
+	return nil
+}
+
+func (i *rejectingIndicator) Update(trade model.Trade) error {
+	if err := i.Check(trade); err != nil {
+		return err
+	}
+
+	return i.lastPriceIndicator.Update(trade)
+}
+
+func TestProcessor_GoProcess_indicatorRejects(t *testing.T) {
+	calc, err := NewVWAPCalc(2)
+	require.NoError(t, err)
+	lastPrice := &lastPriceIndicator{}
+	proc := New(
+		config.VWAP{},
+		calc,
+		NamedIndicator{Name: "last_price", Indicator: lastPrice},
+		NamedIndicator{Name: "rejecting", Indicator: &rejectingIndicator{maxSize: decimal.NewFromInt(5)}},
+	)
+
+	in := make(chan model.Trade, 3)
+	in <- model.Trade{TradingPair: "BTC-USD", Price: decimal.NewFromInt(1), Size: decimal.NewFromInt(1)}
+	in <- model.Trade{TradingPair: "BTC-USD", Price: decimal.NewFromInt(3), Size: decimal.NewFromInt(1)}
+	in <- model.Trade{TradingPair: "BTC-USD", Price: decimal.NewFromInt(9), Size: decimal.NewFromInt(9)}
+	close(in)
+
+	var got []model.VWAP
+	for vwap := range proc.GoProcess(in) {
+		got = append(got, vwap)
+	}
+
+	require.Len(t, got, 2, "the rejected trade is not published")
+	assert.Equal(t, "2", calc.VWAP().String(), "the rejected trade is not added to the calculator")
+	assert.Equal(t, "3", lastPrice.Value().String(), "the rejected trade does not update the other indicators")
+}
+
+type verifyingMockVWAPCalc struct {
+	MockVWAPCalc
+	verifications *int
+	err           error
+}
+
+func (m verifyingMockVWAPCalc) Verify() (Drift, error) {
+	*m.verifications++
+	if m.err != nil {
+		return Drift{}, m.err
+	}
+
+	return Drift{TotalSize: decimal.NewFromInt(1)}, nil
+}
+
+func TestProcessor_GoProcess_verify(t *testing.T) {
+	verifications := 0
+	calc := verifyingMockVWAPCalc{verifications: &verifications}
+	proc := New(config.VWAP{VerifyEvery: 2}, calc)
+
+	in := make(chan model.Trade)
+	out := proc.GoProcess(in)
+
+	for i := 0; i < 5; i++ {
+		in <- model.Trade{TradingPair: "BTC-USD"}
+		<-out
+	}
+	assert.Equal(t, 2, verifications)
+
+	require.NoError(t, proc.RequestVerify())
+	in <- model.Trade{TradingPair: "BTC-USD"}
+	<-out
+	assert.Equal(t, 3, verifications, "a requested verification also counts as the periodic one")
+	assert.Equal(t, uint64(3), proc.VerifyStats().Resyncs.Load(), "every drift is counted")
+
+	close(in)
+
+	require.Error(t, New(config.VWAP{}, NewMockVWAPCalc()).RequestVerify())
+}
+
+func TestProcessor_GoProcess_verifyFails(t *testing.T) {
+	verifications := 0
+	calc := verifyingMockVWAPCalc{verifications: &verifications, err: errors.New("integrity")}
+	proc := New(config.VWAP{VerifyEvery: 1}, calc)
+
+	in := make(chan model.Trade, 2)
+	in <- model.Trade{TradingPair: "BTC-USD"}
+	in <- model.Trade{TradingPair: "BTC-USD"}
+	close(in)
+
+	var got []model.VWAP
+	for vwap := range proc.GoProcess(in) {
+		got = append(got, vwap)
+	}
+
+	assert.Len(t, got, 2, "the trading pair keeps running after a failed verification")
+	assert.Equal(t, uint64(2), proc.VerifyStats().Failures.Load())
+	assert.Zero(t, proc.VerifyStats().Resyncs.Load())
+}
+
+type mockQuarantine struct {
+	rejected chan model.RejectedTrade
+}
+
+func (m mockQuarantine) Quarantine(rejected model.RejectedTrade) {
+	m.rejected <- rejected
+}
+
+func TestProcessor_GoProcess_quarantine(t *testing.T) {
+	calc, err := NewVWAPCalc(2)
+	require.NoError(t, err)
+
+	quarantine := mockQuarantine{rejected: make(chan model.RejectedTrade, 1)}
+	proc := New(config.VWAP{}, calc).
+		WithValidation(NewValidator(config.Validation{RequirePositive: true}), quarantine)
+
+	in := make(chan model.Trade, 3)
+	in <- model.Trade{TradingPair: "BTC-USD", Price: decimal.NewFromInt(1), Size: decimal.NewFromInt(1)}
+	in <- model.Trade{TradingPair: "BTC-USD", Price: decimal.NewFromInt(-3), Size: decimal.NewFromInt(1)}
+	in <- model.Trade{TradingPair: "BTC-USD", Price: decimal.NewFromInt(3), Size: decimal.NewFromInt(1)}
+
+	out := proc.GoProcess(in)
+
+	assert.Equal(t, "1", (<-out).VWAP.String())
+	assert.Equal(t, "2", (<-out).VWAP.String(), "the rejected trade does not reach the calculator")
+
+	close(in)
+
+	rejected := <-quarantine.rejected
+	assert.Equal(t, "BTC-USD", rejected.TradingPair)
+	assert.Equal(t, "-3", rejected.Price.String())
+	assert.Equal(t, "price -3 is not positive", rejected.Reason)
+	assert.False(t, rejected.RejectedAt.IsZero())
+}
+
+func TestProcessor_GoProcess_warmUp(t *testing.T) {
+	tests := []struct {
+		name      string
+		pairCfg   config.PairVWAP
+		wantVWAPs []string
+	}{
+		{
+			name:      "no warm-up",
+			wantVWAPs: []string{"1", "1.5", "2", "3"},
+		},
+		{
+			name:      "full window",
+			pairCfg:   config.PairVWAP{RequireFullWindow: true},
+			wantVWAPs: []string{"2", "3"},
+		},
+		{
+			name:      "min window volume",
+			pairCfg:   config.PairVWAP{MinWindowVolume: decimal.NewFromInt(2)},
+			wantVWAPs: []string{"1.5", "2", "3"},
+		},
+		{
+			name:      "full window or min window volume",
+			pairCfg:   config.PairVWAP{RequireFullWindow: true, MinWindowVolume: decimal.NewFromInt(2)},
+			wantVWAPs: []string{"1.5", "2", "3"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				calc, err := NewVWAPCalc(3)
+				require.NoError(t, err)
+
+				in := make(chan model.Trade, 4)
+				for i := int64(1); i <= 4; i++ {
+					in <- model.Trade{TradingPair: "BTC-USD", Price: decimal.NewFromInt(i), Size: decimal.NewFromInt(1)}
+				}
+				close(in)
+
+				var gotVWAPs []string
+				for vwap := range New(config.VWAP{PairVWAP: tt.pairCfg}, calc).GoProcess(in) {
+					require.NotNil(t, vwap.Window)
+					gotVWAPs = append(gotVWAPs, vwap.VWAP.String())
+				}
+
+				assert.Equal(t, tt.wantVWAPs, gotVWAPs)
+			},
+		)
+	}
+}
+
+func TestProcessor_GoProcessTapped(t *testing.T) {
+	calc, err := NewVWAPCalc(3)
+	require.NoError(t, err)
+	policy, err := ParseEmitPolicy("trades@2")
+	require.NoError(t, err)
+
+	in := make(chan model.Trade, 4)
+	for i := int64(1); i <= 4; i++ {
+		in <- model.Trade{TradingPair: "BTC-USD", Price: decimal.NewFromInt(i), Size: decimal.NewFromInt(1)}
+	}
+	close(in)
+
+	out, tap := New(config.VWAP{}, calc).WithEmitPolicy(policy).GoProcessTapped(in)
+
+	tapped := make(chan []string)
+	go func() {
+		var vwaps []string
+		for vwap := range tap {
+			vwaps = append(vwaps, vwap.VWAP.String())
+		}
+		tapped <- vwaps
+	}()
+
+	var gotVWAPs []string
+	for vwap := range out {
+		gotVWAPs = append(gotVWAPs, vwap.VWAP.String())
+	}
+
+	assert.Equal(t, []string{"1.5", "3"}, gotVWAPs)
+	assert.Equal(t, []string{"1", "1.5", "2", "3"}, <-tapped, "the tap sees the results held back by the policy")
+}
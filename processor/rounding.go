@@ -0,0 +1,132 @@
+package processor
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/aprln/vwap-engine/config"
+	"github.com/aprln/vwap-engine/model"
+	"github.com/shopspring/decimal"
+)
+
+type RoundingMode string
+
+const (
+	RoundingModeHalfEven RoundingMode = "half_even"
+	RoundingModeHalfUp   RoundingMode = "half_up"
+	RoundingModeUp       RoundingMode = "up"
+	RoundingModeDown     RoundingMode = "down"
+	RoundingModeCeil     RoundingMode = "ceil"
+	RoundingModeFloor    RoundingMode = "floor"
+)
+
+// outputPrecisionIncrement derives the output precision from the price increment.
+const outputPrecisionIncrement = "increment"
+
+// priceIndicators are the built-in indicators whose value is a price, rounded like VWAP.
+var priceIndicators = map[string]bool{
+	IndicatorNameTWAP:     true,
+	IndicatorNameMedian:   true,
+	IndicatorNameVWMedian: true,
+}
+
+// Rounder rounds the published prices to the output precision of a trading pair.
+// It is applied when the results are encoded, so the later stages of the pipeline see the full precision.
+// The zero value does not round.
+type Rounder struct {
+	enabled bool
+	places  int32
+	mode    RoundingMode
+}
+
+// SetUpRounder parses the output precision and rounding mode of the trading pair's config.
+func SetUpRounder(vwapCfg config.VWAP) (Rounder, error) {
+	return ParseRounder(vwapCfg.OutputPrecision, RoundingMode(vwapCfg.Rounding), vwapCfg.PriceIncrement)
+}
+
+// ParseRounder parses the output precision, which is empty (no rounding), a number of decimal places
+// or "increment" (the decimal places of the price increment), and the rounding mode.
+// An empty rounding mode is the same as "half_even".
+func ParseRounder(precision string, mode RoundingMode, priceIncrement decimal.Decimal) (Rounder, error) {
+	switch mode {
+	case "", RoundingModeHalfEven, RoundingModeHalfUp, RoundingModeUp, RoundingModeDown, RoundingModeCeil,
+		RoundingModeFloor:
+	default:
+		return Rounder{}, fmt.Errorf(`rounding mode "%s" is unsupported`, mode)
+	}
+
+	switch precision {
+	case "":
+		return Rounder{}, nil
+
+	case outputPrecisionIncrement:
+		places, err := incrementScale(priceIncrement)
+		if err != nil {
+			return Rounder{}, fmt.Errorf("invalid price increment for the output precision: %v", err)
+		}
+
+		return Rounder{enabled: true, places: places, mode: mode}, nil
+
+	default:
+		places, err := strconv.ParseInt(precision, 10, 32)
+		if err != nil || places < 0 {
+			return Rounder{}, fmt.Errorf(`invalid output precision "%s"`, precision)
+		}
+
+		return Rounder{enabled: true, places: int32(places), mode: mode}, nil
+	}
+}
+
+// Round returns a copy of the result with VWAP, the bands and the price indicators rounded.
+// The result's bands and indicators are shared with the other stages, so they are copied rather than rounded
+// in place.
+func (r Rounder) Round(vwap model.VWAP) model.VWAP {
+	if !r.enabled {
+		return vwap
+	}
+
+	vwap.VWAP = r.round(vwap.VWAP)
+
+	if vwap.Bands != nil {
+		bands := make([]model.Band, len(vwap.Bands))
+		for i, b := range vwap.Bands {
+			bands[i] = model.Band{Multiplier: b.Multiplier, Upper: r.round(b.Upper), Lower: r.round(b.Lower)}
+		}
+		vwap.Bands = bands
+	}
+
+	if vwap.Indicators != nil {
+		indicators := make(map[string]decimal.Decimal, len(vwap.Indicators))
+		for name, v := range vwap.Indicators {
+			if priceIndicators[name] {
+				v = r.round(v)
+			}
+			indicators[name] = v
+		}
+		vwap.Indicators = indicators
+	}
+
+	return vwap
+}
+
+func (r Rounder) round(d decimal.Decimal) decimal.Decimal {
+	if !r.enabled {
+		return d
+	}
+
+	switch r.mode {
+	case RoundingModeHalfUp:
+		// decimal.Round rounds half away from zero
+		return d.Round(r.places)
+	case RoundingModeUp:
+		return d.RoundUp(r.places)
+	case RoundingModeDown:
+		return d.RoundDown(r.places)
+	case RoundingModeCeil:
+		return d.RoundCeil(r.places)
+	case RoundingModeFloor:
+		return d.RoundFloor(r.places)
+	default:
+		return d.RoundBank(r.places)
+	}
+}
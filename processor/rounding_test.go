@@ -0,0 +1,122 @@
+package processor
+
+import (
+	"testing"
+
+	"github.com/aprln/vwap-engine/model"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRounder(t *testing.T) {
+	tests := []struct {
+		name           string
+		precision      string
+		mode           RoundingMode
+		priceIncrement string
+		want           Rounder
+		wantErr        bool
+	}{
+		{name: "no rounding", want: Rounder{}},
+		{name: "explicit", precision: "2", mode: RoundingModeFloor, want: Rounder{true, 2, RoundingModeFloor}},
+		{name: "zero places", precision: "0", mode: RoundingModeUp, want: Rounder{true, 0, RoundingModeUp}},
+		{
+			name:           "increment",
+			precision:      "increment",
+			mode:           RoundingModeHalfEven,
+			priceIncrement: "0.01",
+			want:           Rounder{true, 2, RoundingModeHalfEven},
+		},
+		{name: "increment not set", precision: "increment", wantErr: true},
+		{name: "negative", precision: "-1", wantErr: true},
+		{name: "invalid", precision: "banana", wantErr: true},
+		{name: "unsupported mode", precision: "2", mode: "banana", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				priceIncrement := decimal.Zero
+				if tt.priceIncrement != "" {
+					priceIncrement = decimal.RequireFromString(tt.priceIncrement)
+				}
+
+				got, err := ParseRounder(tt.precision, tt.mode, priceIncrement)
+
+				if tt.wantErr {
+					require.Error(t, err)
+
+					return
+				}
+
+				require.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			},
+		)
+	}
+}
+
+func TestRounder_round(t *testing.T) {
+	tests := []struct {
+		mode RoundingMode
+		d    string
+		want string
+	}{
+		{RoundingModeHalfEven, "19427.7342170096256965", "19427.73"},
+		{RoundingModeHalfEven, "1.125", "1.12"},
+		{RoundingModeHalfEven, "1.135", "1.14"},
+		{RoundingModeHalfUp, "1.125", "1.13"},
+		{RoundingModeHalfUp, "-1.125", "-1.13"},
+		{RoundingModeUp, "1.121", "1.13"},
+		{RoundingModeUp, "-1.121", "-1.13"},
+		{RoundingModeDown, "1.129", "1.12"},
+		{RoundingModeDown, "-1.129", "-1.12"},
+		{RoundingModeCeil, "-1.129", "-1.12"},
+		{RoundingModeFloor, "-1.121", "-1.13"},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			string(tt.mode)+" "+tt.d, func(t *testing.T) {
+				r, err := ParseRounder("2", tt.mode, decimal.Zero)
+				require.NoError(t, err)
+
+				assert.Equal(t, tt.want, r.round(decimal.RequireFromString(tt.d)).String())
+			},
+		)
+	}
+
+	assert.Equal(t, "1.125", Rounder{}.round(decimal.RequireFromString("1.125")).String(), "no rounding")
+}
+
+func TestRounder_Round(t *testing.T) {
+	r, err := ParseRounder("2", RoundingModeHalfEven, decimal.Zero)
+	require.NoError(t, err)
+
+	vwap := model.VWAP{
+		VWAP: decimal.RequireFromString("1.6666666666666667"),
+		Bands: []model.Band{
+			{
+				Multiplier: decimal.NewFromInt(1),
+				Upper:      decimal.RequireFromString("2.1380711874576983"),
+				Lower:      decimal.RequireFromString("1.1952621458756351"),
+			},
+		},
+		Indicators: map[string]decimal.Decimal{
+			IndicatorNameTWAP:     decimal.RequireFromString("1.555"),
+			IndicatorNameNotional: decimal.RequireFromString("5.555"),
+		},
+	}
+
+	got := r.Round(vwap)
+	assert.Equal(t, "1.67", got.VWAP.String())
+	require.Len(t, got.Bands, 1)
+	assert.Equal(t, "2.14", got.Bands[0].Upper.String())
+	assert.Equal(t, "1.2", got.Bands[0].Lower.String())
+	assert.Equal(t, "1.56", got.Indicators[IndicatorNameTWAP].String(), "price indicators are rounded")
+	assert.Equal(t, "5.555", got.Indicators[IndicatorNameNotional].String(), "other indicators are not")
+
+	assert.Equal(t, "2.1380711874576983", vwap.Bands[0].Upper.String(), "the result is not rounded in place")
+	assert.Equal(t, "1.555", vwap.Indicators[IndicatorNameTWAP].String())
+}
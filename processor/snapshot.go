@@ -0,0 +1,193 @@
+package processor
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/aprln/vwap-engine/config"
+	"github.com/aprln/vwap-engine/model"
+	"github.com/shopspring/decimal"
+)
+
+// Snapshot is the processor state of a trading pair, persisted so a restart does not start from an empty window.
+type Snapshot struct {
+	TradingPair string                `json:"trading_pair"`
+	Calculator  config.CalculatorName `json:"calculator"`
+	TakenAt     time.Time             `json:"taken_at"`
+	// LastTradeID is the ID of the last trade processed before the snapshot was taken.
+	LastTradeID int64           `json:"last_trade_id"`
+	State       json.RawMessage `json:"state"`
+	// Trades are the trades of the window the extra indicators are computed over. They are replayed into
+	// the indicators on restore, as every indicator is a function of the window.
+	Trades []SnapshotTrade `json:"trades,omitempty"`
+	// Emitter is the state of the emit policy, so a restart does not publish results the policy holds back.
+	Emitter json.RawMessage `json:"emitter,omitempty"`
+}
+
+// SnapshotTrade is a trade of the window of a Snapshot.
+type SnapshotTrade struct {
+	Price decimal.Decimal `json:"price"`
+	Size  decimal.Decimal `json:"size"`
+	Time  time.Time       `json:"time"`
+}
+
+// SnapshotStore persists the snapshots of the processors.
+type SnapshotStore interface {
+	Save(snapshot Snapshot) error
+	// Load returns false if there is no snapshot for the trading pair.
+	Load(tradingPair string) (Snapshot, bool, error)
+}
+
+// Snapshotter is implemented by calculators whose state can be snapshotted and restored.
+type Snapshotter interface {
+	MarshalState() (json.RawMessage, error)
+	RestoreState(state json.RawMessage) error
+}
+
+// WithSnapshots returns a copy of the processor that snapshots its calculator, the window of its extra indicators
+// and its emitter periodically and when its input is closed. The latest snapshot of the trading pair is restored
+// first if it is younger than the max age. Restore failures are logged and the processor starts from scratch.
+// Trades up to the restored snapshot's last trade ID are skipped, so replayed trades are not counted twice.
+func (p Processor) WithSnapshots(tradingPair string, store SnapshotStore, snapshotCfg config.Snapshot) Processor {
+	p.tradingPair = tradingPair
+	p.snapshotStore = store
+	p.snapshotCfg = snapshotCfg
+
+	if _, ok := p.calc.(Snapshotter); !ok {
+		log.Printf("%s calculator %s does not support snapshots", tradingPair, p.vwapCfg.Calculator)
+
+		return p
+	}
+
+	// the first indicator is VWAP itself
+	if len(p.indicators) > 1 {
+		w, err := newTradeWindow(p.vwapCfg.WindowSize)
+		if err != nil {
+			log.Printf("%s snapshots are disabled, the indicators cannot be snapshotted: %v", tradingPair, err)
+
+			p.snapshotStore = nil
+
+			return p
+		}
+		p.indicatorTrades = w
+	}
+
+	p.restore()
+
+	return p
+}
+
+// restore restores the calculator, the indicators and the emitter from the latest snapshot.
+func (p *Processor) restore() {
+	snapshot, ok, err := p.snapshotStore.Load(p.tradingPair)
+	if err != nil {
+		log.Printf("failed to load %s snapshot: %v", p.tradingPair, err)
+
+		return
+	}
+
+	if !ok {
+		return
+	}
+
+	if age := time.Since(snapshot.TakenAt); age > p.snapshotCfg.MaxAge {
+		log.Printf("%s snapshot is not restored, its age %s is over %s", p.tradingPair, age, p.snapshotCfg.MaxAge)
+
+		return
+	}
+
+	if snapshot.Calculator != p.vwapCfg.Calculator {
+		log.Printf(
+			"%s snapshot is not restored, it was taken by calculator %s instead of %s",
+			p.tradingPair, snapshot.Calculator, p.vwapCfg.Calculator,
+		)
+
+		return
+	}
+
+	if p.indicatorTrades != nil && len(snapshot.Trades) == 0 && snapshot.LastTradeID != 0 {
+		log.Printf("%s snapshot is not restored, it has no trades to replay into the indicators", p.tradingPair)
+
+		return
+	}
+
+	if err := p.calc.(Snapshotter).RestoreState(snapshot.State); err != nil {
+		log.Printf("failed to restore %s snapshot: %v", p.tradingPair, err)
+
+		return
+	}
+
+	p.replayIndicators(snapshot.Trades)
+	p.restoredEmitter = snapshot.Emitter
+	p.restoredTradeID = snapshot.LastTradeID
+
+	log.Printf("%s snapshot taken at %s restored", p.tradingPair, snapshot.TakenAt.Format(time.RFC3339))
+}
+
+// replayIndicators updates the extra indicators with the trades of the snapshot, from the oldest to the newest.
+func (p Processor) replayIndicators(trades []SnapshotTrade) {
+	if p.indicatorTrades == nil {
+		return
+	}
+
+	for _, t := range trades {
+		trade := model.Trade{TradingPair: p.tradingPair, Price: t.Price, Size: t.Size, Time: t.Time}
+		for _, ind := range p.indicators[1:] {
+			if err := ind.Update(trade); err != nil {
+				log.Printf("failed to replay a %s snapshot trade into indicator %s: %v", p.tradingPair, ind.Name, err)
+			}
+		}
+		p.indicatorTrades.push(trade)
+	}
+}
+
+// snapshot saves the processor state if snapshots are enabled and supported by the calculator.
+func (p Processor) snapshot(lastTradeID int64, em *emitter) {
+	if p.snapshotStore == nil {
+		return
+	}
+
+	s, ok := p.calc.(Snapshotter)
+	if !ok {
+		return
+	}
+
+	state, err := s.MarshalState()
+	if err != nil {
+		log.Printf("failed to snapshot %s calculator: %v", p.tradingPair, err)
+
+		return
+	}
+
+	emitterState, err := em.marshalState()
+	if err != nil {
+		log.Printf("failed to snapshot %s emitter: %v", p.tradingPair, err)
+
+		return
+	}
+
+	var trades []SnapshotTrade
+	if p.indicatorTrades != nil {
+		trades = make([]SnapshotTrade, p.indicatorTrades.len())
+		for i := range trades {
+			t := p.indicatorTrades.at(i)
+			trades[i] = SnapshotTrade{Price: t.Price, Size: t.Size, Time: t.Time}
+		}
+	}
+
+	err = p.snapshotStore.Save(
+		Snapshot{
+			TradingPair: p.tradingPair,
+			Calculator:  p.vwapCfg.Calculator,
+			TakenAt:     time.Now().UTC(),
+			LastTradeID: lastTradeID,
+			State:       state,
+			Trades:      trades,
+			Emitter:     emitterState,
+		},
+	)
+	if err != nil {
+		log.Printf("failed to save %s snapshot: %v", p.tradingPair, err)
+	}
+}
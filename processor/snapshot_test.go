@@ -0,0 +1,171 @@
+package processor
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/aprln/vwap-engine/config"
+	"github.com/aprln/vwap-engine/model"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockSnapshotStore struct {
+	snapshots map[string]Snapshot
+}
+
+func (m mockSnapshotStore) Save(snapshot Snapshot) error {
+	m.snapshots[snapshot.TradingPair] = snapshot
+
+	return nil
+}
+
+func (m mockSnapshotStore) Load(tradingPair string) (Snapshot, bool, error) {
+	snapshot, ok := m.snapshots[tradingPair]
+
+	return snapshot, ok, nil
+}
+
+func TestProcessor_WithSnapshots(t *testing.T) {
+	vwapCfg := config.VWAP{WindowSize: 2, PairVWAP: config.PairVWAP{Calculator: config.CalculatorNameWindow}}
+	snapshotCfg := config.Snapshot{Dir: "unused", MaxAge: time.Minute}
+	store := mockSnapshotStore{snapshots: map[string]Snapshot{}}
+
+	// the first run starts from an empty window and snapshots it on close
+	calc, err := NewVWAPCalc(2)
+	require.NoError(t, err)
+	proc := New(vwapCfg, calc).WithSnapshots("BTC-USD", store, snapshotCfg)
+
+	in := make(chan model.Trade, 2)
+	in <- model.Trade{ID: 1, TradingPair: "BTC-USD", Price: decimal.NewFromInt(1), Size: decimal.NewFromInt(1)}
+	in <- model.Trade{ID: 2, TradingPair: "BTC-USD", Price: decimal.NewFromInt(3), Size: decimal.NewFromInt(1)}
+	close(in)
+	for range proc.GoProcess(in) {
+	}
+
+	snapshot, ok := store.snapshots["BTC-USD"]
+	require.True(t, ok)
+	assert.Equal(t, int64(2), snapshot.LastTradeID)
+	assert.Equal(t, config.CalculatorNameWindow, snapshot.Calculator)
+
+	// the second run restores the window and skips the trades already processed
+	calc, err = NewVWAPCalc(2)
+	require.NoError(t, err)
+	proc = New(vwapCfg, calc).WithSnapshots("BTC-USD", store, snapshotCfg)
+	assert.Equal(t, "2", calc.VWAP().String())
+
+	in = make(chan model.Trade, 2)
+	in <- model.Trade{ID: 2, TradingPair: "BTC-USD", Price: decimal.NewFromInt(3), Size: decimal.NewFromInt(1)}
+	in <- model.Trade{ID: 3, TradingPair: "BTC-USD", Price: decimal.NewFromInt(5), Size: decimal.NewFromInt(1)}
+	close(in)
+
+	out := proc.GoProcess(in)
+	assert.Equal(t, "4", (<-out).VWAP.String())
+	_, more := <-out
+	assert.False(t, more)
+	assert.Equal(t, int64(3), store.snapshots["BTC-USD"].LastTradeID)
+}
+
+func TestProcessor_WithSnapshots_indicatorsAndEmitter(t *testing.T) {
+	vwapCfg := config.VWAP{WindowSize: 2, PairVWAP: config.PairVWAP{Calculator: config.CalculatorNameWindow}}
+	snapshotCfg := config.Snapshot{Dir: "unused", MaxAge: time.Minute}
+	store := mockSnapshotStore{snapshots: map[string]Snapshot{}}
+	newProc := func() Processor {
+		calc, err := NewVWAPCalc(2)
+		require.NoError(t, err)
+		median, err := newMedianIndicator(vwapCfg)
+		require.NoError(t, err)
+		policy, err := ParseEmitPolicy("trades@2")
+		require.NoError(t, err)
+
+		return New(vwapCfg, calc, NamedIndicator{Name: IndicatorNameMedian, Indicator: median}).
+			WithEmitPolicy(policy).
+			WithSnapshots("BTC-USD", store, snapshotCfg)
+	}
+
+	in := make(chan model.Trade, 3)
+	for i := int64(1); i <= 3; i++ {
+		in <- model.Trade{ID: i, TradingPair: "BTC-USD", Price: decimal.NewFromInt(i), Size: decimal.NewFromInt(1)}
+	}
+	close(in)
+	var got []model.VWAP
+	for vwap := range newProc().GoProcess(in) {
+		got = append(got, vwap)
+	}
+	require.Len(t, got, 1, "every second result is published")
+	require.Len(t, store.snapshots["BTC-USD"].Trades, 2, "the window of the indicators is snapshotted")
+
+	in = make(chan model.Trade, 1)
+	in <- model.Trade{ID: 4, TradingPair: "BTC-USD", Price: decimal.NewFromInt(6), Size: decimal.NewFromInt(1)}
+	close(in)
+	got = nil
+	for vwap := range newProc().GoProcess(in) {
+		got = append(got, vwap)
+	}
+	require.Len(t, got, 1, "the emitter carries on counting the trades from the snapshot")
+	assert.Equal(t, "4.5", got[0].VWAP.String())
+	assert.Equal(t, "4.5", got[0].Indicators[IndicatorNameMedian].String(), "the indicators are restored too")
+}
+
+func TestProcessor_WithSnapshots_notRestored(t *testing.T) {
+	state, err := json.Marshal(
+		vwapCalcState{
+			WindowSize: 2,
+			DataPoints: []VWAPCalcDataPoint{
+				{Price: decimal.NewFromInt(1), Size: decimal.NewFromInt(1)},
+				{Price: decimal.Zero, Size: decimal.Zero},
+			},
+			TradeTimes:         make([]time.Time, 2),
+			DataPointCount:     1,
+			OldestDataPointIdx: 1,
+			TotalValue:         decimal.NewFromInt(1),
+			TotalSquaredValue:  decimal.NewFromInt(1),
+			TotalSize:          decimal.NewFromInt(1),
+		},
+	)
+	require.NoError(t, err)
+	valid := Snapshot{
+		TradingPair: "BTC-USD",
+		Calculator:  config.CalculatorNameWindow,
+		TakenAt:     time.Now(),
+		LastTradeID: 1,
+		State:       state,
+	}
+
+	tooOld := valid
+	tooOld.TakenAt = time.Now().Add(-2 * time.Minute)
+	otherCalculator := valid
+	otherCalculator.Calculator = config.CalculatorNameFixed
+	invalidState := valid
+	invalidState.State = json.RawMessage(`{"window_size":3}`)
+
+	tests := []struct {
+		name     string
+		snapshot Snapshot
+		wantVWAP string
+	}{
+		{name: "valid", snapshot: valid, wantVWAP: "1"},
+		{name: "too old", snapshot: tooOld, wantVWAP: "0"},
+		{name: "other calculator", snapshot: otherCalculator, wantVWAP: "0"},
+		{name: "invalid state", snapshot: invalidState, wantVWAP: "0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				store := mockSnapshotStore{snapshots: map[string]Snapshot{"BTC-USD": tt.snapshot}}
+				calc, err := NewVWAPCalc(2)
+				require.NoError(t, err)
+
+				New(
+					config.VWAP{WindowSize: 2, PairVWAP: config.PairVWAP{Calculator: config.CalculatorNameWindow}},
+					calc,
+				).WithSnapshots("BTC-USD", store, config.Snapshot{MaxAge: time.Minute})
+
+				assert.Equal(t, tt.wantVWAP, calc.VWAP().String())
+			},
+		)
+	}
+}
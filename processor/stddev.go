@@ -0,0 +1,47 @@
+package processor
+
+import (
+	"math"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	sqrtPrecision = 16
+	// sqrtGuardDigits are extra digits carried through the iterations so the result rounds correctly
+	sqrtGuardDigits = 4
+	sqrtIterations  = 3
+)
+
+var two = decimal.NewFromInt(2)
+
+// volumeWeightedStdDev returns sqrt(Σ(size·price²)/Σsize - vwap²).
+// Tiny negative variances caused by rounding are treated as zero.
+func volumeWeightedStdDev(totalSquaredValue, totalSize, vwap decimal.Decimal) decimal.Decimal {
+	if totalSize.IsZero() {
+		return decimal.Zero
+	}
+
+	variance := totalSquaredValue.Div(totalSize).Sub(vwap.Mul(vwap))
+
+	return sqrt(variance)
+}
+
+// sqrt returns the square root of d using Newton's method seeded with the float64 square root.
+// It returns zero for non-positive d.
+func sqrt(d decimal.Decimal) decimal.Decimal {
+	if d.Sign() <= 0 {
+		return decimal.Zero
+	}
+
+	x := d
+	if f := math.Sqrt(d.InexactFloat64()); !math.IsInf(f, 0) && f > 0 {
+		x = decimal.NewFromFloat(f)
+	}
+
+	for i := 0; i < sqrtIterations; i++ {
+		x = x.Add(d.DivRound(x, sqrtPrecision+sqrtGuardDigits)).DivRound(two, sqrtPrecision+sqrtGuardDigits)
+	}
+
+	return x.Round(sqrtPrecision)
+}
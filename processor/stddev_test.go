@@ -0,0 +1,30 @@
+package processor
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSqrt(t *testing.T) {
+	testCases := []struct {
+		d    string
+		want string
+	}{
+		{"-4", "0"},
+		{"0", "0"},
+		{"4", "2"},
+		{"2", "1.4142135623730950"},
+		{"0.0001", "0.01"},
+		{"380224.512345", "616.6234769654817481"},
+	}
+	for _, tc := range testCases {
+		t.Run(
+			tc.d, func(t *testing.T) {
+				got := sqrt(decimal.RequireFromString(tc.d))
+				assert.Equal(t, decimal.RequireFromString(tc.want).String(), got.String())
+			},
+		)
+	}
+}
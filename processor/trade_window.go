@@ -0,0 +1,57 @@
+package processor
+
+import (
+	"fmt"
+
+	"github.com/aprln/vwap-engine/model"
+)
+
+func newTradeWindow(windowSize int) (*tradeWindow, error) {
+	if windowSize <= 0 {
+		return nil, fmt.Errorf("invalid window size %d", windowSize)
+	}
+
+	return &tradeWindow{trades: make([]model.Trade, windowSize)}, nil
+}
+
+// tradeWindow is a fixed size ring buffer holding the last trades.
+type tradeWindow struct {
+	trades    []model.Trade
+	oldestIdx int
+	count     int
+}
+
+// push adds the trade as the newest one and returns the oldest trade if it had to be evicted.
+func (w *tradeWindow) push(trade model.Trade) (evicted model.Trade, ok bool) {
+	newestIdx := (w.oldestIdx + w.count) % len(w.trades)
+
+	if w.count < len(w.trades) {
+		w.trades[newestIdx] = trade
+		w.count++
+
+		return model.Trade{}, false
+	}
+
+	evicted = w.trades[w.oldestIdx]
+	w.trades[w.oldestIdx] = trade
+	w.oldestIdx = (w.oldestIdx + 1) % len(w.trades)
+
+	return evicted, true
+}
+
+func (w *tradeWindow) len() int {
+	return w.count
+}
+
+// at returns the i-th trade of the window, from the oldest to the newest.
+func (w *tradeWindow) at(i int) model.Trade {
+	return w.trades[(w.oldestIdx+i)%len(w.trades)]
+}
+
+func (w *tradeWindow) oldest() model.Trade {
+	return w.at(0)
+}
+
+func (w *tradeWindow) newest() model.Trade {
+	return w.at(w.count - 1)
+}
@@ -0,0 +1,40 @@
+package processor
+
+import (
+	"testing"
+
+	"github.com/aprln/vwap-engine/model"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTradeWindow_push(t *testing.T) {
+	_, err := newTradeWindow(0)
+	require.Error(t, err)
+
+	w, err := newTradeWindow(2)
+	require.NoError(t, err)
+
+	trade := func(price int64) model.Trade {
+		return model.Trade{Price: decimal.NewFromInt(price)}
+	}
+
+	_, ok := w.push(trade(1))
+	assert.False(t, ok)
+	_, ok = w.push(trade(2))
+	assert.False(t, ok)
+	assert.Equal(t, 2, w.len())
+
+	evicted, ok := w.push(trade(3))
+	require.True(t, ok)
+	assert.Equal(t, "1", evicted.Price.String())
+	assert.Equal(t, "2", w.oldest().Price.String())
+	assert.Equal(t, "3", w.newest().Price.String())
+
+	evicted, ok = w.push(trade(4))
+	require.True(t, ok)
+	assert.Equal(t, "2", evicted.Price.String())
+	assert.Equal(t, "3", w.at(0).Price.String())
+	assert.Equal(t, "4", w.at(1).Price.String())
+}
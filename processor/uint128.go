@@ -0,0 +1,76 @@
+package processor
+
+import (
+	"fmt"
+	"math/big"
+	"math/bits"
+)
+
+// uint128 is an unsigned 128-bit integer used for the fixed-point totals.
+type uint128 struct {
+	hi uint64
+	lo uint64
+}
+
+func mul64(a, b uint64) uint128 {
+	hi, lo := bits.Mul64(a, b)
+
+	return uint128{hi: hi, lo: lo}
+}
+
+func (u uint128) add(v uint128) (uint128, error) {
+	lo, carry := bits.Add64(u.lo, v.lo, 0)
+	hi, carry := bits.Add64(u.hi, v.hi, carry)
+	if carry != 0 {
+		return uint128{}, fmt.Errorf("uint128 overflow")
+	}
+
+	return uint128{hi: hi, lo: lo}, nil
+}
+
+func (u uint128) sub(v uint128) (uint128, error) {
+	lo, borrow := bits.Sub64(u.lo, v.lo, 0)
+	hi, borrow := bits.Sub64(u.hi, v.hi, borrow)
+	if borrow != 0 {
+		return uint128{}, fmt.Errorf("uint128 underflow")
+	}
+
+	return uint128{hi: hi, lo: lo}, nil
+}
+
+// mul returns u·v, or false on overflow.
+func (u uint128) mul(v uint64) (uint128, bool) {
+	hi, lo := bits.Mul64(u.lo, v)
+	carry, hiLo := bits.Mul64(u.hi, v)
+	if carry != 0 {
+		return uint128{}, false
+	}
+
+	hi, c := bits.Add64(hi, hiLo, 0)
+	if c != 0 {
+		return uint128{}, false
+	}
+
+	return uint128{hi: hi, lo: lo}, true
+}
+
+// divRound returns u/v rounded half up, like decimal.Decimal.DivRound, or false on overflow. v must not be zero.
+func (u uint128) divRound(v uint64) (uint128, bool) {
+	hi, r := bits.Div64(0, u.hi, v)
+	lo, r := bits.Div64(r, u.lo, v)
+	q := uint128{hi: hi, lo: lo}
+	if r < v-r {
+		return q, true
+	}
+
+	q, err := q.add(uint128{lo: 1})
+
+	return q, err == nil
+}
+
+func (u uint128) bigInt() *big.Int {
+	b := new(big.Int).SetUint64(u.hi)
+	b.Lsh(b, 64)
+
+	return b.Or(b, new(big.Int).SetUint64(u.lo))
+}
@@ -0,0 +1,85 @@
+package processor
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aprln/vwap-engine/config"
+	"github.com/aprln/vwap-engine/model"
+	"github.com/shopspring/decimal"
+)
+
+var hundred = decimal.NewFromInt(100)
+
+func NewValidator(validationCfg config.Validation) Validator {
+	return Validator{
+		validationCfg: validationCfg,
+		now:           time.Now,
+	}
+}
+
+// Validator checks trades against the configured rules before they reach the calculators.
+type Validator struct {
+	validationCfg config.Validation
+	now           func() time.Time
+	// lastPrice is the price of the previous trade checked by the price deviation rule, rejected or not
+	lastPrice decimal.Decimal
+}
+
+// Validate returns the reason why the trade is rejected, or nil if it is valid.
+//
+// The price deviation rule rejects a price deviating from both the current VWAP and the previous trade's price,
+// rejected or not. An outlier is rejected, but after a real price gap only the first trade is, instead of every
+// trade until the VWAP catches up, which it never would. The rule is skipped while the current VWAP is zero,
+// and currentVWAP is only called when the rule is on.
+func (v *Validator) Validate(trade model.Trade, currentVWAP func() decimal.Decimal) error {
+	cfg := v.validationCfg
+
+	if cfg.RequirePositive && !trade.Price.IsPositive() {
+		return fmt.Errorf("price %s is not positive", trade.Price)
+	}
+
+	if cfg.RequirePositive && !trade.Size.IsPositive() {
+		return fmt.Errorf("size %s is not positive", trade.Size)
+	}
+
+	if cfg.MaxSize.IsPositive() && trade.Size.GreaterThan(cfg.MaxSize) {
+		return fmt.Errorf("size %s is greater than %s", trade.Size, cfg.MaxSize)
+	}
+
+	now := v.now()
+
+	if cfg.MaxClockSkew > 0 && trade.Time.After(now.Add(cfg.MaxClockSkew)) {
+		return fmt.Errorf("time %s is in the future", trade.Time.Format(time.RFC3339Nano))
+	}
+
+	if cfg.MaxTradeAge > 0 && trade.Time.Before(now.Add(-cfg.MaxTradeAge)) {
+		return fmt.Errorf("time %s is older than %s", trade.Time.Format(time.RFC3339Nano), cfg.MaxTradeAge)
+	}
+
+	if cfg.MaxPriceDeviation.IsPositive() {
+		lastPrice := v.lastPrice
+		v.lastPrice = trade.Price
+
+		vwap := currentVWAP()
+		if vwap.IsZero() {
+			return nil
+		}
+
+		deviation := percentDeviation(trade.Price, vwap)
+		if deviation.GreaterThan(cfg.MaxPriceDeviation) &&
+			(lastPrice.IsZero() || percentDeviation(trade.Price, lastPrice).GreaterThan(cfg.MaxPriceDeviation)) {
+			return fmt.Errorf(
+				"price %s deviates from VWAP %s by %s%%, more than %s%%",
+				trade.Price, vwap, deviation.Round(2), cfg.MaxPriceDeviation,
+			)
+		}
+	}
+
+	return nil
+}
+
+// percentDeviation returns how far price is from the non-zero reference, in percent.
+func percentDeviation(price, reference decimal.Decimal) decimal.Decimal {
+	return price.Sub(reference).Abs().Div(reference.Abs()).Mul(hundred)
+}
@@ -0,0 +1,187 @@
+package processor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aprln/vwap-engine/config"
+	"github.com/aprln/vwap-engine/model"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidator_Validate(t *testing.T) {
+	now := time.Date(2022, 1, 1, 12, 0, 0, 0, time.UTC)
+	cfg := config.Validation{
+		RequirePositive: true,
+		MaxClockSkew:    5 * time.Second,
+		MaxTradeAge:     time.Minute,
+		PairValidation: config.PairValidation{
+			MaxSize:           decimal.NewFromInt(100),
+			MaxPriceDeviation: decimal.NewFromInt(10),
+		},
+	}
+
+	tests := []struct {
+		name        string
+		cfg         config.Validation
+		price       string
+		size        string
+		time        time.Time
+		currentVWAP string
+		wantErr     string
+	}{
+		{
+			name:        "valid",
+			cfg:         cfg,
+			price:       "105",
+			size:        "100",
+			time:        now,
+			currentVWAP: "100",
+		},
+		{
+			name:        "zero price",
+			cfg:         cfg,
+			price:       "0",
+			size:        "1",
+			time:        now,
+			currentVWAP: "0",
+			wantErr:     "price 0 is not positive",
+		},
+		{
+			name:        "negative size",
+			cfg:         cfg,
+			price:       "1",
+			size:        "-1",
+			time:        now,
+			currentVWAP: "0",
+			wantErr:     "size -1 is not positive",
+		},
+		{
+			name:        "zero size allowed when positivity is not required",
+			cfg:         config.Validation{},
+			price:       "1",
+			size:        "0",
+			time:        now,
+			currentVWAP: "0",
+		},
+		{
+			name:        "too big",
+			cfg:         cfg,
+			price:       "1",
+			size:        "100.1",
+			time:        now,
+			currentVWAP: "0",
+			wantErr:     "size 100.1 is greater than 100",
+		},
+		{
+			name:        "in the future",
+			cfg:         cfg,
+			price:       "1",
+			size:        "1",
+			time:        now.Add(6 * time.Second),
+			currentVWAP: "0",
+			wantErr:     "time 2022-01-01T12:00:06Z is in the future",
+		},
+		{
+			name:        "within the clock skew",
+			cfg:         cfg,
+			price:       "1",
+			size:        "1",
+			time:        now.Add(5 * time.Second),
+			currentVWAP: "0",
+		},
+		{
+			name:        "too old",
+			cfg:         cfg,
+			price:       "1",
+			size:        "1",
+			time:        now.Add(-time.Minute - time.Nanosecond),
+			currentVWAP: "0",
+			wantErr:     "time 2022-01-01T11:58:59.999999999Z is older than 1m0s",
+		},
+		{
+			name:        "price deviation",
+			cfg:         cfg,
+			price:       "89",
+			size:        "1",
+			time:        now,
+			currentVWAP: "100",
+			wantErr:     "price 89 deviates from VWAP 100 by 11%, more than 10%",
+		},
+		{
+			name:        "price deviation is skipped without VWAP",
+			cfg:         cfg,
+			price:       "89",
+			size:        "1",
+			time:        now,
+			currentVWAP: "0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				v := NewValidator(tt.cfg)
+				v.now = func() time.Time { return now }
+
+				err := v.Validate(
+					model.Trade{
+						TradingPair: "BTC-USD",
+						Price:       decimal.RequireFromString(tt.price),
+						Size:        decimal.RequireFromString(tt.size),
+						Time:        tt.time,
+					},
+					func() decimal.Decimal { return decimal.RequireFromString(tt.currentVWAP) },
+				)
+
+				if tt.wantErr == "" {
+					assert.NoError(t, err)
+
+					return
+				}
+
+				assert.EqualError(t, err, tt.wantErr)
+			},
+		)
+	}
+}
+
+func TestValidator_Validate_priceGap(t *testing.T) {
+	v := NewValidator(config.Validation{PairValidation: config.PairValidation{MaxPriceDeviation: decimal.NewFromInt(10)}})
+	currentVWAP := func() decimal.Decimal { return decimal.NewFromInt(100) }
+
+	sequence := []struct {
+		price   int64
+		wantErr bool
+	}{
+		{101, false},
+		// an outlier is rejected, and the next trade is checked against the VWAP again
+		{150, true},
+		{99, false},
+		// after a sustained gap, only the first trade is rejected
+		{150, true},
+		{151, false},
+		{149, false},
+		{150, false},
+	}
+
+	for i, s := range sequence {
+		err := v.Validate(model.Trade{Price: decimal.NewFromInt(s.price), Size: decimal.NewFromInt(1)}, currentVWAP)
+		assert.Equalf(t, s.wantErr, err != nil, "step %d: %v", i, err)
+	}
+}
+
+func TestValidator_Validate_priceDeviationDisabled(t *testing.T) {
+	v := NewValidator(config.Validation{})
+
+	err := v.Validate(
+		model.Trade{Price: decimal.NewFromInt(1), Size: decimal.NewFromInt(1)},
+		func() decimal.Decimal {
+			t.Fatal("the VWAP is read while the price deviation rule is off")
+
+			return decimal.Zero
+		},
+	)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,188 @@
+package processor
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aprln/vwap-engine/model"
+	"github.com/shopspring/decimal"
+)
+
+func NewVolumeVWAPCalc(windowVolume decimal.Decimal) (*VolumeVWAPCalc, error) {
+	if !windowVolume.IsPositive() {
+		return nil, fmt.Errorf("invalid window volume %s", windowVolume)
+	}
+
+	return &VolumeVWAPCalc{
+		windowVolume:      windowVolume,
+		totalValue:        decimal.Zero,
+		totalSquaredValue: decimal.Zero,
+		totalSize:         decimal.Zero,
+		vwap:              decimal.Zero,
+	}, nil
+}
+
+// VolumeVWAPCalc calculates the VWAP of the last windowVolume units of base volume traded.
+// When the window overflows, data points are evicted from the oldest end and the oldest remaining one
+// is split so the window volume stays exact. Totals are adjusted incrementally like VWAPCalc does.
+type VolumeVWAPCalc struct {
+	windowVolume decimal.Decimal
+	// dataPoints[oldestDataPointIdx:] are the data points in the window, from the oldest to the newest
+	dataPoints []VWAPCalcDataPoint
+	// tradeTimes[i] is the time of the trade of dataPoints[i]
+	tradeTimes         []time.Time
+	nextTradeAt        time.Time
+	oldestDataPointIdx int
+	totalValue         decimal.Decimal
+	totalSquaredValue  decimal.Decimal
+	totalSize          decimal.Decimal
+	vwap               decimal.Decimal
+}
+
+func (c *VolumeVWAPCalc) VWAP() decimal.Decimal {
+	return c.vwap
+}
+
+// StdDev returns the volume-weighted standard deviation of price around VWAP.
+func (c *VolumeVWAPCalc) StdDev() decimal.Decimal {
+	return volumeWeightedStdDev(c.totalSquaredValue, c.totalSize, c.vwap)
+}
+
+// AdvanceTo records the trade time of the next data point, which is reported by Window.
+func (c *VolumeVWAPCalc) AdvanceTo(t time.Time) {
+	c.nextTradeAt = t
+}
+
+// Window returns the number of data points in the window, their totals and trade times.
+// The window is full once it holds the whole window volume.
+func (c *VolumeVWAPCalc) Window() model.Window {
+	w := model.Window{
+		TradeCount: len(c.dataPoints) - c.oldestDataPointIdx,
+		Full:       c.totalSize.Equal(c.windowVolume),
+		Volume:     c.totalSize,
+		Notional:   c.totalValue,
+	}
+
+	if w.TradeCount > 0 {
+		w.OldestTradeAt = c.tradeTimes[c.oldestDataPointIdx]
+		w.NewestTradeAt = c.tradeTimes[len(c.tradeTimes)-1]
+	}
+
+	return w
+}
+
+func (c *VolumeVWAPCalc) CheckDataPoint(price, size decimal.Decimal) error {
+	if size.IsNegative() {
+		return fmt.Errorf("VolumeVWAPCalc.AddDataPoint got negative size %s", size)
+	}
+
+	return nil
+}
+
+func (c *VolumeVWAPCalc) AddDataPoint(price, size decimal.Decimal) error {
+	if err := c.CheckDataPoint(price, size); err != nil {
+		return err
+	}
+
+	if size.IsZero() {
+		return nil
+	}
+
+	newDP := VWAPCalcDataPoint{Price: price, Size: size}
+	c.dataPoints = append(c.dataPoints, newDP)
+	c.tradeTimes = append(c.tradeTimes, c.nextTradeAt)
+	c.addToTotals(newDP)
+	c.evictOverflow()
+	c.compact()
+	c.calcVWAP()
+
+	return nil
+}
+
+// Verify recomputes the totals from the data points and returns their drift from the running totals.
+// On drift, the running totals and VWAP are resynchronized with the recomputed ones.
+func (c *VolumeVWAPCalc) Verify() (Drift, error) {
+	totalValue, totalSquaredValue, totalSize := decimal.Zero, decimal.Zero, decimal.Zero
+	for _, dp := range c.dataPoints[c.oldestDataPointIdx:] {
+		totalValue = totalValue.Add(dp.Value())
+		totalSquaredValue = totalSquaredValue.Add(dp.SquaredValue())
+		totalSize = totalSize.Add(dp.Size)
+	}
+
+	if totalSize.GreaterThan(c.windowVolume) {
+		return Drift{}, fmt.Errorf("VolumeVWAPCalc.Verify window holds %s, more than %s", totalSize, c.windowVolume)
+	}
+
+	drift := Drift{
+		TotalValue:        c.totalValue.Sub(totalValue),
+		TotalSquaredValue: c.totalSquaredValue.Sub(totalSquaredValue),
+		TotalSize:         c.totalSize.Sub(totalSize),
+	}
+
+	if !drift.IsZero() {
+		c.totalValue = totalValue
+		c.totalSquaredValue = totalSquaredValue
+		c.totalSize = totalSize
+		c.calcVWAP()
+	}
+
+	return drift, nil
+}
+
+func (c *VolumeVWAPCalc) evictOverflow() {
+	overflow := c.totalSize.Sub(c.windowVolume)
+
+	for overflow.IsPositive() {
+		oldest := c.dataPoints[c.oldestDataPointIdx]
+
+		if oldest.Size.LessThanOrEqual(overflow) {
+			c.subtractFromTotals(oldest)
+			c.dataPoints[c.oldestDataPointIdx] = VWAPCalcDataPoint{}
+			c.oldestDataPointIdx++
+			overflow = overflow.Sub(oldest.Size)
+
+			continue
+		}
+
+		// split the oldest data point and only evict the overflowing part of it
+		c.subtractFromTotals(VWAPCalcDataPoint{Price: oldest.Price, Size: overflow})
+		c.dataPoints[c.oldestDataPointIdx].Size = oldest.Size.Sub(overflow)
+		overflow = decimal.Zero
+	}
+}
+
+// compact moves the data points to the front of the slice once at least half of it has been evicted,
+// so the slice does not grow forever.
+func (c *VolumeVWAPCalc) compact() {
+	if c.oldestDataPointIdx == 0 || c.oldestDataPointIdx < len(c.dataPoints)/2 {
+		return
+	}
+
+	n := copy(c.dataPoints, c.dataPoints[c.oldestDataPointIdx:])
+	c.dataPoints = c.dataPoints[:n]
+	copy(c.tradeTimes, c.tradeTimes[c.oldestDataPointIdx:])
+	c.tradeTimes = c.tradeTimes[:n]
+	c.oldestDataPointIdx = 0
+}
+
+func (c *VolumeVWAPCalc) addToTotals(dp VWAPCalcDataPoint) {
+	c.totalValue = c.totalValue.Add(dp.Value())
+	c.totalSquaredValue = c.totalSquaredValue.Add(dp.SquaredValue())
+	c.totalSize = c.totalSize.Add(dp.Size)
+}
+
+func (c *VolumeVWAPCalc) subtractFromTotals(dp VWAPCalcDataPoint) {
+	c.totalValue = c.totalValue.Sub(dp.Value())
+	c.totalSquaredValue = c.totalSquaredValue.Sub(dp.SquaredValue())
+	c.totalSize = c.totalSize.Sub(dp.Size)
+}
+
+func (c *VolumeVWAPCalc) calcVWAP() {
+	if c.totalSize.IsZero() {
+		c.vwap = decimal.Zero
+
+		return
+	}
+
+	c.vwap = c.totalValue.Div(c.totalSize)
+}
@@ -0,0 +1,105 @@
+package processor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewVolumeVWAPCalc(t *testing.T) {
+	_, err := NewVolumeVWAPCalc(decimal.Zero)
+	require.Error(t, err)
+
+	_, err = NewVolumeVWAPCalc(decimal.NewFromInt(-1))
+	require.Error(t, err)
+
+	c, err := NewVolumeVWAPCalc(decimal.NewFromInt(10))
+	require.NoError(t, err)
+	assert.Equal(t, "0", c.VWAP().String())
+}
+
+func TestVolumeVWAPCalc_VWAPFlow(t *testing.T) {
+	c, err := NewVolumeVWAPCalc(decimal.NewFromInt(10))
+	require.NoError(t, err)
+
+	sequence := []struct {
+		price          string
+		size           string
+		wantVWAP       string
+		wantTotalSize  string
+		wantDataPoints int
+	}{
+		{"1", "4", "1", "4", 1},
+		{"2", "4", "1.5", "8", 2},
+		// 2 units of the first data point are evicted: 1x2 + 2x4 + 3x4
+		{"3", "4", "2.2", "10", 3},
+		// the first data point is evicted and 1 unit of the second one: 2x3 + 3x4 + 4x3
+		{"4", "3", "3", "10", 3},
+		// a data point bigger than the window evicts everything else
+		{"5", "25", "5", "10", 1},
+		{"6", "0", "5", "10", 1},
+		{"6", "5", "5.5", "10", 2},
+	}
+
+	for i, s := range sequence {
+		err = c.AddDataPoint(decimal.RequireFromString(s.price), decimal.RequireFromString(s.size))
+		require.NoError(t, err)
+		require.Equalf(t, s.wantVWAP, c.VWAP().String(), "failed at step %d", i)
+		require.Equalf(t, s.wantTotalSize, c.totalSize.String(), "failed at step %d", i)
+		require.Equalf(t, s.wantDataPoints, len(c.dataPoints)-c.oldestDataPointIdx, "failed at step %d", i)
+	}
+
+	require.Error(t, c.AddDataPoint(decimal.NewFromInt(1), decimal.NewFromInt(-1)))
+}
+
+func TestVolumeVWAPCalc_Verify(t *testing.T) {
+	c, err := NewVolumeVWAPCalc(decimal.NewFromInt(10))
+	require.NoError(t, err)
+	require.NoError(t, c.AddDataPoint(decimal.NewFromInt(1), decimal.NewFromInt(6)))
+	require.NoError(t, c.AddDataPoint(decimal.NewFromInt(2), decimal.NewFromInt(6)))
+
+	drift, err := c.Verify()
+	require.NoError(t, err)
+	assert.True(t, drift.IsZero())
+
+	c.totalValue = c.totalValue.Add(decimal.NewFromInt(1))
+	drift, err = c.Verify()
+	require.NoError(t, err)
+	assert.Equal(t, "1", drift.TotalValue.String())
+	assert.Equal(t, "1.6", c.VWAP().String())
+}
+
+func TestVolumeVWAPCalc_Window(t *testing.T) {
+	c, err := NewVolumeVWAPCalc(decimal.NewFromInt(3))
+	require.NoError(t, err)
+
+	start := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	sequence := []struct {
+		size       int64
+		wantCount  int
+		wantFull   bool
+		wantVolume string
+		wantOldest time.Time
+	}{
+		{1, 1, false, "1", start},
+		{1, 2, false, "2", start},
+		{2, 2, true, "3", start.Add(time.Second)},
+		{3, 1, true, "3", start.Add(3 * time.Second)},
+	}
+
+	for i, s := range sequence {
+		tradeAt := start.Add(time.Duration(i) * time.Second)
+		c.AdvanceTo(tradeAt)
+		require.NoError(t, c.AddDataPoint(decimal.NewFromInt(1), decimal.NewFromInt(s.size)))
+
+		w := c.Window()
+		assert.Equalf(t, s.wantCount, w.TradeCount, "failed at step %d", i)
+		assert.Equalf(t, s.wantFull, w.Full, "failed at step %d", i)
+		assert.Equalf(t, s.wantVolume, w.Volume.String(), "failed at step %d", i)
+		assert.Equalf(t, s.wantOldest, w.OldestTradeAt, "failed at step %d", i)
+		assert.Equalf(t, tradeAt, w.NewestTradeAt, "failed at step %d", i)
+	}
+}
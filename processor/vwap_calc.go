@@ -1,26 +1,36 @@
 package processor
 
 import (
+	"encoding/json"
 	"fmt"
+	"time"
 
+	"github.com/aprln/vwap-engine/model"
 	"github.com/shopspring/decimal"
 )
 
 type VWAPCalcDataPoint struct {
-	Price decimal.Decimal
-	Size  decimal.Decimal
+	Price decimal.Decimal `json:"price"`
+	Size  decimal.Decimal `json:"size"`
 }
 
 func (t VWAPCalcDataPoint) Value() decimal.Decimal {
 	return t.Price.Mul(t.Size)
 }
 
+// SquaredValue returns size·price², which is needed for the volume-weighted variance.
+func (t VWAPCalcDataPoint) SquaredValue() decimal.Decimal {
+	return t.Price.Mul(t.Price).Mul(t.Size)
+}
+
 func NewVWAPCalc(windowSize int) (*VWAPCalc, error) {
 	c := VWAPCalc{
 		windowSize:         windowSize,
 		dataPoints:         make([]VWAPCalcDataPoint, windowSize),
+		tradeTimes:         make([]time.Time, windowSize),
 		oldestDataPointIdx: 0,
 		totalValue:         decimal.Zero,
+		totalSquaredValue:  decimal.Zero,
 		totalSize:          decimal.Zero,
 		vwap:               decimal.Zero,
 	}
@@ -33,10 +43,15 @@ func NewVWAPCalc(windowSize int) (*VWAPCalc, error) {
 }
 
 type VWAPCalc struct {
-	windowSize         int
-	dataPoints         []VWAPCalcDataPoint
+	windowSize int
+	dataPoints []VWAPCalcDataPoint
+	// tradeTimes[i] is the time of the trade of dataPoints[i]
+	tradeTimes         []time.Time
+	nextTradeAt        time.Time
+	dataPointCount     int
 	oldestDataPointIdx int
 	totalValue         decimal.Decimal
+	totalSquaredValue  decimal.Decimal
 	totalSize          decimal.Decimal
 	vwap               decimal.Decimal
 }
@@ -45,13 +60,42 @@ func (c *VWAPCalc) VWAP() decimal.Decimal {
 	return c.vwap
 }
 
+// StdDev returns the volume-weighted standard deviation of price around VWAP.
+func (c *VWAPCalc) StdDev() decimal.Decimal {
+	return volumeWeightedStdDev(c.totalSquaredValue, c.totalSize, c.vwap)
+}
+
+// AdvanceTo records the trade time of the next data point, which is reported by Window.
+func (c *VWAPCalc) AdvanceTo(t time.Time) {
+	c.nextTradeAt = t
+}
+
+// Window returns the number of data points in the window, their totals and trade times.
+func (c *VWAPCalc) Window() model.Window {
+	return newRingWindow(c.tradeTimes, c.oldestDataPointIdx, c.dataPointCount, c.totalSize, c.totalValue)
+}
+
+func (c *VWAPCalc) CheckDataPoint(price, size decimal.Decimal) error {
+	if err := c.checkIntegrity(); err != nil {
+		return fmt.Errorf("VWAPCalc.AddDataPoint failed data integrity test: %v", err)
+	}
+
+	return nil
+}
+
 func (c *VWAPCalc) AddDataPoint(price, size decimal.Decimal) error {
 	if err := c.checkIntegrity(); err != nil {
 		return fmt.Errorf("VWAPCalc.AddDataPoint failed data integrity test: %v", err)
 	}
 
+	c.tradeTimes[c.oldestDataPointIdx] = c.nextTradeAt
+	if c.dataPointCount < c.windowSize {
+		c.dataPointCount++
+	}
+
 	oldDP, newDP := c.replaceOldestDataPoint(price, size)
 	c.adjustTotalValue(oldDP, newDP)
+	c.adjustTotalSquaredValue(oldDP, newDP)
 	c.adjustTotalSize(oldDP, newDP)
 	c.calcVWAP()
 	c.adjustOldestDataPointIdx()
@@ -59,6 +103,96 @@ func (c *VWAPCalc) AddDataPoint(price, size decimal.Decimal) error {
 	return nil
 }
 
+// Verify recomputes the totals from the data points and returns their drift from the running totals.
+// On drift, the running totals and VWAP are resynchronized with the recomputed ones.
+func (c *VWAPCalc) Verify() (Drift, error) {
+	if err := c.checkIntegrity(); err != nil {
+		return Drift{}, fmt.Errorf("VWAPCalc.Verify failed data integrity test: %v", err)
+	}
+
+	totalValue, totalSquaredValue, totalSize := decimal.Zero, decimal.Zero, decimal.Zero
+	for _, dp := range c.dataPoints {
+		totalValue = totalValue.Add(dp.Value())
+		totalSquaredValue = totalSquaredValue.Add(dp.SquaredValue())
+		totalSize = totalSize.Add(dp.Size)
+	}
+
+	drift := Drift{
+		TotalValue:        c.totalValue.Sub(totalValue),
+		TotalSquaredValue: c.totalSquaredValue.Sub(totalSquaredValue),
+		TotalSize:         c.totalSize.Sub(totalSize),
+	}
+
+	if !drift.IsZero() {
+		c.totalValue = totalValue
+		c.totalSquaredValue = totalSquaredValue
+		c.totalSize = totalSize
+		c.calcVWAP()
+	}
+
+	return drift, nil
+}
+
+// vwapCalcState is the snapshot state of a VWAPCalc.
+type vwapCalcState struct {
+	WindowSize         int                 `json:"window_size"`
+	DataPoints         []VWAPCalcDataPoint `json:"data_points"`
+	TradeTimes         []time.Time         `json:"trade_times"`
+	DataPointCount     int                 `json:"data_point_count"`
+	OldestDataPointIdx int                 `json:"oldest_data_point_idx"`
+	TotalValue         decimal.Decimal     `json:"total_value"`
+	TotalSquaredValue  decimal.Decimal     `json:"total_squared_value"`
+	TotalSize          decimal.Decimal     `json:"total_size"`
+}
+
+// MarshalState returns the data points with their trade times, the oldest index and the totals as JSON.
+func (c *VWAPCalc) MarshalState() (json.RawMessage, error) {
+	return json.Marshal(
+		vwapCalcState{
+			WindowSize:         c.windowSize,
+			DataPoints:         c.dataPoints,
+			TradeTimes:         c.tradeTimes,
+			DataPointCount:     c.dataPointCount,
+			OldestDataPointIdx: c.oldestDataPointIdx,
+			TotalValue:         c.totalValue,
+			TotalSquaredValue:  c.totalSquaredValue,
+			TotalSize:          c.totalSize,
+		},
+	)
+}
+
+// RestoreState replaces the calculator's state with one returned by MarshalState.
+// The calculator is left unchanged if the state is invalid or was taken with another window size.
+func (c *VWAPCalc) RestoreState(state json.RawMessage) error {
+	var s vwapCalcState
+	if err := json.Unmarshal(state, &s); err != nil {
+		return fmt.Errorf("VWAPCalc.RestoreState invalid state: %v", err)
+	}
+
+	if s.WindowSize != c.windowSize {
+		return fmt.Errorf("VWAPCalc.RestoreState window size %d is not the same as %d", s.WindowSize, c.windowSize)
+	}
+
+	restored := VWAPCalc{
+		windowSize:         s.WindowSize,
+		dataPoints:         s.DataPoints,
+		tradeTimes:         s.TradeTimes,
+		dataPointCount:     s.DataPointCount,
+		oldestDataPointIdx: s.OldestDataPointIdx,
+		totalValue:         s.TotalValue,
+		totalSquaredValue:  s.TotalSquaredValue,
+		totalSize:          s.TotalSize,
+	}
+	if err := restored.checkIntegrity(); err != nil {
+		return fmt.Errorf("VWAPCalc.RestoreState failed data integrity test: %v", err)
+	}
+
+	restored.calcVWAP()
+	*c = restored
+
+	return nil
+}
+
 func (c *VWAPCalc) replaceOldestDataPoint(price, size decimal.Decimal) (oldDP, newDP VWAPCalcDataPoint) {
 	oldDP = c.dataPoints[c.oldestDataPointIdx]
 	newDP = VWAPCalcDataPoint{Price: price, Size: size}
@@ -70,6 +204,10 @@ func (c *VWAPCalc) adjustTotalValue(oldDP, newDP VWAPCalcDataPoint) {
 	c.totalValue = c.totalValue.Sub(oldDP.Value()).Add(newDP.Value())
 }
 
+func (c *VWAPCalc) adjustTotalSquaredValue(oldDP, newDP VWAPCalcDataPoint) {
+	c.totalSquaredValue = c.totalSquaredValue.Sub(oldDP.SquaredValue()).Add(newDP.SquaredValue())
+}
+
 func (c *VWAPCalc) adjustTotalSize(oldDP, newDP VWAPCalcDataPoint) {
 	c.totalSize = c.totalSize.Sub(oldDP.Size).Add(newDP.Size)
 }
@@ -104,5 +242,13 @@ func (c *VWAPCalc) checkIntegrity() error {
 		return fmt.Errorf("oldestDataPointIdx out of range: %d", c.oldestDataPointIdx)
 	}
 
+	if len(c.tradeTimes) != len(c.dataPoints) {
+		return fmt.Errorf("trade times size %d is not the same as data points size %d", len(c.tradeTimes), len(c.dataPoints))
+	}
+
+	if c.dataPointCount < 0 || c.dataPointCount > c.windowSize {
+		return fmt.Errorf("dataPointCount out of range: %d", c.dataPointCount)
+	}
+
 	return nil
 }
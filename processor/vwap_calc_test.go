@@ -3,6 +3,7 @@ package processor
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
@@ -53,8 +54,10 @@ func TestNewVWAPCalc(t *testing.T) {
 			want: VWAPCalc{
 				windowSize:         200,
 				dataPoints:         make([]VWAPCalcDataPoint, 200),
+				tradeTimes:         make([]time.Time, 200),
 				oldestDataPointIdx: 0,
 				totalValue:         decimal.Zero,
+				totalSquaredValue:  decimal.Zero,
 				totalSize:          decimal.Zero,
 				vwap:               decimal.Zero,
 			},
@@ -213,6 +216,7 @@ func TestVWAPCalc_AddDataPoint(t *testing.T) {
 				c := VWAPCalc{
 					windowSize:         tc.windowSize,
 					dataPoints:         tc.dataPoints,
+					tradeTimes:         make([]time.Time, len(tc.dataPoints)),
 					oldestDataPointIdx: tc.oldestDataPointIdx,
 					totalValue:         decimal.NewFromFloat(tc.totalValue),
 					totalSize:          decimal.NewFromFloat(tc.totalSize),
@@ -261,3 +265,119 @@ func TestVWAPCalc_VWAPFlow(t *testing.T) {
 		require.Equalf(t, s.wantVWAP, c.VWAP().String(), "failed at step %d", i)
 	}
 }
+
+func TestVWAPCalc_StdDevFlow(t *testing.T) {
+	c, e := NewVWAPCalc(2)
+	require.NoError(t, e)
+	require.Equal(t, "0", c.StdDev().String(), "failed at init")
+
+	sequence := []struct {
+		price      float64
+		size       float64
+		wantStdDev string
+	}{
+		{1, 1, "0"},
+		{3, 1, "1"},
+		{3, 3, "0"},
+		{5, 1, "0.8660254037844386"},
+	}
+
+	for i, s := range sequence {
+		e = c.AddDataPoint(decimal.NewFromFloat(s.price), decimal.NewFromFloat(s.size))
+		require.NoError(t, e)
+		require.Equalf(t, s.wantStdDev, c.StdDev().String(), "failed at step %d", i)
+	}
+}
+
+func TestVWAPCalc_Verify(t *testing.T) {
+	c, e := NewVWAPCalc(2)
+	require.NoError(t, e)
+	require.NoError(t, c.AddDataPoint(decimal.NewFromInt(1), decimal.NewFromInt(1)))
+	require.NoError(t, c.AddDataPoint(decimal.NewFromInt(3), decimal.NewFromInt(1)))
+
+	drift, e := c.Verify()
+	require.NoError(t, e)
+	assert.True(t, drift.IsZero())
+
+	// simulate drifting running totals
+	c.totalValue = c.totalValue.Add(decimal.NewFromFloat(0.5))
+	c.totalSize = c.totalSize.Sub(decimal.NewFromFloat(0.25))
+	c.calcVWAP()
+
+	drift, e = c.Verify()
+	require.NoError(t, e)
+	assert.Equal(t, "0.5", drift.TotalValue.String())
+	assert.Equal(t, "0", drift.TotalSquaredValue.String())
+	assert.Equal(t, "-0.25", drift.TotalSize.String())
+	assert.Equal(t, "2", c.VWAP().String())
+
+	c.oldestDataPointIdx = 2
+	_, e = c.Verify()
+	require.Error(t, e)
+}
+
+func TestVWAPCalc_RestoreState(t *testing.T) {
+	c, err := NewVWAPCalc(3)
+	require.NoError(t, err)
+	for _, price := range []int64{1, 2, 3, 4} {
+		require.NoError(t, c.AddDataPoint(decimal.NewFromInt(price), decimal.NewFromInt(1)))
+	}
+
+	state, err := c.MarshalState()
+	require.NoError(t, err)
+
+	restored, err := NewVWAPCalc(3)
+	require.NoError(t, err)
+	require.NoError(t, restored.RestoreState(state))
+	assert.Equal(t, c.VWAP().String(), restored.VWAP().String())
+
+	// the restored calculator carries on from the same window
+	require.NoError(t, c.AddDataPoint(decimal.NewFromInt(5), decimal.NewFromInt(1)))
+	require.NoError(t, restored.AddDataPoint(decimal.NewFromInt(5), decimal.NewFromInt(1)))
+	assert.Equal(t, "4", restored.VWAP().String())
+	assert.Equal(t, c.VWAP().String(), restored.VWAP().String())
+
+	other, err := NewVWAPCalc(2)
+	require.NoError(t, err)
+	require.Error(t, other.RestoreState(state), "window size mismatch")
+	require.Error(t, other.RestoreState([]byte(`{"window_size":2,"data_points":[],"oldest_data_point_idx":0}`)))
+	require.Error(t, other.RestoreState([]byte(`{`)))
+	assert.Equal(t, "0", other.VWAP().String(), "the calculator is unchanged after a failed restore")
+}
+
+func TestVWAPCalc_Window(t *testing.T) {
+	c, err := NewVWAPCalc(2)
+	require.NoError(t, err)
+
+	w := c.Window()
+	assert.Equal(t, 0, w.TradeCount)
+	assert.False(t, w.Full)
+	assert.True(t, w.OldestTradeAt.IsZero())
+
+	start := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	sequence := []struct {
+		price      int64
+		wantCount  int
+		wantFull   bool
+		wantVolume string
+		wantOldest time.Time
+	}{
+		{1, 1, false, "1", start},
+		{2, 2, true, "2", start},
+		{3, 2, true, "2", start.Add(time.Second)},
+	}
+
+	for i, s := range sequence {
+		tradeAt := start.Add(time.Duration(i) * time.Second)
+		c.AdvanceTo(tradeAt)
+		require.NoError(t, c.AddDataPoint(decimal.NewFromInt(s.price), decimal.NewFromInt(1)))
+
+		w = c.Window()
+		assert.Equalf(t, s.wantCount, w.TradeCount, "failed at step %d", i)
+		assert.Equalf(t, s.wantFull, w.Full, "failed at step %d", i)
+		assert.Equalf(t, s.wantVolume, w.Volume.String(), "failed at step %d", i)
+		assert.Equalf(t, c.totalValue.String(), w.Notional.String(), "failed at step %d", i)
+		assert.Equalf(t, s.wantOldest, w.OldestTradeAt, "failed at step %d", i)
+		assert.Equalf(t, tradeAt, w.NewestTradeAt, "failed at step %d", i)
+	}
+}
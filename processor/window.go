@@ -0,0 +1,33 @@
+package processor
+
+import (
+	"time"
+
+	"github.com/aprln/vwap-engine/model"
+	"github.com/shopspring/decimal"
+)
+
+// newRingWindow returns the window of a fixed size ring of data points, whose next write index is nextIdx.
+// While the ring is not full, its data points are at the indexes [0, count).
+func newRingWindow(tradeTimes []time.Time, nextIdx, count int, volume, notional decimal.Decimal) model.Window {
+	w := model.Window{
+		TradeCount: count,
+		Full:       count == len(tradeTimes),
+		Volume:     volume,
+		Notional:   notional,
+	}
+
+	if count == 0 {
+		return w
+	}
+
+	oldestIdx := 0
+	if w.Full {
+		oldestIdx = nextIdx
+	}
+
+	w.OldestTradeAt = tradeTimes[oldestIdx]
+	w.NewestTradeAt = tradeTimes[(nextIdx-1+len(tradeTimes))%len(tradeTimes)]
+
+	return w
+}
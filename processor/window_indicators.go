@@ -0,0 +1,232 @@
+package processor
+
+import (
+	"sort"
+
+	"github.com/aprln/vwap-engine/config"
+	"github.com/aprln/vwap-engine/model"
+	"github.com/shopspring/decimal"
+)
+
+func newTradeCountIndicator(vwapCfg config.VWAP) (Indicator, error) {
+	w, err := newTradeWindow(vwapCfg.WindowSize)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tradeCountIndicator{window: w}, nil
+}
+
+// tradeCountIndicator counts the trades in the window.
+type tradeCountIndicator struct {
+	window *tradeWindow
+}
+
+func (i *tradeCountIndicator) Update(trade model.Trade) error {
+	i.window.push(trade)
+
+	return nil
+}
+
+func (i *tradeCountIndicator) Value() decimal.Decimal {
+	return decimal.NewFromInt(int64(i.window.len()))
+}
+
+func newNotionalIndicator(vwapCfg config.VWAP) (Indicator, error) {
+	w, err := newTradeWindow(vwapCfg.WindowSize)
+	if err != nil {
+		return nil, err
+	}
+
+	return &notionalIndicator{window: w, totalValue: decimal.Zero}, nil
+}
+
+// notionalIndicator sums price·size over the window.
+type notionalIndicator struct {
+	window     *tradeWindow
+	totalValue decimal.Decimal
+}
+
+func (i *notionalIndicator) Update(trade model.Trade) error {
+	i.totalValue = i.totalValue.Add(trade.Price.Mul(trade.Size))
+
+	if evicted, ok := i.window.push(trade); ok {
+		i.totalValue = i.totalValue.Sub(evicted.Price.Mul(evicted.Size))
+	}
+
+	return nil
+}
+
+func (i *notionalIndicator) Value() decimal.Decimal {
+	return i.totalValue
+}
+
+func newTWAPIndicator(vwapCfg config.VWAP) (Indicator, error) {
+	w, err := newTradeWindow(vwapCfg.WindowSize)
+	if err != nil {
+		return nil, err
+	}
+
+	return &twapIndicator{window: w, totalWeightedPrice: decimal.Zero, totalPrice: decimal.Zero}, nil
+}
+
+// twapIndicator calculates the time-weighted average price over the window.
+// Each price is weighted by the time until the next trade, so the newest price has no weight yet.
+// If all trades in the window share the same time, the plain average price is used instead.
+type twapIndicator struct {
+	window *tradeWindow
+	// totalWeightedPrice is Σ price·(next trade time - trade time) in nanoseconds
+	totalWeightedPrice decimal.Decimal
+	totalPrice         decimal.Decimal
+}
+
+func (i *twapIndicator) Update(trade model.Trade) error {
+	if i.window.len() > 0 {
+		prev := i.window.newest()
+		i.totalWeightedPrice = i.totalWeightedPrice.Add(weightedPrice(prev, trade))
+	}
+
+	i.totalPrice = i.totalPrice.Add(trade.Price)
+
+	if evicted, ok := i.window.push(trade); ok {
+		i.totalWeightedPrice = i.totalWeightedPrice.Sub(weightedPrice(evicted, i.window.oldest()))
+		i.totalPrice = i.totalPrice.Sub(evicted.Price)
+	}
+
+	return nil
+}
+
+func (i *twapIndicator) Value() decimal.Decimal {
+	if i.window.len() == 0 {
+		return decimal.Zero
+	}
+
+	duration := i.window.newest().Time.Sub(i.window.oldest().Time)
+	if duration <= 0 {
+		return i.totalPrice.Div(decimal.NewFromInt(int64(i.window.len())))
+	}
+
+	return i.totalWeightedPrice.Div(decimal.NewFromInt(duration.Nanoseconds()))
+}
+
+func weightedPrice(trade, next model.Trade) decimal.Decimal {
+	return trade.Price.Mul(decimal.NewFromInt(next.Time.Sub(trade.Time).Nanoseconds()))
+}
+
+func newMedianIndicator(vwapCfg config.VWAP) (Indicator, error) {
+	w, err := newTradeWindow(vwapCfg.WindowSize)
+	if err != nil {
+		return nil, err
+	}
+
+	return &medianIndicator{sorted: sortedTrades{window: w}}, nil
+}
+
+// medianIndicator calculates the simple moving median of price over the window.
+type medianIndicator struct {
+	sorted sortedTrades
+}
+
+func (i *medianIndicator) Update(trade model.Trade) error {
+	i.sorted.push(trade)
+
+	return nil
+}
+
+func (i *medianIndicator) Value() decimal.Decimal {
+	n := len(i.sorted.trades)
+	if n == 0 {
+		return decimal.Zero
+	}
+
+	if n%2 == 1 {
+		return i.sorted.trades[n/2].Price
+	}
+
+	return i.sorted.trades[n/2-1].Price.Add(i.sorted.trades[n/2].Price).Div(two)
+}
+
+func newVWMedianIndicator(vwapCfg config.VWAP) (Indicator, error) {
+	w, err := newTradeWindow(vwapCfg.WindowSize)
+	if err != nil {
+		return nil, err
+	}
+
+	return &vwMedianIndicator{sorted: sortedTrades{window: w}, totalSize: decimal.Zero}, nil
+}
+
+// vwMedianIndicator calculates the volume-weighted moving median of price over the window,
+// i.e. the lowest price at which the cumulative size reaches half of the window's total traded size (volume).
+type vwMedianIndicator struct {
+	sorted    sortedTrades
+	totalSize decimal.Decimal
+}
+
+func (i *vwMedianIndicator) Update(trade model.Trade) error {
+	i.totalSize = i.totalSize.Add(trade.Size)
+
+	if evicted, ok := i.sorted.push(trade); ok {
+		i.totalSize = i.totalSize.Sub(evicted.Size)
+	}
+
+	return nil
+}
+
+func (i *vwMedianIndicator) Value() decimal.Decimal {
+	half := i.totalSize.Div(two)
+	cumulativeSize := decimal.Zero
+	for _, trade := range i.sorted.trades {
+		cumulativeSize = cumulativeSize.Add(trade.Size)
+		if cumulativeSize.GreaterThanOrEqual(half) {
+			return trade.Price
+		}
+	}
+
+	return decimal.Zero
+}
+
+// sortedTrades keeps the trades of a window sorted by price.
+// Insertion and removal are O(window size), which is fine for the window sizes used.
+type sortedTrades struct {
+	window *tradeWindow
+	trades []model.Trade
+}
+
+func (s *sortedTrades) push(trade model.Trade) (evicted model.Trade, ok bool) {
+	evicted, ok = s.window.push(trade)
+	if ok {
+		s.remove(evicted)
+	}
+
+	s.insert(trade)
+
+	return evicted, ok
+}
+
+func (s *sortedTrades) insert(trade model.Trade) {
+	idx := sort.Search(
+		len(s.trades), func(i int) bool {
+			return s.trades[i].Price.GreaterThanOrEqual(trade.Price)
+		},
+	)
+
+	s.trades = append(s.trades, model.Trade{})
+	copy(s.trades[idx+1:], s.trades[idx:])
+	s.trades[idx] = trade
+}
+
+func (s *sortedTrades) remove(trade model.Trade) {
+	idx := sort.Search(
+		len(s.trades), func(i int) bool {
+			return s.trades[i].Price.GreaterThanOrEqual(trade.Price)
+		},
+	)
+
+	for ; idx < len(s.trades) && s.trades[idx].Price.Equal(trade.Price); idx++ {
+		if s.trades[idx].Size.Equal(trade.Size) && s.trades[idx].Time.Equal(trade.Time) {
+			s.trades = append(s.trades[:idx], s.trades[idx+1:]...)
+
+			return
+		}
+	}
+}
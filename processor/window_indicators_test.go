@@ -0,0 +1,72 @@
+package processor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aprln/vwap-engine/config"
+	"github.com/aprln/vwap-engine/model"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWindowIndicators_flow(t *testing.T) {
+	base := time.Date(2022, 11, 2, 14, 27, 0, 0, time.UTC)
+	trades := []model.Trade{
+		{Price: decimal.NewFromInt(10), Size: decimal.NewFromInt(1), Time: base},
+		{Price: decimal.NewFromInt(20), Size: decimal.NewFromInt(3), Time: base.Add(1 * time.Second)},
+		{Price: decimal.NewFromInt(15), Size: decimal.NewFromInt(1), Time: base.Add(4 * time.Second)},
+		{Price: decimal.NewFromInt(5), Size: decimal.NewFromInt(2), Time: base.Add(5 * time.Second)},
+	}
+
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{
+			name: IndicatorNameTradeCount,
+			want: []string{"1", "2", "3", "3"},
+		},
+		{
+			name: IndicatorNameNotional,
+			want: []string{"10", "70", "85", "85"},
+		},
+		{
+			// [10] -> [10 for 1s, 20] -> [10 for 1s, 20 for 3s, 15] -> [20 for 3s, 15 for 1s, 5]
+			name: IndicatorNameTWAP,
+			want: []string{"10", "10", "17.5", "18.75"},
+		},
+		{
+			name: IndicatorNameMedian,
+			want: []string{"10", "15", "15", "15"},
+		},
+		{
+			name: IndicatorNameVWMedian,
+			want: []string{"10", "20", "20", "15"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				ind, err := newIndicator(tt.name, config.VWAP{WindowSize: 3})
+				require.NoError(t, err)
+				require.Equal(t, "0", ind.Value().String(), "failed at init")
+
+				for i, trade := range trades {
+					require.NoError(t, ind.Update(trade))
+					require.Equalf(t, tt.want[i], ind.Value().String(), "failed at step %d", i)
+				}
+			},
+		)
+	}
+}
+
+func TestWindowIndicators_invalidWindowSize(t *testing.T) {
+	for _, name := range []string{
+		IndicatorNameTradeCount, IndicatorNameNotional, IndicatorNameTWAP, IndicatorNameMedian, IndicatorNameVWMedian,
+	} {
+		_, err := newIndicator(name, config.VWAP{})
+		require.Errorf(t, err, "indicator %s", name)
+	}
+}
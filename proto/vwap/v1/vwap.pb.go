@@ -0,0 +1,571 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.30.0
+// 	protoc        v23.4.0
+// source: vwap/v1/vwap.proto
+
+package vwapv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type SubscribeRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	TradingPairs []string `protobuf:"bytes,1,rep,name=trading_pairs,json=tradingPairs,proto3" json:"trading_pairs,omitempty"`
+}
+
+func (x *SubscribeRequest) Reset() {
+	*x = SubscribeRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_vwap_v1_vwap_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SubscribeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubscribeRequest) ProtoMessage() {}
+
+func (x *SubscribeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_vwap_v1_vwap_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubscribeRequest.ProtoReflect.Descriptor instead.
+func (*SubscribeRequest) Descriptor() ([]byte, []int) {
+	return file_vwap_v1_vwap_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *SubscribeRequest) GetTradingPairs() []string {
+	if x != nil {
+		return x.TradingPairs
+	}
+	return nil
+}
+
+type GetLatestRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	TradingPair string `protobuf:"bytes,1,opt,name=trading_pair,json=tradingPair,proto3" json:"trading_pair,omitempty"`
+}
+
+func (x *GetLatestRequest) Reset() {
+	*x = GetLatestRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_vwap_v1_vwap_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GetLatestRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetLatestRequest) ProtoMessage() {}
+
+func (x *GetLatestRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_vwap_v1_vwap_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetLatestRequest.ProtoReflect.Descriptor instead.
+func (*GetLatestRequest) Descriptor() ([]byte, []int) {
+	return file_vwap_v1_vwap_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *GetLatestRequest) GetTradingPair() string {
+	if x != nil {
+		return x.TradingPair
+	}
+	return ""
+}
+
+// VWAP is the VWAP result of a trading pair. Decimals are strings to keep their precision, as in the JSON results.
+type VWAP struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	TradingPair string                 `protobuf:"bytes,1,opt,name=trading_pair,json=tradingPair,proto3" json:"trading_pair,omitempty"`
+	LastTradeAt *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=last_trade_at,json=lastTradeAt,proto3" json:"last_trade_at,omitempty"`
+	Vwap        string                 `protobuf:"bytes,3,opt,name=vwap,proto3" json:"vwap,omitempty"`
+	// session_start is only set by anchored calculators.
+	SessionStart *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=session_start,json=sessionStart,proto3" json:"session_start,omitempty"`
+	// bands are only set when band multipliers are configured.
+	Bands []*Band `protobuf:"bytes,5,rep,name=bands,proto3" json:"bands,omitempty"`
+	// indicators holds the values of the extra indicators configured for the trading pair, keyed by name.
+	Indicators map[string]string `protobuf:"bytes,6,rep,name=indicators,proto3" json:"indicators,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	// window is only set by calculators with a trade window, i.e. the window, fixed and volume calculators.
+	Window *Window `protobuf:"bytes,7,opt,name=window,proto3" json:"window,omitempty"`
+}
+
+func (x *VWAP) Reset() {
+	*x = VWAP{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_vwap_v1_vwap_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *VWAP) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VWAP) ProtoMessage() {}
+
+func (x *VWAP) ProtoReflect() protoreflect.Message {
+	mi := &file_vwap_v1_vwap_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VWAP.ProtoReflect.Descriptor instead.
+func (*VWAP) Descriptor() ([]byte, []int) {
+	return file_vwap_v1_vwap_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *VWAP) GetTradingPair() string {
+	if x != nil {
+		return x.TradingPair
+	}
+	return ""
+}
+
+func (x *VWAP) GetLastTradeAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.LastTradeAt
+	}
+	return nil
+}
+
+func (x *VWAP) GetVwap() string {
+	if x != nil {
+		return x.Vwap
+	}
+	return ""
+}
+
+func (x *VWAP) GetSessionStart() *timestamppb.Timestamp {
+	if x != nil {
+		return x.SessionStart
+	}
+	return nil
+}
+
+func (x *VWAP) GetBands() []*Band {
+	if x != nil {
+		return x.Bands
+	}
+	return nil
+}
+
+func (x *VWAP) GetIndicators() map[string]string {
+	if x != nil {
+		return x.Indicators
+	}
+	return nil
+}
+
+func (x *VWAP) GetWindow() *Window {
+	if x != nil {
+		return x.Window
+	}
+	return nil
+}
+
+// Band is the VWAP ± multiplier·σ band, where σ is the volume-weighted standard deviation of price.
+type Band struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Multiplier string `protobuf:"bytes,1,opt,name=multiplier,proto3" json:"multiplier,omitempty"`
+	Upper      string `protobuf:"bytes,2,opt,name=upper,proto3" json:"upper,omitempty"`
+	Lower      string `protobuf:"bytes,3,opt,name=lower,proto3" json:"lower,omitempty"`
+}
+
+func (x *Band) Reset() {
+	*x = Band{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_vwap_v1_vwap_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Band) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Band) ProtoMessage() {}
+
+func (x *Band) ProtoReflect() protoreflect.Message {
+	mi := &file_vwap_v1_vwap_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Band.ProtoReflect.Descriptor instead.
+func (*Band) Descriptor() ([]byte, []int) {
+	return file_vwap_v1_vwap_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Band) GetMultiplier() string {
+	if x != nil {
+		return x.Multiplier
+	}
+	return ""
+}
+
+func (x *Band) GetUpper() string {
+	if x != nil {
+		return x.Upper
+	}
+	return ""
+}
+
+func (x *Band) GetLower() string {
+	if x != nil {
+		return x.Lower
+	}
+	return ""
+}
+
+// Window describes the trades the VWAP is calculated over.
+type Window struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	TradeCount    int64                  `protobuf:"varint,1,opt,name=trade_count,json=tradeCount,proto3" json:"trade_count,omitempty"`
+	Full          bool                   `protobuf:"varint,2,opt,name=full,proto3" json:"full,omitempty"`
+	Volume        string                 `protobuf:"bytes,3,opt,name=volume,proto3" json:"volume,omitempty"`
+	Notional      string                 `protobuf:"bytes,4,opt,name=notional,proto3" json:"notional,omitempty"`
+	OldestTradeAt *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=oldest_trade_at,json=oldestTradeAt,proto3" json:"oldest_trade_at,omitempty"`
+	NewestTradeAt *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=newest_trade_at,json=newestTradeAt,proto3" json:"newest_trade_at,omitempty"`
+}
+
+func (x *Window) Reset() {
+	*x = Window{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_vwap_v1_vwap_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Window) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Window) ProtoMessage() {}
+
+func (x *Window) ProtoReflect() protoreflect.Message {
+	mi := &file_vwap_v1_vwap_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Window.ProtoReflect.Descriptor instead.
+func (*Window) Descriptor() ([]byte, []int) {
+	return file_vwap_v1_vwap_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *Window) GetTradeCount() int64 {
+	if x != nil {
+		return x.TradeCount
+	}
+	return 0
+}
+
+func (x *Window) GetFull() bool {
+	if x != nil {
+		return x.Full
+	}
+	return false
+}
+
+func (x *Window) GetVolume() string {
+	if x != nil {
+		return x.Volume
+	}
+	return ""
+}
+
+func (x *Window) GetNotional() string {
+	if x != nil {
+		return x.Notional
+	}
+	return ""
+}
+
+func (x *Window) GetOldestTradeAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.OldestTradeAt
+	}
+	return nil
+}
+
+func (x *Window) GetNewestTradeAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.NewestTradeAt
+	}
+	return nil
+}
+
+var File_vwap_v1_vwap_proto protoreflect.FileDescriptor
+
+var file_vwap_v1_vwap_proto_rawDesc = []byte{
+	0x0a, 0x12, 0x76, 0x77, 0x61, 0x70, 0x2f, 0x76, 0x31, 0x2f, 0x76, 0x77, 0x61, 0x70, 0x2e, 0x70,
+	0x72, 0x6f, 0x74, 0x6f, 0x12, 0x07, 0x76, 0x77, 0x61, 0x70, 0x2e, 0x76, 0x31, 0x1a, 0x1f, 0x67,
+	0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2f, 0x74,
+	0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0x37,
+	0x0a, 0x10, 0x53, 0x75, 0x62, 0x73, 0x63, 0x72, 0x69, 0x62, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x12, 0x23, 0x0a, 0x0d, 0x74, 0x72, 0x61, 0x64, 0x69, 0x6e, 0x67, 0x5f, 0x70, 0x61,
+	0x69, 0x72, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x09, 0x52, 0x0c, 0x74, 0x72, 0x61, 0x64, 0x69,
+	0x6e, 0x67, 0x50, 0x61, 0x69, 0x72, 0x73, 0x22, 0x35, 0x0a, 0x10, 0x47, 0x65, 0x74, 0x4c, 0x61,
+	0x74, 0x65, 0x73, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x21, 0x0a, 0x0c, 0x74,
+	0x72, 0x61, 0x64, 0x69, 0x6e, 0x67, 0x5f, 0x70, 0x61, 0x69, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x0b, 0x74, 0x72, 0x61, 0x64, 0x69, 0x6e, 0x67, 0x50, 0x61, 0x69, 0x72, 0x22, 0x8a,
+	0x03, 0x0a, 0x04, 0x56, 0x57, 0x41, 0x50, 0x12, 0x21, 0x0a, 0x0c, 0x74, 0x72, 0x61, 0x64, 0x69,
+	0x6e, 0x67, 0x5f, 0x70, 0x61, 0x69, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x74,
+	0x72, 0x61, 0x64, 0x69, 0x6e, 0x67, 0x50, 0x61, 0x69, 0x72, 0x12, 0x3e, 0x0a, 0x0d, 0x6c, 0x61,
+	0x73, 0x74, 0x5f, 0x74, 0x72, 0x61, 0x64, 0x65, 0x5f, 0x61, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x0b, 0x32, 0x1a, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x62, 0x75, 0x66, 0x2e, 0x54, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x52, 0x0b, 0x6c,
+	0x61, 0x73, 0x74, 0x54, 0x72, 0x61, 0x64, 0x65, 0x41, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x76, 0x77,
+	0x61, 0x70, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x76, 0x77, 0x61, 0x70, 0x12, 0x3f,
+	0x0a, 0x0d, 0x73, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x5f, 0x73, 0x74, 0x61, 0x72, 0x74, 0x18,
+	0x04, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70,
+	0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x54, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d,
+	0x70, 0x52, 0x0c, 0x73, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x53, 0x74, 0x61, 0x72, 0x74, 0x12,
+	0x23, 0x0a, 0x05, 0x62, 0x61, 0x6e, 0x64, 0x73, 0x18, 0x05, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x0d,
+	0x2e, 0x76, 0x77, 0x61, 0x70, 0x2e, 0x76, 0x31, 0x2e, 0x42, 0x61, 0x6e, 0x64, 0x52, 0x05, 0x62,
+	0x61, 0x6e, 0x64, 0x73, 0x12, 0x3d, 0x0a, 0x0a, 0x69, 0x6e, 0x64, 0x69, 0x63, 0x61, 0x74, 0x6f,
+	0x72, 0x73, 0x18, 0x06, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1d, 0x2e, 0x76, 0x77, 0x61, 0x70, 0x2e,
+	0x76, 0x31, 0x2e, 0x56, 0x57, 0x41, 0x50, 0x2e, 0x49, 0x6e, 0x64, 0x69, 0x63, 0x61, 0x74, 0x6f,
+	0x72, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x0a, 0x69, 0x6e, 0x64, 0x69, 0x63, 0x61, 0x74,
+	0x6f, 0x72, 0x73, 0x12, 0x27, 0x0a, 0x06, 0x77, 0x69, 0x6e, 0x64, 0x6f, 0x77, 0x18, 0x07, 0x20,
+	0x01, 0x28, 0x0b, 0x32, 0x0f, 0x2e, 0x76, 0x77, 0x61, 0x70, 0x2e, 0x76, 0x31, 0x2e, 0x57, 0x69,
+	0x6e, 0x64, 0x6f, 0x77, 0x52, 0x06, 0x77, 0x69, 0x6e, 0x64, 0x6f, 0x77, 0x1a, 0x3d, 0x0a, 0x0f,
+	0x49, 0x6e, 0x64, 0x69, 0x63, 0x61, 0x74, 0x6f, 0x72, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12,
+	0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65,
+	0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x22, 0x52, 0x0a, 0x04, 0x42,
+	0x61, 0x6e, 0x64, 0x12, 0x1e, 0x0a, 0x0a, 0x6d, 0x75, 0x6c, 0x74, 0x69, 0x70, 0x6c, 0x69, 0x65,
+	0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x6d, 0x75, 0x6c, 0x74, 0x69, 0x70, 0x6c,
+	0x69, 0x65, 0x72, 0x12, 0x14, 0x0a, 0x05, 0x75, 0x70, 0x70, 0x65, 0x72, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x05, 0x75, 0x70, 0x70, 0x65, 0x72, 0x12, 0x14, 0x0a, 0x05, 0x6c, 0x6f, 0x77,
+	0x65, 0x72, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x6c, 0x6f, 0x77, 0x65, 0x72, 0x22,
+	0xf9, 0x01, 0x0a, 0x06, 0x57, 0x69, 0x6e, 0x64, 0x6f, 0x77, 0x12, 0x1f, 0x0a, 0x0b, 0x74, 0x72,
+	0x61, 0x64, 0x65, 0x5f, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52,
+	0x0a, 0x74, 0x72, 0x61, 0x64, 0x65, 0x43, 0x6f, 0x75, 0x6e, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x66,
+	0x75, 0x6c, 0x6c, 0x18, 0x02, 0x20, 0x01, 0x28, 0x08, 0x52, 0x04, 0x66, 0x75, 0x6c, 0x6c, 0x12,
+	0x16, 0x0a, 0x06, 0x76, 0x6f, 0x6c, 0x75, 0x6d, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x06, 0x76, 0x6f, 0x6c, 0x75, 0x6d, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x6e, 0x6f, 0x74, 0x69, 0x6f,
+	0x6e, 0x61, 0x6c, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x6e, 0x6f, 0x74, 0x69, 0x6f,
+	0x6e, 0x61, 0x6c, 0x12, 0x42, 0x0a, 0x0f, 0x6f, 0x6c, 0x64, 0x65, 0x73, 0x74, 0x5f, 0x74, 0x72,
+	0x61, 0x64, 0x65, 0x5f, 0x61, 0x74, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x67,
+	0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x54,
+	0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x52, 0x0d, 0x6f, 0x6c, 0x64, 0x65, 0x73, 0x74,
+	0x54, 0x72, 0x61, 0x64, 0x65, 0x41, 0x74, 0x12, 0x42, 0x0a, 0x0f, 0x6e, 0x65, 0x77, 0x65, 0x73,
+	0x74, 0x5f, 0x74, 0x72, 0x61, 0x64, 0x65, 0x5f, 0x61, 0x74, 0x18, 0x06, 0x20, 0x01, 0x28, 0x0b,
+	0x32, 0x1a, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62,
+	0x75, 0x66, 0x2e, 0x54, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x52, 0x0d, 0x6e, 0x65,
+	0x77, 0x65, 0x73, 0x74, 0x54, 0x72, 0x61, 0x64, 0x65, 0x41, 0x74, 0x32, 0x7d, 0x0a, 0x0b, 0x56,
+	0x57, 0x41, 0x50, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x12, 0x37, 0x0a, 0x09, 0x53, 0x75,
+	0x62, 0x73, 0x63, 0x72, 0x69, 0x62, 0x65, 0x12, 0x19, 0x2e, 0x76, 0x77, 0x61, 0x70, 0x2e, 0x76,
+	0x31, 0x2e, 0x53, 0x75, 0x62, 0x73, 0x63, 0x72, 0x69, 0x62, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x1a, 0x0d, 0x2e, 0x76, 0x77, 0x61, 0x70, 0x2e, 0x76, 0x31, 0x2e, 0x56, 0x57, 0x41,
+	0x50, 0x30, 0x01, 0x12, 0x35, 0x0a, 0x09, 0x47, 0x65, 0x74, 0x4c, 0x61, 0x74, 0x65, 0x73, 0x74,
+	0x12, 0x19, 0x2e, 0x76, 0x77, 0x61, 0x70, 0x2e, 0x76, 0x31, 0x2e, 0x47, 0x65, 0x74, 0x4c, 0x61,
+	0x74, 0x65, 0x73, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x0d, 0x2e, 0x76, 0x77,
+	0x61, 0x70, 0x2e, 0x76, 0x31, 0x2e, 0x56, 0x57, 0x41, 0x50, 0x42, 0x48, 0x0a, 0x11, 0x63, 0x6f,
+	0x6d, 0x2e, 0x61, 0x70, 0x72, 0x6c, 0x6e, 0x2e, 0x76, 0x77, 0x61, 0x70, 0x2e, 0x76, 0x31, 0x50,
+	0x01, 0x5a, 0x31, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x61, 0x70,
+	0x72, 0x6c, 0x6e, 0x2f, 0x76, 0x77, 0x61, 0x70, 0x2d, 0x65, 0x6e, 0x67, 0x69, 0x6e, 0x65, 0x2f,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x76, 0x77, 0x61, 0x70, 0x2f, 0x76, 0x31, 0x3b, 0x76, 0x77,
+	0x61, 0x70, 0x76, 0x31, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_vwap_v1_vwap_proto_rawDescOnce sync.Once
+	file_vwap_v1_vwap_proto_rawDescData = file_vwap_v1_vwap_proto_rawDesc
+)
+
+func file_vwap_v1_vwap_proto_rawDescGZIP() []byte {
+	file_vwap_v1_vwap_proto_rawDescOnce.Do(func() {
+		file_vwap_v1_vwap_proto_rawDescData = protoimpl.X.CompressGZIP(file_vwap_v1_vwap_proto_rawDescData)
+	})
+	return file_vwap_v1_vwap_proto_rawDescData
+}
+
+var file_vwap_v1_vwap_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
+var file_vwap_v1_vwap_proto_goTypes = []interface{}{
+	(*SubscribeRequest)(nil),      // 0: vwap.v1.SubscribeRequest
+	(*GetLatestRequest)(nil),      // 1: vwap.v1.GetLatestRequest
+	(*VWAP)(nil),                  // 2: vwap.v1.VWAP
+	(*Band)(nil),                  // 3: vwap.v1.Band
+	(*Window)(nil),                // 4: vwap.v1.Window
+	nil,                           // 5: vwap.v1.VWAP.IndicatorsEntry
+	(*timestamppb.Timestamp)(nil), // 6: google.protobuf.Timestamp
+}
+var file_vwap_v1_vwap_proto_depIdxs = []int32{
+	6, // 0: vwap.v1.VWAP.last_trade_at:type_name -> google.protobuf.Timestamp
+	6, // 1: vwap.v1.VWAP.session_start:type_name -> google.protobuf.Timestamp
+	3, // 2: vwap.v1.VWAP.bands:type_name -> vwap.v1.Band
+	5, // 3: vwap.v1.VWAP.indicators:type_name -> vwap.v1.VWAP.IndicatorsEntry
+	4, // 4: vwap.v1.VWAP.window:type_name -> vwap.v1.Window
+	6, // 5: vwap.v1.Window.oldest_trade_at:type_name -> google.protobuf.Timestamp
+	6, // 6: vwap.v1.Window.newest_trade_at:type_name -> google.protobuf.Timestamp
+	0, // 7: vwap.v1.VWAPService.Subscribe:input_type -> vwap.v1.SubscribeRequest
+	1, // 8: vwap.v1.VWAPService.GetLatest:input_type -> vwap.v1.GetLatestRequest
+	2, // 9: vwap.v1.VWAPService.Subscribe:output_type -> vwap.v1.VWAP
+	2, // 10: vwap.v1.VWAPService.GetLatest:output_type -> vwap.v1.VWAP
+	9, // [9:11] is the sub-list for method output_type
+	7, // [7:9] is the sub-list for method input_type
+	7, // [7:7] is the sub-list for extension type_name
+	7, // [7:7] is the sub-list for extension extendee
+	0, // [0:7] is the sub-list for field type_name
+}
+
+func init() { file_vwap_v1_vwap_proto_init() }
+func file_vwap_v1_vwap_proto_init() {
+	if File_vwap_v1_vwap_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_vwap_v1_vwap_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SubscribeRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_vwap_v1_vwap_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetLatestRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_vwap_v1_vwap_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*VWAP); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_vwap_v1_vwap_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Band); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_vwap_v1_vwap_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Window); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_vwap_v1_vwap_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   6,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_vwap_v1_vwap_proto_goTypes,
+		DependencyIndexes: file_vwap_v1_vwap_proto_depIdxs,
+		MessageInfos:      file_vwap_v1_vwap_proto_msgTypes,
+	}.Build()
+	File_vwap_v1_vwap_proto = out.File
+	file_vwap_v1_vwap_proto_rawDesc = nil
+	file_vwap_v1_vwap_proto_goTypes = nil
+	file_vwap_v1_vwap_proto_depIdxs = nil
+}
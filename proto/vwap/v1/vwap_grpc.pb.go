@@ -0,0 +1,184 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             v23.4.0
+// source: vwap/v1/vwap.proto
+
+package vwapv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	VWAPService_Subscribe_FullMethodName = "/vwap.v1.VWAPService/Subscribe"
+	VWAPService_GetLatest_FullMethodName = "/vwap.v1.VWAPService/GetLatest"
+)
+
+// VWAPServiceClient is the client API for VWAPService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type VWAPServiceClient interface {
+	// Subscribe streams the VWAP results of the trading pairs, every trading pair without trading pairs.
+	// The latest VWAP of each trading pair is sent first. A subscriber lagging too far behind is ended with
+	// RESOURCE_EXHAUSTED.
+	Subscribe(ctx context.Context, in *SubscribeRequest, opts ...grpc.CallOption) (VWAPService_SubscribeClient, error)
+	// GetLatest returns the latest VWAP of the trading pair, NOT_FOUND if there is none yet,
+	// or UNAVAILABLE while its window is not full.
+	GetLatest(ctx context.Context, in *GetLatestRequest, opts ...grpc.CallOption) (*VWAP, error)
+}
+
+type vWAPServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewVWAPServiceClient(cc grpc.ClientConnInterface) VWAPServiceClient {
+	return &vWAPServiceClient{cc}
+}
+
+func (c *vWAPServiceClient) Subscribe(ctx context.Context, in *SubscribeRequest, opts ...grpc.CallOption) (VWAPService_SubscribeClient, error) {
+	stream, err := c.cc.NewStream(ctx, &VWAPService_ServiceDesc.Streams[0], VWAPService_Subscribe_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &vWAPServiceSubscribeClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type VWAPService_SubscribeClient interface {
+	Recv() (*VWAP, error)
+	grpc.ClientStream
+}
+
+type vWAPServiceSubscribeClient struct {
+	grpc.ClientStream
+}
+
+func (x *vWAPServiceSubscribeClient) Recv() (*VWAP, error) {
+	m := new(VWAP)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *vWAPServiceClient) GetLatest(ctx context.Context, in *GetLatestRequest, opts ...grpc.CallOption) (*VWAP, error) {
+	out := new(VWAP)
+	err := c.cc.Invoke(ctx, VWAPService_GetLatest_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// VWAPServiceServer is the server API for VWAPService service.
+// All implementations must embed UnimplementedVWAPServiceServer
+// for forward compatibility
+type VWAPServiceServer interface {
+	// Subscribe streams the VWAP results of the trading pairs, every trading pair without trading pairs.
+	// The latest VWAP of each trading pair is sent first. A subscriber lagging too far behind is ended with
+	// RESOURCE_EXHAUSTED.
+	Subscribe(*SubscribeRequest, VWAPService_SubscribeServer) error
+	// GetLatest returns the latest VWAP of the trading pair, NOT_FOUND if there is none yet,
+	// or UNAVAILABLE while its window is not full.
+	GetLatest(context.Context, *GetLatestRequest) (*VWAP, error)
+	mustEmbedUnimplementedVWAPServiceServer()
+}
+
+// UnimplementedVWAPServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedVWAPServiceServer struct {
+}
+
+func (UnimplementedVWAPServiceServer) Subscribe(*SubscribeRequest, VWAPService_SubscribeServer) error {
+	return status.Errorf(codes.Unimplemented, "method Subscribe not implemented")
+}
+func (UnimplementedVWAPServiceServer) GetLatest(context.Context, *GetLatestRequest) (*VWAP, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetLatest not implemented")
+}
+func (UnimplementedVWAPServiceServer) mustEmbedUnimplementedVWAPServiceServer() {}
+
+// UnsafeVWAPServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to VWAPServiceServer will
+// result in compilation errors.
+type UnsafeVWAPServiceServer interface {
+	mustEmbedUnimplementedVWAPServiceServer()
+}
+
+func RegisterVWAPServiceServer(s grpc.ServiceRegistrar, srv VWAPServiceServer) {
+	s.RegisterService(&VWAPService_ServiceDesc, srv)
+}
+
+func _VWAPService_Subscribe_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SubscribeRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(VWAPServiceServer).Subscribe(m, &vWAPServiceSubscribeServer{stream})
+}
+
+type VWAPService_SubscribeServer interface {
+	Send(*VWAP) error
+	grpc.ServerStream
+}
+
+type vWAPServiceSubscribeServer struct {
+	grpc.ServerStream
+}
+
+func (x *vWAPServiceSubscribeServer) Send(m *VWAP) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _VWAPService_GetLatest_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetLatestRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VWAPServiceServer).GetLatest(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: VWAPService_GetLatest_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VWAPServiceServer).GetLatest(ctx, req.(*GetLatestRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// VWAPService_ServiceDesc is the grpc.ServiceDesc for VWAPService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var VWAPService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "vwap.v1.VWAPService",
+	HandlerType: (*VWAPServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetLatest",
+			Handler:    _VWAPService_GetLatest_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Subscribe",
+			Handler:       _VWAPService_Subscribe_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "vwap/v1/vwap.proto",
+}
@@ -0,0 +1,217 @@
+package publisher
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/aprln/vwap-engine/config"
+)
+
+// deadLetterBackupExt is appended to the path of the dead letter file once it is full
+const deadLetterBackupExt = ".1"
+
+var errDeadLetterClosed = errors.New("dead letter file is closed")
+
+// SetUpDeadLetter opens the configured dead letter file, or returns nil if there is none.
+func SetUpDeadLetter(sinkCfg config.Sink) (*DeadLetter, error) {
+	if sinkCfg.DeadLetterFile == "" {
+		return nil, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(sinkCfg.DeadLetterFile), 0o755); err != nil {
+		return nil, err
+	}
+
+	return NewDeadLetter(sinkCfg.DeadLetterFile, int64(sinkCfg.DeadLetterMaxSize), sinkCfg.QueueSize, time.Now)
+}
+
+// NewDeadLetter opens the dead letter file at path, which is rotated once it would exceed maxSize bytes,
+// 0 meaning no limit. Up to queueSize entries wait to be written.
+func NewDeadLetter(path string, maxSize int64, queueSize int, now func() time.Time) (*DeadLetter, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, filePermission)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+
+		return nil, err
+	}
+
+	d := &DeadLetter{
+		path:    path,
+		maxSize: maxSize,
+		now:     now,
+		file:    file,
+		size:    info.Size(),
+		queue:   make(chan []byte, queueSize),
+		done:    make(chan struct{}),
+	}
+
+	go d.writeForever()
+
+	return d, nil
+}
+
+// DeadLetter appends the messages the sinks could not send to a JSON lines file, along with the sink and the error.
+//
+// The lines are written by its own goroutine, so a broken sink dead-lettering every message never waits for the
+// disk. The entries not fitting in its queue are dropped and counted, see Dropped. Once the file would exceed the
+// max size, it is renamed to <path>.1, replacing the previous one, so at most twice the max size is kept.
+// It is safe for concurrent use.
+type DeadLetter struct {
+	path    string
+	maxSize int64
+	now     func() time.Time
+	done    chan struct{}
+	// file and size are only used by the writing goroutine, and by Close once it is done
+	file *os.File
+	size int64
+	// writeErr is the first error of the writing goroutine, returned by Close
+	writeErr error
+
+	mu sync.Mutex
+	// queue is only written to and closed with the lock held
+	queue   chan []byte
+	closed  bool
+	dropped int
+	// full is true from an entry not fitting in the queue until the next one fits, to log it once
+	full bool
+}
+
+// deadLetterEntry is a line of the dead letter file. A JSON message is kept as is in Message, any other message is
+// base64 encoded in MessageBase64.
+type deadLetterEntry struct {
+	Sink          string          `json:"sink"`
+	Error         string          `json:"error"`
+	FailedAt      time.Time       `json:"failed_at"`
+	Message       json.RawMessage `json:"message,omitempty"`
+	MessageBase64 []byte          `json:"message_base64,omitempty"`
+}
+
+// Write queues the entry of the message without blocking. It fails once the dead letter file is closed.
+func (d *DeadLetter) Write(sink string, msg []byte, sendErr error) error {
+	entry := deadLetterEntry{Sink: sink, Error: sendErr.Error(), FailedAt: d.now()}
+	if json.Valid(msg) {
+		entry.Message = msg
+	} else {
+		entry.MessageBase64 = msg
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.closed {
+		return errDeadLetterClosed
+	}
+
+	select {
+	case d.queue <- append(line, '\n'):
+		d.full = false
+	default:
+		if !d.full {
+			log.Printf("dead letter queue is full, dropping the entries until it has room")
+			d.full = true
+		}
+		d.dropped++
+	}
+
+	return nil
+}
+
+// Dropped returns the number of entries dropped as the queue was full.
+func (d *DeadLetter) Dropped() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.dropped
+}
+
+// Close writes the queued entries and closes the file.
+func (d *DeadLetter) Close() error {
+	d.mu.Lock()
+	if !d.closed {
+		d.closed = true
+		close(d.queue)
+	}
+	dropped := d.dropped
+	d.mu.Unlock()
+
+	<-d.done
+
+	if dropped > 0 {
+		log.Printf("dead letter queue dropped %d entries", dropped)
+	}
+
+	if err := d.file.Sync(); err != nil {
+		_ = d.file.Close()
+
+		return err
+	}
+	if err := d.file.Close(); err != nil {
+		return err
+	}
+
+	return d.writeErr
+}
+
+func (d *DeadLetter) writeForever() {
+	defer close(d.done)
+
+	for line := range d.queue {
+		if err := d.write(line); err != nil {
+			log.Printf("failed to dead-letter a message: %v", err)
+			if d.writeErr == nil {
+				d.writeErr = err
+			}
+		}
+	}
+}
+
+func (d *DeadLetter) write(line []byte) error {
+	if d.maxSize > 0 && d.size > 0 && d.size+int64(len(line)) > d.maxSize {
+		if err := d.rotate(); err != nil {
+			return fmt.Errorf("dead letter: %w", err)
+		}
+	}
+
+	n, err := d.file.Write(line)
+	d.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("dead letter: %w", err)
+	}
+
+	return nil
+}
+
+// rotate renames the file to its backup and opens a new one. The file is reopened even if it cannot be closed
+// or renamed, so the next entries are still written.
+func (d *DeadLetter) rotate() error {
+	closeErr := d.file.Close()
+	renameErr := os.Rename(d.path, d.path+deadLetterBackupExt)
+
+	file, err := os.OpenFile(d.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, filePermission)
+	if err != nil {
+		return err
+	}
+	d.file = file
+	if renameErr != nil {
+		return renameErr
+	}
+	d.size = 0
+
+	return closeErr
+}
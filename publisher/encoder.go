@@ -0,0 +1,337 @@
+package publisher
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/aprln/vwap-engine/config"
+	"github.com/aprln/vwap-engine/model"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+// CSVHeader lists the columns of the CSV encoding. Bands and indicators are only in the other encodings.
+const CSVHeader = "trading_pair,last_trade_at,vwap,session_start,trade_count,window_full,volume,notional"
+
+// Encoder encodes the VWAP results. It runs once per published result, see the benchmarks of the encoders.
+type Encoder interface {
+	Encode(vwap model.VWAP) ([]byte, error)
+}
+
+func NewEncoder(encoding config.Encoding) (Encoder, error) {
+	switch encoding {
+	case config.EncodingJSON:
+		return jsonEncoder{}, nil
+	case config.EncodingJSONNumbers:
+		return jsonNumbersEncoder{}, nil
+	case config.EncodingCSV:
+		return csvEncoder{}, nil
+	case config.EncodingMsgpack:
+		return msgpackEncoder{}, nil
+	case config.EncodingProtobuf:
+		return protobufEncoder{}, nil
+	default:
+		return nil, fmt.Errorf(`output encoding "%s" is unsupported`, encoding)
+	}
+}
+
+// jsonEncoder encodes the results as JSON, with decimals as strings to keep their precision.
+type jsonEncoder struct{}
+
+func (jsonEncoder) Encode(vwap model.VWAP) ([]byte, error) {
+	return json.Marshal(vwap)
+}
+
+// jsonNumbersEncoder encodes the results as JSON with decimals as numbers, for consumers parsing them as floats.
+// The fields are the same as the jsonEncoder's.
+type jsonNumbersEncoder struct{}
+
+type vwapNumbers struct {
+	TradingPair  string                 `json:"trading_pair"`
+	LastTradeAt  time.Time              `json:"last_trade_at"`
+	VWAP         json.Number            `json:"vwap"`
+	SessionStart *time.Time             `json:"session_start,omitempty"`
+	Bands        []bandNumbers          `json:"bands,omitempty"`
+	Indicators   map[string]json.Number `json:"indicators,omitempty"`
+	Window       *windowNumbers         `json:"window,omitempty"`
+}
+
+type bandNumbers struct {
+	Multiplier json.Number `json:"multiplier"`
+	Upper      json.Number `json:"upper"`
+	Lower      json.Number `json:"lower"`
+}
+
+type windowNumbers struct {
+	TradeCount    int         `json:"trade_count"`
+	Full          bool        `json:"full"`
+	Volume        json.Number `json:"volume"`
+	Notional      json.Number `json:"notional"`
+	OldestTradeAt time.Time   `json:"oldest_trade_at"`
+	NewestTradeAt time.Time   `json:"newest_trade_at"`
+}
+
+func (jsonNumbersEncoder) Encode(vwap model.VWAP) ([]byte, error) {
+	v := vwapNumbers{
+		TradingPair:  vwap.TradingPair,
+		LastTradeAt:  vwap.LastTradeAt,
+		VWAP:         json.Number(vwap.VWAP.String()),
+		SessionStart: vwap.SessionStart,
+	}
+
+	if len(vwap.Bands) > 0 {
+		v.Bands = make([]bandNumbers, len(vwap.Bands))
+		for i, band := range vwap.Bands {
+			v.Bands[i] = bandNumbers{
+				Multiplier: json.Number(band.Multiplier.String()),
+				Upper:      json.Number(band.Upper.String()),
+				Lower:      json.Number(band.Lower.String()),
+			}
+		}
+	}
+
+	if len(vwap.Indicators) > 0 {
+		v.Indicators = make(map[string]json.Number, len(vwap.Indicators))
+		for name, value := range vwap.Indicators {
+			v.Indicators[name] = json.Number(value.String())
+		}
+	}
+
+	if vwap.Window != nil {
+		v.Window = &windowNumbers{
+			TradeCount:    vwap.Window.TradeCount,
+			Full:          vwap.Window.Full,
+			Volume:        json.Number(vwap.Window.Volume.String()),
+			Notional:      json.Number(vwap.Window.Notional.String()),
+			OldestTradeAt: vwap.Window.OldestTradeAt,
+			NewestTradeAt: vwap.Window.NewestTradeAt,
+		}
+	}
+
+	return json.Marshal(v)
+}
+
+// csvEncoder encodes the results as a CSV row without line terminator, see CSVHeader.
+// The columns of a missing session start or window are empty.
+type csvEncoder struct{}
+
+func (csvEncoder) Encode(vwap model.VWAP) ([]byte, error) {
+	row := make([]string, 0, 8)
+	row = append(row, vwap.TradingPair, vwap.LastTradeAt.Format(time.RFC3339Nano), vwap.VWAP.String())
+
+	if vwap.SessionStart != nil {
+		row = append(row, vwap.SessionStart.Format(time.RFC3339Nano))
+	} else {
+		row = append(row, "")
+	}
+
+	if w := vwap.Window; w != nil {
+		row = append(row, strconv.Itoa(w.TradeCount), strconv.FormatBool(w.Full), w.Volume.String(), w.Notional.String())
+	} else {
+		row = append(row, "", "", "", "")
+	}
+
+	var buf bytes.Buffer
+	cw := csv.NewWriter(&buf)
+	if err := cw.Write(row); err != nil {
+		return nil, err
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return nil, err
+	}
+
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// msgpackEncoder encodes the results as a MessagePack map with the same keys as the JSON encoding.
+// Decimals are strings to keep their precision, and times use the MessagePack timestamp extension.
+type msgpackEncoder struct{}
+
+func (msgpackEncoder) Encode(vwap model.VWAP) ([]byte, error) {
+	fields := 3
+	if vwap.SessionStart != nil {
+		fields++
+	}
+	if len(vwap.Bands) > 0 {
+		fields++
+	}
+	if len(vwap.Indicators) > 0 {
+		fields++
+	}
+	if vwap.Window != nil {
+		fields++
+	}
+
+	b := make([]byte, 0, 128)
+	b = appendMsgpackMapHeader(b, fields)
+	b = appendMsgpackString(b, "trading_pair")
+	b = appendMsgpackString(b, vwap.TradingPair)
+	b = appendMsgpackString(b, "last_trade_at")
+	b = appendMsgpackTime(b, vwap.LastTradeAt)
+	b = appendMsgpackString(b, "vwap")
+	b = appendMsgpackString(b, vwap.VWAP.String())
+
+	if vwap.SessionStart != nil {
+		b = appendMsgpackString(b, "session_start")
+		b = appendMsgpackTime(b, *vwap.SessionStart)
+	}
+
+	if len(vwap.Bands) > 0 {
+		b = appendMsgpackString(b, "bands")
+		b = appendMsgpackArrayHeader(b, len(vwap.Bands))
+		for _, band := range vwap.Bands {
+			b = appendMsgpackMapHeader(b, 3)
+			b = appendMsgpackString(b, "multiplier")
+			b = appendMsgpackString(b, band.Multiplier.String())
+			b = appendMsgpackString(b, "upper")
+			b = appendMsgpackString(b, band.Upper.String())
+			b = appendMsgpackString(b, "lower")
+			b = appendMsgpackString(b, band.Lower.String())
+		}
+	}
+
+	if len(vwap.Indicators) > 0 {
+		// the names are sorted like encoding/json does, so equal results are encoded the same
+		names := make([]string, 0, len(vwap.Indicators))
+		for name := range vwap.Indicators {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		b = appendMsgpackString(b, "indicators")
+		b = appendMsgpackMapHeader(b, len(names))
+		for _, name := range names {
+			b = appendMsgpackString(b, name)
+			b = appendMsgpackString(b, vwap.Indicators[name].String())
+		}
+	}
+
+	if w := vwap.Window; w != nil {
+		b = appendMsgpackString(b, "window")
+		b = appendMsgpackMapHeader(b, 6)
+		b = appendMsgpackString(b, "trade_count")
+		b = appendMsgpackInt(b, int64(w.TradeCount))
+		b = appendMsgpackString(b, "full")
+		b = appendMsgpackBool(b, w.Full)
+		b = appendMsgpackString(b, "volume")
+		b = appendMsgpackString(b, w.Volume.String())
+		b = appendMsgpackString(b, "notional")
+		b = appendMsgpackString(b, w.Notional.String())
+		b = appendMsgpackString(b, "oldest_trade_at")
+		b = appendMsgpackTime(b, w.OldestTradeAt)
+		b = appendMsgpackString(b, "newest_trade_at")
+		b = appendMsgpackTime(b, w.NewestTradeAt)
+	}
+
+	return b, nil
+}
+
+func appendMsgpackMapHeader(b []byte, n int) []byte {
+	switch {
+	case n < 16:
+		return append(b, 0x80|byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, 0xde), uint16(n))
+	default:
+		return binary.BigEndian.AppendUint32(append(b, 0xdf), uint32(n))
+	}
+}
+
+func appendMsgpackArrayHeader(b []byte, n int) []byte {
+	switch {
+	case n < 16:
+		return append(b, 0x90|byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, 0xdc), uint16(n))
+	default:
+		return binary.BigEndian.AppendUint32(append(b, 0xdd), uint32(n))
+	}
+}
+
+func appendMsgpackString(b []byte, s string) []byte {
+	n := len(s)
+	switch {
+	case n < 32:
+		b = append(b, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		b = append(b, 0xd9, byte(n))
+	case n <= math.MaxUint16:
+		b = binary.BigEndian.AppendUint16(append(b, 0xda), uint16(n))
+	default:
+		b = binary.BigEndian.AppendUint32(append(b, 0xdb), uint32(n))
+	}
+
+	return append(b, s...)
+}
+
+func appendMsgpackBool(b []byte, v bool) []byte {
+	if v {
+		return append(b, 0xc3)
+	}
+
+	return append(b, 0xc2)
+}
+
+// appendMsgpackInt appends the integer in its smallest representation.
+func appendMsgpackInt(b []byte, v int64) []byte {
+	switch {
+	case v >= 0 && v <= math.MaxInt8:
+		return append(b, byte(v))
+	case v >= -32 && v < 0:
+		return append(b, byte(v))
+	case v >= 0 && v <= math.MaxUint8:
+		return append(b, 0xcc, byte(v))
+	case v >= 0 && v <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, 0xcd), uint16(v))
+	case v >= 0 && v <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(b, 0xce), uint32(v))
+	case v >= 0:
+		return binary.BigEndian.AppendUint64(append(b, 0xcf), uint64(v))
+	case v >= math.MinInt8:
+		return append(b, 0xd0, byte(v))
+	case v >= math.MinInt16:
+		return binary.BigEndian.AppendUint16(append(b, 0xd1), uint16(v))
+	case v >= math.MinInt32:
+		return binary.BigEndian.AppendUint32(append(b, 0xd2), uint32(v))
+	default:
+		return binary.BigEndian.AppendUint64(append(b, 0xd3), uint64(v))
+	}
+}
+
+// appendMsgpackTime appends the time as a timestamp extension (type -1), in its smallest format.
+func appendMsgpackTime(b []byte, t time.Time) []byte {
+	sec, nsec := t.Unix(), uint64(t.Nanosecond())
+	if sec >= 0 && sec>>34 == 0 {
+		if nsec == 0 && sec <= math.MaxUint32 {
+			return binary.BigEndian.AppendUint32(append(b, 0xd6, 0xff), uint32(sec))
+		}
+
+		return binary.BigEndian.AppendUint64(append(b, 0xd7, 0xff), nsec<<34|uint64(sec))
+	}
+
+	b = binary.BigEndian.AppendUint32(append(b, 0xc7, 12, 0xff), uint32(nsec))
+
+	return binary.BigEndian.AppendUint64(b, uint64(sec))
+}
+
+// protobufEncoder encodes the results as vwap.v1.VWAP messages prefixed with their varint encoded length,
+// i.e. Java's parseDelimitedFrom and Go's protodelim framing.
+type protobufEncoder struct{}
+
+func (protobufEncoder) Encode(vwap model.VWAP) ([]byte, error) {
+	pb := toProtoVWAP(vwap)
+	size := proto.Size(pb)
+
+	b := make([]byte, 0, protowire.SizeVarint(uint64(size))+size)
+	b = protowire.AppendVarint(b, uint64(size))
+
+	return proto.MarshalOptions{}.MarshalAppend(b, pb)
+}
@@ -0,0 +1,248 @@
+package publisher
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aprln/vwap-engine/config"
+	"github.com/aprln/vwap-engine/model"
+	vwapv1 "github.com/aprln/vwap-engine/proto/vwap/v1"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protodelim"
+	"google.golang.org/protobuf/proto"
+)
+
+var (
+	encodedLastTradeAt  = time.Date(2022, 1, 1, 0, 0, 30, 500_000_000, time.UTC)
+	encodedSessionStart = time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+)
+
+// fullVWAP returns a VWAP result with every optional field set.
+func fullVWAP() model.VWAP {
+	return model.VWAP{
+		TradingPair:  "BTC-USD",
+		LastTradeAt:  encodedLastTradeAt,
+		VWAP:         decimal.RequireFromString("20433.31"),
+		SessionStart: &encodedSessionStart,
+		Bands: []model.Band{
+			{
+				Multiplier: decimal.NewFromInt(2),
+				Upper:      decimal.RequireFromString("20500.5"),
+				Lower:      decimal.RequireFromString("20366.12"),
+			},
+		},
+		Indicators: map[string]decimal.Decimal{"twap": decimal.RequireFromString("20400.1")},
+		Window: &model.Window{
+			TradeCount:    3,
+			Full:          true,
+			Volume:        decimal.RequireFromString("1.5"),
+			Notional:      decimal.RequireFromString("30649.965"),
+			OldestTradeAt: encodedSessionStart,
+			NewestTradeAt: encodedLastTradeAt,
+		},
+	}
+}
+
+func TestNewEncoder(t *testing.T) {
+	for _, encoding := range []config.Encoding{
+		config.EncodingJSON, config.EncodingJSONNumbers, config.EncodingCSV, config.EncodingMsgpack,
+		config.EncodingProtobuf,
+	} {
+		_, err := NewEncoder(encoding)
+		assert.NoError(t, err, encoding)
+	}
+
+	_, err := NewEncoder("xml")
+	assert.Error(t, err)
+}
+
+func TestJSONNumbersEncoder(t *testing.T) {
+	got, err := jsonNumbersEncoder{}.Encode(fullVWAP())
+	require.NoError(t, err)
+	assert.JSONEq(
+		t, `{"trading_pair":"BTC-USD","last_trade_at":"2022-01-01T00:00:30.5Z","vwap":20433.31,
+			"session_start":"2022-01-01T00:00:00Z",
+			"bands":[{"multiplier":2,"upper":20500.5,"lower":20366.12}],
+			"indicators":{"twap":20400.1},
+			"window":{"trade_count":3,"full":true,"volume":1.5,"notional":30649.965,
+				"oldest_trade_at":"2022-01-01T00:00:00Z","newest_trade_at":"2022-01-01T00:00:30.5Z"}}`,
+		string(got),
+	)
+
+	want, err := jsonEncoder{}.Encode(fullVWAP())
+	require.NoError(t, err)
+	assert.Equal(t, jsonKeys(t, want), jsonKeys(t, got), "the fields are the same as in JSON")
+}
+
+// jsonKeys returns the sorted paths of every key of the JSON object, nested keys included.
+func jsonKeys(t *testing.T, data []byte) []string {
+	t.Helper()
+
+	var v interface{}
+	require.NoError(t, json.Unmarshal(data, &v))
+
+	var keys []string
+	var walk func(prefix string, v interface{})
+	walk = func(prefix string, v interface{}) {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			for k, child := range v {
+				keys = append(keys, prefix+k)
+				walk(prefix+k+".", child)
+			}
+		case []interface{}:
+			for _, child := range v {
+				walk(prefix, child)
+			}
+		}
+	}
+	walk("", v)
+	sort.Strings(keys)
+
+	return keys
+}
+
+func TestCSVEncoder(t *testing.T) {
+	tests := []struct {
+		name string
+		vwap model.VWAP
+		want string
+	}{
+		{
+			name: "full",
+			vwap: fullVWAP(),
+			want: "BTC-USD,2022-01-01T00:00:30.5Z,20433.31,2022-01-01T00:00:00Z,3,true,1.5,30649.965",
+		},
+		{
+			name: "no session start and window",
+			vwap: model.VWAP{TradingPair: "BTC-USD", LastTradeAt: encodedLastTradeAt, VWAP: decimal.NewFromInt(1)},
+			want: "BTC-USD,2022-01-01T00:00:30.5Z,1,,,,,",
+		},
+		{
+			name: "quoted",
+			vwap: model.VWAP{TradingPair: "BTC,USD", LastTradeAt: encodedLastTradeAt, VWAP: decimal.NewFromInt(1)},
+			want: `"BTC,USD",2022-01-01T00:00:30.5Z,1,,,,,`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				got, err := csvEncoder{}.Encode(tt.vwap)
+				require.NoError(t, err)
+				assert.Equal(t, tt.want, string(got))
+			},
+		)
+	}
+}
+
+func TestMsgpackEncoder(t *testing.T) {
+	got, err := msgpackEncoder{}.Encode(
+		model.VWAP{
+			TradingPair: "BTC-USD",
+			LastTradeAt: time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+			VWAP:        decimal.RequireFromString("1.5"),
+		},
+	)
+	require.NoError(t, err)
+
+	var want []byte
+	want = append(want, 0x83)
+	want = append(want, append([]byte{0xac}, "trading_pair"...)...)
+	want = append(want, append([]byte{0xa7}, "BTC-USD"...)...)
+	want = append(want, append([]byte{0xad}, "last_trade_at"...)...)
+	want = append(want, 0xd6, 0xff, 0x61, 0xcf, 0x99, 0x80)
+	want = append(want, append([]byte{0xa4}, "vwap"...)...)
+	want = append(want, append([]byte{0xa3}, "1.5"...)...)
+	assert.Equal(t, want, got)
+
+	full, err := msgpackEncoder{}.Encode(fullVWAP())
+	require.NoError(t, err)
+	assert.Equal(t, byte(0x87), full[0], "a map of 7 fields")
+}
+
+func TestAppendMsgpack(t *testing.T) {
+	tests := []struct {
+		name string
+		got  []byte
+		want []byte
+	}{
+		{name: "positive fixint", got: appendMsgpackInt(nil, 127), want: []byte{0x7f}},
+		{name: "negative fixint", got: appendMsgpackInt(nil, -32), want: []byte{0xe0}},
+		{name: "uint8", got: appendMsgpackInt(nil, 200), want: []byte{0xcc, 0xc8}},
+		{name: "uint16", got: appendMsgpackInt(nil, 256), want: []byte{0xcd, 0x01, 0x00}},
+		{name: "uint32", got: appendMsgpackInt(nil, 1<<16), want: []byte{0xce, 0x00, 0x01, 0x00, 0x00}},
+		{name: "uint64", got: appendMsgpackInt(nil, 1<<32), want: []byte{0xcf, 0, 0, 0, 0x01, 0, 0, 0, 0}},
+		{name: "int8", got: appendMsgpackInt(nil, -33), want: []byte{0xd0, 0xdf}},
+		{name: "int16", got: appendMsgpackInt(nil, -129), want: []byte{0xd1, 0xff, 0x7f}},
+		{name: "true", got: appendMsgpackBool(nil, true), want: []byte{0xc3}},
+		{name: "false", got: appendMsgpackBool(nil, false), want: []byte{0xc2}},
+		{name: "str8", got: appendMsgpackString(nil, strings.Repeat("a", 32))[:2], want: []byte{0xd9, 32}},
+		{name: "str16", got: appendMsgpackString(nil, strings.Repeat("a", 256))[:3], want: []byte{0xda, 0x01, 0x00}},
+		{name: "map16", got: appendMsgpackMapHeader(nil, 16), want: []byte{0xde, 0x00, 0x10}},
+		{name: "array16", got: appendMsgpackArrayHeader(nil, 16), want: []byte{0xdc, 0x00, 0x10}},
+		{
+			name: "timestamp 64",
+			got:  appendMsgpackTime(nil, time.Date(2022, 1, 1, 0, 0, 0, 500_000_000, time.UTC)),
+			want: []byte{0xd7, 0xff, 0x77, 0x35, 0x94, 0x00, 0x61, 0xcf, 0x99, 0x80},
+		},
+		{
+			name: "timestamp 96",
+			got:  appendMsgpackTime(nil, time.Unix(-1, 0)),
+			want: []byte{0xc7, 12, 0xff, 0, 0, 0, 0, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				assert.Equal(t, tt.want, tt.got)
+			},
+		)
+	}
+}
+
+func TestProtobufEncoder(t *testing.T) {
+	var stream bytes.Buffer
+	for i := 0; i < 2; i++ {
+		encoded, err := protobufEncoder{}.Encode(fullVWAP())
+		require.NoError(t, err)
+		stream.Write(encoded)
+	}
+
+	r := bufio.NewReader(&stream)
+	for i := 0; i < 2; i++ {
+		var got vwapv1.VWAP
+		require.NoError(t, protodelim.UnmarshalFrom(r, &got), "the messages are length delimited")
+		assert.True(t, proto.Equal(toProtoVWAP(fullVWAP()), &got), "got %v", &got)
+	}
+}
+
+func BenchmarkEncoder_Encode(b *testing.B) {
+	vwap := fullVWAP()
+
+	for _, encoding := range []config.Encoding{
+		config.EncodingJSON, config.EncodingJSONNumbers, config.EncodingCSV, config.EncodingMsgpack,
+		config.EncodingProtobuf,
+	} {
+		enc, err := NewEncoder(encoding)
+		require.NoError(b, err)
+
+		b.Run(
+			fmt.Sprint(encoding), func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					_, _ = enc.Encode(vwap)
+				}
+			},
+		)
+	}
+}
@@ -0,0 +1,481 @@
+package publisher
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aprln/vwap-engine/config"
+)
+
+const (
+	fsyncAlways         = "always"
+	fsyncNever          = "never"
+	fsyncIntervalPrefix = "interval@"
+
+	fileExt        = ".jsonl"
+	gzipExt        = ".gz"
+	tmpExt         = ".tmp"
+	rotatedLayout  = "20060102T150405.000000000"
+	dayLayout      = "2006-01-02"
+	dayFilePrefix  = "vwap-"
+	filePermission = 0o644
+)
+
+var errFileSenderClosed = errors.New("file sender is closed")
+
+// rotatedFile matches the name of a rotated file which is not compressed
+var rotatedFile = regexp.MustCompile(`\.\d{8}T\d{6}\.\d{9}(-\d+)?` + regexp.QuoteMeta(fileExt) + `$`)
+
+type fsyncKind int
+
+const (
+	fsyncKindNever fsyncKind = iota
+	fsyncKindAlways
+	fsyncKindInterval
+)
+
+// FsyncPolicy decides when the written lines are flushed to disk.
+type FsyncPolicy struct {
+	kind     fsyncKind
+	interval time.Duration
+}
+
+// ParseFsyncPolicy parses "always" (after every line), "never" (leaving it to the OS) or "interval@<duration>"
+// (every duration, so at most a duration of lines is lost on a crash).
+func ParseFsyncPolicy(s string) (FsyncPolicy, error) {
+	switch {
+	case s == fsyncAlways:
+		return FsyncPolicy{kind: fsyncKindAlways}, nil
+
+	case s == fsyncNever:
+		return FsyncPolicy{kind: fsyncKindNever}, nil
+
+	case strings.HasPrefix(s, fsyncIntervalPrefix):
+		interval, err := time.ParseDuration(strings.TrimPrefix(s, fsyncIntervalPrefix))
+		if err != nil || interval <= 0 {
+			return FsyncPolicy{}, fmt.Errorf(`invalid interval in fsync policy "%s"`, s)
+		}
+
+		return FsyncPolicy{kind: fsyncKindInterval, interval: interval}, nil
+
+	default:
+		return FsyncPolicy{}, fmt.Errorf(`fsync policy "%s" is unsupported`, s)
+	}
+}
+
+// SetUpFileSender creates the directory of the JSON lines files and a file sender writing to it.
+func SetUpFileSender(fileSinkCfg config.FileSink) (*FileSender, error) {
+	if err := os.MkdirAll(fileSinkCfg.Dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	return NewFileSender(fileSinkCfg, time.Now)
+}
+
+func NewFileSender(fileSinkCfg config.FileSink, now func() time.Time) (*FileSender, error) {
+	if fileSinkCfg.Naming != config.FileNamingDay && fileSinkCfg.Naming != config.FileNamingPair {
+		return nil, fmt.Errorf(`file naming "%s" is unsupported`, fileSinkCfg.Naming)
+	}
+
+	fsync, err := ParseFsyncPolicy(fileSinkCfg.Fsync)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &FileSender{
+		dir:            fileSinkCfg.Dir,
+		naming:         fileSinkCfg.Naming,
+		maxSize:        int64(fileSinkCfg.MaxSize),
+		rotateInterval: fileSinkCfg.RotateInterval,
+		gzip:           fileSinkCfg.Gzip,
+		fsync:          fsync,
+		now:            now,
+		files:          make(map[string]*activeFile),
+		closed:         make(chan struct{}),
+	}
+
+	if s.gzip {
+		if err := s.compressLeftovers(); err != nil {
+			return nil, err
+		}
+	}
+
+	if fsync.kind == fsyncKindInterval {
+		s.wg.Add(1)
+		go s.syncForever()
+	}
+
+	return s, nil
+}
+
+// FileSender writes each message as a line to a JSON lines file, named after the UTC day or the trading pair.
+//
+// The file being written to is always <name>.jsonl. Once it is bigger than the max size or older than the rotate
+// interval, or once the day is over, it is renamed to <name>.<time of rotation>.jsonl and compressed to
+// <name>.<time of rotation>.jsonl.gz in the background if gzip is enabled. The rotation is checked before each
+// write. A file left over by a previous run is appended to, its age being taken from its modification time, and the
+// rotated files a previous run did not compress are compressed on start. It is safe for concurrent use.
+type FileSender struct {
+	dir            string
+	naming         config.FileNaming
+	maxSize        int64
+	rotateInterval time.Duration
+	gzip           bool
+	fsync          FsyncPolicy
+	now            func() time.Time
+	closed         chan struct{}
+	closeOnce      sync.Once
+	// wg tracks the fsync and compression goroutines
+	wg sync.WaitGroup
+
+	mu sync.Mutex
+	// files are the files being written to, keyed by name
+	files map[string]*activeFile
+}
+
+type activeFile struct {
+	file     *os.File
+	size     int64
+	openedAt time.Time
+	// dirty is true when lines were written since the last fsync
+	dirty bool
+}
+
+// Send decodes the trading pair of the message when the files are named after it, see SendMessage.
+func (s *FileSender) Send(msg []byte) error {
+	m := Message{Data: msg}
+	if s.naming == config.FileNamingPair {
+		var decoded struct {
+			TradingPair string `json:"trading_pair"`
+		}
+		if err := json.Unmarshal(msg, &decoded); err == nil {
+			m.TradingPair = decoded.TradingPair
+		}
+	}
+
+	return s.SendMessage(m)
+}
+
+// SendMessage fails on a message without trading pair when the files are named after the trading pair,
+// on file errors and once the sender is closed.
+func (s *FileSender) SendMessage(msg Message) error {
+	now := s.now()
+
+	name, err := s.fileName(msg, now)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	select {
+	case <-s.closed:
+		return errFileSenderClosed
+	default:
+	}
+
+	// only the file of the current day is written to, the files of the previous days are rotated
+	if s.naming == config.FileNamingDay {
+		for other := range s.files {
+			if other != name {
+				if err := s.rotateLocked(other, now); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	f, err := s.fileLocked(name, int64(len(msg.Data)+1), now)
+	if err != nil {
+		return err
+	}
+
+	line := make([]byte, 0, len(msg.Data)+1)
+	n, err := f.file.Write(append(append(line, msg.Data...), '\n'))
+	f.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("file sink: %w", err)
+	}
+
+	if s.fsync.kind == fsyncKindAlways {
+		return f.file.Sync()
+	}
+	f.dirty = true
+
+	return nil
+}
+
+// Close flushes and closes the files. The rotated files are compressed before it returns, and the messages sent
+// afterwards fail.
+func (s *FileSender) Close() error {
+	s.closeOnce.Do(func() { close(s.closed) })
+
+	s.mu.Lock()
+	var errs []string
+	for name, f := range s.files {
+		if err := f.file.Sync(); err != nil {
+			errs = append(errs, err.Error())
+		}
+		if err := f.file.Close(); err != nil {
+			errs = append(errs, err.Error())
+		}
+		delete(s.files, name)
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+
+	if len(errs) > 0 {
+		return fmt.Errorf("file sink: %s", strings.Join(errs, "; "))
+	}
+
+	return nil
+}
+
+// fileName returns the name of the file the message is written to, without extension.
+func (s *FileSender) fileName(msg Message, now time.Time) (string, error) {
+	if s.naming == config.FileNamingDay {
+		return dayFilePrefix + now.UTC().Format(dayLayout), nil
+	}
+
+	if msg.TradingPair == "" {
+		return "", fmt.Errorf("file sink: message without trading pair: %s", msg.Data)
+	}
+	// the trading pair ends up in a path
+	if strings.ContainsAny(msg.TradingPair, `/\`) || strings.HasPrefix(msg.TradingPair, ".") {
+		return "", fmt.Errorf(`file sink: invalid trading pair "%s"`, msg.TradingPair)
+	}
+
+	return msg.TradingPair, nil
+}
+
+// fileLocked returns the file to write the next line of lineSize bytes to, rotating it first if needed.
+func (s *FileSender) fileLocked(name string, lineSize int64, now time.Time) (*activeFile, error) {
+	f, ok := s.files[name]
+	if !ok {
+		var err error
+		if f, err = s.openLocked(name, now); err != nil {
+			return nil, err
+		}
+	}
+
+	tooBig := s.maxSize > 0 && f.size > 0 && f.size+lineSize > s.maxSize
+	tooOld := s.rotateInterval > 0 && now.Sub(f.openedAt) >= s.rotateInterval
+	if !tooBig && !tooOld {
+		return f, nil
+	}
+
+	if err := s.rotateLocked(name, now); err != nil {
+		return nil, err
+	}
+
+	return s.openLocked(name, now)
+}
+
+// openLocked opens the file for appending. A file left over by a previous run is as old as its last write,
+// so restarts do not keep it from rotating.
+func (s *FileSender) openLocked(name string, now time.Time) (*activeFile, error) {
+	file, err := os.OpenFile(s.path(name), os.O_WRONLY|os.O_CREATE|os.O_APPEND, filePermission)
+	if err != nil {
+		return nil, fmt.Errorf("file sink: %w", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+
+		return nil, fmt.Errorf("file sink: %w", err)
+	}
+
+	f := &activeFile{file: file, size: info.Size(), openedAt: now}
+	if f.size > 0 && info.ModTime().Before(now) {
+		f.openedAt = info.ModTime()
+	}
+	s.files[name] = f
+
+	return f, nil
+}
+
+// rotateLocked closes the file and renames it after the time of rotation, then compresses it if gzip is enabled.
+func (s *FileSender) rotateLocked(name string, now time.Time) error {
+	f := s.files[name]
+	delete(s.files, name)
+
+	if err := f.file.Sync(); err != nil {
+		_ = f.file.Close()
+
+		return fmt.Errorf("file sink: %w", err)
+	}
+	if err := f.file.Close(); err != nil {
+		return fmt.Errorf("file sink: %w", err)
+	}
+
+	rotated := s.rotatedPath(name, now)
+	if err := os.Rename(s.path(name), rotated); err != nil {
+		return fmt.Errorf("file sink: %w", err)
+	}
+
+	if s.gzip {
+		s.compressInBackground(rotated)
+	}
+
+	return nil
+}
+
+// compressLeftovers compresses the rotated files left uncompressed by a previous run, e.g. after a crash.
+func (s *FileSender) compressLeftovers() error {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return fmt.Errorf("file sink: %w", err)
+	}
+
+	var rotated []string
+	for _, e := range entries {
+		if !e.Type().IsRegular() {
+			continue
+		}
+
+		path := filepath.Join(s.dir, e.Name())
+		switch {
+		case strings.HasSuffix(e.Name(), gzipExt+tmpExt):
+			// the compression was interrupted, the rotated file is still there
+			if err := os.Remove(path); err != nil {
+				return fmt.Errorf("file sink: %w", err)
+			}
+
+		case rotatedFile.MatchString(e.Name()):
+			rotated = append(rotated, path)
+		}
+	}
+
+	// the partially compressed files are removed first, as the compression writes to the same temporary files
+	for _, path := range rotated {
+		s.compressInBackground(path)
+	}
+
+	return nil
+}
+
+func (s *FileSender) compressInBackground(path string) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		if err := compressFile(path); err != nil {
+			log.Printf("failed to compress %s: %v", path, err)
+		}
+	}()
+}
+
+func (s *FileSender) path(name string) string {
+	return filepath.Join(s.dir, name+fileExt)
+}
+
+// rotatedPath returns a path for the rotated file that is not taken yet, compressed or not.
+func (s *FileSender) rotatedPath(name string, now time.Time) string {
+	base := filepath.Join(s.dir, name+"."+now.UTC().Format(rotatedLayout))
+	for i := 0; ; i++ {
+		path := base
+		if i > 0 {
+			path += "-" + strconv.Itoa(i)
+		}
+		path += fileExt
+
+		if !exists(path) && !exists(path+gzipExt) {
+			return path
+		}
+	}
+}
+
+// syncForever flushes the files written to every fsync interval, until the sender is closed.
+func (s *FileSender) syncForever() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.fsync.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.syncDirty()
+
+		case <-s.closed:
+			return
+		}
+	}
+}
+
+func (s *FileSender) syncDirty() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, f := range s.files {
+		if !f.dirty {
+			continue
+		}
+
+		if err := f.file.Sync(); err != nil {
+			log.Printf("file sink fsync error: %v", err)
+
+			continue
+		}
+		f.dirty = false
+	}
+}
+
+// compressFile compresses the file to path.gz and removes it. The compressed file is written under a temporary
+// name first, so a crash never leaves a truncated .gz file.
+func compressFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	tmp := path + gzipExt + tmpExt
+	dst, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, filePermission)
+	if err != nil {
+		return err
+	}
+
+	zw := gzip.NewWriter(dst)
+	_, err = io.Copy(zw, src)
+	if err == nil {
+		err = zw.Close()
+	}
+	if err == nil {
+		err = dst.Sync()
+	}
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+
+		return err
+	}
+
+	if err := os.Rename(tmp, path+gzipExt); err != nil {
+		return err
+	}
+
+	return os.Remove(path)
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+
+	return err == nil
+}
@@ -0,0 +1,284 @@
+package publisher
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aprln/vwap-engine/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFsyncPolicy(t *testing.T) {
+	tests := []struct {
+		s       string
+		want    FsyncPolicy
+		wantErr bool
+	}{
+		{s: "always", want: FsyncPolicy{kind: fsyncKindAlways}},
+		{s: "never", want: FsyncPolicy{kind: fsyncKindNever}},
+		{s: "interval@1s", want: FsyncPolicy{kind: fsyncKindInterval, interval: time.Second}},
+		{s: "interval@0s", wantErr: true},
+		{s: "interval@x", wantErr: true},
+		{s: "", wantErr: true},
+		{s: "sometimes", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.s, func(t *testing.T) {
+				got, err := ParseFsyncPolicy(tt.s)
+				if tt.wantErr {
+					assert.Error(t, err)
+
+					return
+				}
+				require.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			},
+		)
+	}
+}
+
+func newFileSinkConfig(dir string, naming config.FileNaming) config.FileSink {
+	return config.FileSink{Dir: dir, Naming: naming, Fsync: "always"}
+}
+
+// readDir returns the content of every file in the directory keyed by file name, gzipped files being decompressed.
+func readDir(t *testing.T, dir string) map[string]string {
+	t.Helper()
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+
+	files := make(map[string]string, len(entries))
+	for _, e := range entries {
+		data, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		require.NoError(t, err)
+
+		if strings.HasSuffix(e.Name(), gzipExt) {
+			zr, err := gzip.NewReader(bytes.NewReader(data))
+			require.NoError(t, err)
+			data, err = io.ReadAll(zr)
+			require.NoError(t, err)
+		}
+
+		files[e.Name()] = string(data)
+	}
+
+	return files
+}
+
+func fileNames(files map[string]string) []string {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func TestFileSender_naming(t *testing.T) {
+	now := time.Date(2022, 1, 1, 23, 59, 59, 0, time.UTC)
+	clock := func() time.Time { return now }
+
+	t.Run(
+		"pair", func(t *testing.T) {
+			dir := t.TempDir()
+			s, err := NewFileSender(newFileSinkConfig(dir, config.FileNamingPair), clock)
+			require.NoError(t, err)
+
+			require.NoError(t, s.Send([]byte(btcMsg)))
+			require.NoError(t, s.Send([]byte(ethMsg)))
+			require.NoError(t, s.Send([]byte(btcMsg)))
+			assert.Error(t, s.Send([]byte(`{"vwap":"1"}`)), "no trading pair")
+			assert.Error(t, s.Send([]byte(`{"trading_pair":"../BTC-USD"}`)), "a trading pair is not a path")
+			// the trading pair of a message is not decoded, so the data can be in any encoding
+			require.NoError(t, s.SendMessage(Message{Data: []byte("SOL-USD,1"), TradingPair: "SOL-USD"}))
+			require.NoError(t, s.Close())
+			assert.Error(t, s.Send([]byte(btcMsg)), "closed")
+
+			assert.Equal(
+				t, map[string]string{
+					"BTC-USD.jsonl": btcMsg + "\n" + btcMsg + "\n",
+					"ETH-USD.jsonl": ethMsg + "\n",
+					"SOL-USD.jsonl": "SOL-USD,1\n",
+				}, readDir(t, dir),
+			)
+		},
+	)
+
+	t.Run(
+		"day", func(t *testing.T) {
+			dir := t.TempDir()
+			s, err := NewFileSender(newFileSinkConfig(dir, config.FileNamingDay), clock)
+			require.NoError(t, err)
+
+			require.NoError(t, s.Send([]byte(btcMsg)))
+			require.NoError(t, s.Send([]byte(ethMsg)))
+			now = now.Add(time.Second)
+			require.NoError(t, s.Send([]byte(btcMsg)))
+			require.NoError(t, s.Close())
+
+			assert.Equal(
+				t, map[string]string{
+					"vwap-2022-01-01.20220102T000000.000000000.jsonl": btcMsg + "\n" + ethMsg + "\n",
+					"vwap-2022-01-02.jsonl":                           btcMsg + "\n",
+				}, readDir(t, dir), "the file of the previous day is rotated",
+			)
+		},
+	)
+
+	_, err := NewFileSender(newFileSinkConfig(t.TempDir(), "hour"), clock)
+	assert.Error(t, err)
+}
+
+func TestFileSender_rotation(t *testing.T) {
+	start := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		maxSize        int
+		rotateInterval time.Duration
+		gzip           bool
+		wantFiles      []string
+	}{
+		{
+			name:      "no rotation",
+			wantFiles: []string{"BTC-USD.jsonl"},
+		},
+		{
+			name:    "by size",
+			maxSize: 2 * (len(btcMsg) + 1),
+			wantFiles: []string{
+				"BTC-USD.20220101T000002.000000000.jsonl",
+				"BTC-USD.jsonl",
+			},
+		},
+		{
+			name:           "by time",
+			rotateInterval: time.Second,
+			wantFiles: []string{
+				"BTC-USD.20220101T000001.000000000.jsonl",
+				"BTC-USD.20220101T000002.000000000.jsonl",
+				"BTC-USD.jsonl",
+			},
+		},
+		{
+			name:    "gzip",
+			maxSize: 2 * (len(btcMsg) + 1),
+			gzip:    true,
+			wantFiles: []string{
+				"BTC-USD.20220101T000002.000000000.jsonl.gz",
+				"BTC-USD.jsonl",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				dir := t.TempDir()
+				now := start
+				cfg := newFileSinkConfig(dir, config.FileNamingPair)
+				cfg.MaxSize = tt.maxSize
+				cfg.RotateInterval = tt.rotateInterval
+				cfg.Gzip = tt.gzip
+				s, err := NewFileSender(cfg, func() time.Time { return now })
+				require.NoError(t, err)
+
+				for i := 0; i < 3; i++ {
+					require.NoError(t, s.Send([]byte(btcMsg)))
+					now = now.Add(time.Second)
+				}
+				require.NoError(t, s.Close())
+
+				files := readDir(t, dir)
+				assert.Equal(t, tt.wantFiles, fileNames(files))
+				var all string
+				for _, name := range fileNames(files) {
+					all += files[name]
+				}
+				assert.Equal(t, strings.Repeat(btcMsg+"\n", 3), all, "no line is lost")
+			},
+		)
+	}
+}
+
+func TestFileSender_append(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	cfg := newFileSinkConfig(dir, config.FileNamingPair)
+	cfg.Fsync = "interval@1ms"
+
+	for i := 0; i < 2; i++ {
+		s, err := NewFileSender(cfg, func() time.Time { return now })
+		require.NoError(t, err)
+		require.NoError(t, s.Send([]byte(btcMsg)))
+		require.NoError(t, s.Close())
+	}
+
+	assert.Equal(
+		t, map[string]string{"BTC-USD.jsonl": btcMsg + "\n" + btcMsg + "\n"}, readDir(t, dir),
+		"the file of a previous run is appended to",
+	)
+}
+
+func TestFileSender_leftovers(t *testing.T) {
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+
+	t.Run(
+		"rotation by age", func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "BTC-USD.jsonl")
+			require.NoError(t, os.WriteFile(path, []byte(btcMsg+"\n"), filePermission))
+			require.NoError(t, os.Chtimes(path, now.Add(-time.Hour), now.Add(-time.Hour)))
+
+			cfg := newFileSinkConfig(dir, config.FileNamingPair)
+			cfg.RotateInterval = time.Hour
+			s, err := NewFileSender(cfg, clock)
+			require.NoError(t, err)
+			require.NoError(t, s.Send([]byte(btcMsg)))
+			require.NoError(t, s.Close())
+
+			assert.Equal(
+				t, map[string]string{
+					"BTC-USD.20220101T000000.000000000.jsonl": btcMsg + "\n",
+					"BTC-USD.jsonl": btcMsg + "\n",
+				}, readDir(t, dir), "the file left over is as old as its last write",
+			)
+		},
+	)
+
+	t.Run(
+		"compression", func(t *testing.T) {
+			dir := t.TempDir()
+			rotated := filepath.Join(dir, "BTC-USD.20211231T000000.000000000.jsonl")
+			require.NoError(t, os.WriteFile(rotated, []byte(btcMsg+"\n"), filePermission))
+			require.NoError(t, os.WriteFile(rotated+gzipExt+tmpExt, []byte("partial"), filePermission))
+			require.NoError(t, os.WriteFile(filepath.Join(dir, "BTC-USD.jsonl"), []byte(btcMsg+"\n"), filePermission))
+
+			cfg := newFileSinkConfig(dir, config.FileNamingPair)
+			cfg.Gzip = true
+			s, err := NewFileSender(cfg, clock)
+			require.NoError(t, err)
+			require.NoError(t, s.Close())
+
+			assert.Equal(
+				t, map[string]string{
+					"BTC-USD.20211231T000000.000000000.jsonl.gz": btcMsg + "\n",
+					"BTC-USD.jsonl": btcMsg + "\n",
+				}, readDir(t, dir), "the rotated file left uncompressed is compressed, the active one is kept",
+			)
+		},
+	)
+}
@@ -0,0 +1,199 @@
+package publisher
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+
+	"github.com/aprln/vwap-engine/config"
+	"github.com/aprln/vwap-engine/model"
+	vwapv1 "github.com/aprln/vwap-engine/proto/vwap/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// SetUpGRPCServer starts a gRPC server serving vwap.v1.VWAPService on the configured address, see GRPCSender.
+func SetUpGRPCServer(grpcServerCfg config.GRPCServer) (*GRPCSender, error) {
+	ln, err := net.Listen("tcp", grpcServerCfg.Addr)
+	if err != nil {
+		return nil, err
+	}
+
+	s := NewGRPCSender(grpcServerCfg.BufferSize)
+	s.addr = ln.Addr()
+	s.server = grpc.NewServer()
+	vwapv1.RegisterVWAPServiceServer(s.server, s)
+
+	go func() {
+		if err := s.server.Serve(ln); err != nil {
+			log.Printf("gRPC server error: %v", err)
+		}
+	}()
+
+	log.Printf("gRPC server listening on %s", ln.Addr())
+
+	return s, nil
+}
+
+func NewGRPCSender(bufferSize int) *GRPCSender {
+	return &GRPCSender{bufferSize: bufferSize, hub: newHub[*vwapv1.VWAP](bufferSize, true)}
+}
+
+// GRPCSender serves the VWAP results over gRPC, see proto/vwap/v1/vwap.proto:
+//   - Subscribe streams the VWAPs of the requested trading pairs, or of every trading pair if none is requested,
+//     starting with their latest VWAP. A subscriber more than the buffer size of VWAPs behind has its stream ended
+//     with RESOURCE_EXHAUSTED, so a slow subscriber never holds back the pipeline.
+//   - GetLatest returns the latest VWAP of a trading pair, NOT_FOUND if there is none yet,
+//     or UNAVAILABLE while its window is not full.
+//
+// It is safe for concurrent use.
+type GRPCSender struct {
+	vwapv1.UnimplementedVWAPServiceServer
+
+	bufferSize int
+	server     *grpc.Server
+	addr       net.Addr
+	// hub keeps the latest VWAP of every trading pair, the messages are shared by the subscribers and never modified
+	hub *hub[*vwapv1.VWAP]
+}
+
+// Send fails, as the VWAP result of the message is required, see SendMessage.
+func (s *GRPCSender) Send(msg []byte) error {
+	return s.SendMessage(Message{Data: msg})
+}
+
+// SendMessage never fails because of a client, it only fails on a message which is not a VWAP result.
+func (s *GRPCSender) SendMessage(msg Message) error {
+	if msg.VWAP == nil || msg.VWAP.TradingPair == "" {
+		return fmt.Errorf("gRPC server: message is not a VWAP: %s", msg.Data)
+	}
+
+	s.hub.publish(msg.VWAP.TradingPair, toProtoVWAP(*msg.VWAP))
+
+	return nil
+}
+
+// Forget drops the latest VWAP of the trading pair, so it is neither sent on subscribe nor returned by GetLatest.
+func (s *GRPCSender) Forget(tradingPair string) {
+	s.hub.forget(tradingPair)
+}
+
+// Subscribe streams the VWAPs to the client until it cancels, lags behind or the sender is closed.
+func (s *GRPCSender) Subscribe(req *vwapv1.SubscribeRequest, stream vwapv1.VWAPService_SubscribeServer) error {
+	var pairs map[string]bool
+	if len(req.TradingPairs) > 0 {
+		pairs = make(map[string]bool, len(req.TradingPairs))
+		for _, tradingPair := range req.TradingPairs {
+			pairs[tradingPair] = true
+		}
+	}
+
+	c := s.hub.newSubscriber("gRPC subscriber", pairs)
+	if !s.hub.add(c) {
+		return status.Error(codes.Unavailable, "server is shutting down")
+	}
+	defer s.hub.remove(c)
+
+	for {
+		select {
+		case pb, more := <-c.queue:
+			if !more {
+				return s.endStatus(c)
+			}
+
+			if err := stream.Send(pb); err != nil {
+				return err
+			}
+
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		}
+	}
+}
+
+// GetLatest returns the latest VWAP of the trading pair.
+func (s *GRPCSender) GetLatest(_ context.Context, req *vwapv1.GetLatestRequest) (*vwapv1.VWAP, error) {
+	if req.TradingPair == "" {
+		return nil, status.Error(codes.InvalidArgument, "trading pair is required")
+	}
+
+	pb, ok := s.hub.latestOf(req.TradingPair)
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, `trading pair "%s" has no VWAP`, req.TradingPair)
+	}
+	if pb.Window != nil && !pb.Window.Full {
+		return nil, status.Errorf(codes.Unavailable, `trading pair "%s" is warming up`, req.TradingPair)
+	}
+
+	return pb, nil
+}
+
+// Addr returns the address the server listens on, or nil without server.
+func (s *GRPCSender) Addr() net.Addr {
+	return s.addr
+}
+
+// Close stops the server, if any, and ends every stream.
+func (s *GRPCSender) Close() {
+	s.hub.close()
+
+	// the streams are ended already, so the server only waits for them to return
+	if s.server != nil {
+		s.server.GracefulStop()
+	}
+}
+
+// endStatus is the status a stream ends with once the client's queue is closed.
+func (s *GRPCSender) endStatus(c *subscriber[*vwapv1.VWAP]) error {
+	if s.hub.lagged(c) {
+		return status.Errorf(codes.ResourceExhausted, "more than %d VWAPs behind", s.bufferSize)
+	}
+
+	return status.Error(codes.Unavailable, "server is shutting down")
+}
+
+// toProtoVWAP converts a VWAP to its protobuf message, decimals being kept as strings.
+func toProtoVWAP(vwap model.VWAP) *vwapv1.VWAP {
+	pb := &vwapv1.VWAP{
+		TradingPair: vwap.TradingPair,
+		LastTradeAt: timestamppb.New(vwap.LastTradeAt),
+		Vwap:        vwap.VWAP.String(),
+	}
+
+	if vwap.SessionStart != nil {
+		pb.SessionStart = timestamppb.New(*vwap.SessionStart)
+	}
+
+	for _, band := range vwap.Bands {
+		pb.Bands = append(
+			pb.Bands, &vwapv1.Band{
+				Multiplier: band.Multiplier.String(),
+				Upper:      band.Upper.String(),
+				Lower:      band.Lower.String(),
+			},
+		)
+	}
+
+	if len(vwap.Indicators) > 0 {
+		pb.Indicators = make(map[string]string, len(vwap.Indicators))
+		for name, value := range vwap.Indicators {
+			pb.Indicators[name] = value.String()
+		}
+	}
+
+	if vwap.Window != nil {
+		pb.Window = &vwapv1.Window{
+			TradeCount:    int64(vwap.Window.TradeCount),
+			Full:          vwap.Window.Full,
+			Volume:        vwap.Window.Volume.String(),
+			Notional:      vwap.Window.Notional.String(),
+			OldestTradeAt: timestamppb.New(vwap.Window.OldestTradeAt),
+			NewestTradeAt: timestamppb.New(vwap.Window.NewestTradeAt),
+		}
+	}
+
+	return pb
+}
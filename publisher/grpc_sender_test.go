@@ -0,0 +1,181 @@
+package publisher
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aprln/vwap-engine/config"
+	"github.com/aprln/vwap-engine/model"
+	vwapv1 "github.com/aprln/vwap-engine/proto/vwap/v1"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func setUpGRPC(t *testing.T, bufferSize int) (*GRPCSender, vwapv1.VWAPServiceClient) {
+	t.Helper()
+
+	s, err := SetUpGRPCServer(config.GRPCServer{Addr: "127.0.0.1:0", BufferSize: bufferSize})
+	require.NoError(t, err)
+	t.Cleanup(s.Close)
+
+	conn, err := grpc.Dial(s.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return s, vwapv1.NewVWAPServiceClient(conn)
+}
+
+// subscribeGRPC subscribes and waits until the client is registered.
+func subscribeGRPC(
+	t *testing.T, s *GRPCSender, client vwapv1.VWAPServiceClient, tradingPairs ...string,
+) vwapv1.VWAPService_SubscribeClient {
+	t.Helper()
+
+	clients := s.hub.len()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	stream, err := client.Subscribe(ctx, &vwapv1.SubscribeRequest{TradingPairs: tradingPairs})
+	require.NoError(t, err)
+
+	assert.Eventually(t, func() bool { return s.hub.len() == clients+1 }, time.Second, time.Millisecond)
+
+	return stream
+}
+
+func recvGRPC(t *testing.T, stream vwapv1.VWAPService_SubscribeClient) string {
+	t.Helper()
+
+	pb, err := stream.Recv()
+	require.NoError(t, err)
+
+	return pb.TradingPair + " " + pb.Vwap
+}
+
+func TestGRPCSender_Subscribe(t *testing.T) {
+	s, client := setUpGRPC(t, 16)
+
+	require.NoError(t, s.SendMessage(newMessage(t, ethMsg)))
+	require.NoError(t, s.SendMessage(newMessage(t, btcMsg)))
+
+	btc := subscribeGRPC(t, s, client, "BTC-USD")
+	all := subscribeGRPC(t, s, client)
+
+	assert.Equal(t, "BTC-USD 20433.31", recvGRPC(t, btc), "the latest VWAP is sent first")
+	assert.Equal(t, "BTC-USD 20433.31", recvGRPC(t, all))
+	assert.Equal(t, "ETH-USD 1500", recvGRPC(t, all))
+
+	require.NoError(t, s.SendMessage(newMessage(t, ethMsg)))
+	require.NoError(t, s.SendMessage(newMessage(t, `{"trading_pair":"BTC-USD","vwap":"20500"}`)))
+
+	assert.Equal(t, "BTC-USD 20500", recvGRPC(t, btc), "only the subscribed trading pairs are sent")
+	assert.Equal(t, "ETH-USD 1500", recvGRPC(t, all))
+	assert.Equal(t, "BTC-USD 20500", recvGRPC(t, all))
+
+	assert.Error(t, s.Send([]byte(`{"vwap":"1"}`)), "no trading pair")
+
+	s.Close()
+	_, err := btc.Recv()
+	assert.Equal(t, codes.Unavailable, status.Code(err), "the stream ends when the sender is closed")
+}
+
+func TestGRPCSender_GetLatest(t *testing.T) {
+	s, client := setUpGRPC(t, 16)
+
+	require.NoError(t, s.SendMessage(newMessage(t, btcMsg)))
+	require.NoError(t, s.SendMessage(newMessage(t, `{"trading_pair":"ETH-USD","vwap":"1500","window":{"trade_count":3,"full":false}}`)))
+
+	tests := []struct {
+		name        string
+		tradingPair string
+		wantCode    codes.Code
+		wantVWAP    string
+	}{
+		{name: "latest", tradingPair: "BTC-USD", wantCode: codes.OK, wantVWAP: "20433.31"},
+		{name: "window not full", tradingPair: "ETH-USD", wantCode: codes.Unavailable},
+		{name: "no vwap", tradingPair: "SOL-USD", wantCode: codes.NotFound},
+		{name: "no trading pair", wantCode: codes.InvalidArgument},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				got, err := client.GetLatest(context.Background(), &vwapv1.GetLatestRequest{TradingPair: tt.tradingPair})
+				assert.Equal(t, tt.wantCode, status.Code(err))
+				assert.Equal(t, tt.wantVWAP, got.GetVwap())
+			},
+		)
+	}
+}
+
+func TestGRPCSender_lagging(t *testing.T) {
+	s := NewGRPCSender(1)
+
+	c := s.hub.newSubscriber("gRPC subscriber", nil)
+	require.True(t, s.hub.add(c))
+
+	require.NoError(t, s.SendMessage(newMessage(t, btcMsg)))
+	require.NoError(t, s.SendMessage(newMessage(t, btcMsg)), "a lagging client does not fail the sender")
+
+	assert.Zero(t, s.hub.len())
+	<-c.queue
+	_, more := <-c.queue
+	assert.False(t, more, "the lagging client's queue is closed")
+	assert.Equal(t, codes.ResourceExhausted, status.Code(s.endStatus(c)))
+}
+
+func Test_toProtoVWAP(t *testing.T) {
+	lastTradeAt := time.Date(2022, 1, 1, 0, 0, 30, 0, time.UTC)
+	sessionStart := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	got := toProtoVWAP(
+		model.VWAP{
+			TradingPair:  "BTC-USD",
+			LastTradeAt:  lastTradeAt,
+			VWAP:         decimal.RequireFromString("20433.31"),
+			SessionStart: &sessionStart,
+			Bands: []model.Band{
+				{
+					Multiplier: decimal.NewFromInt(2),
+					Upper:      decimal.NewFromInt(20500),
+					Lower:      decimal.NewFromInt(20300),
+				},
+			},
+			Indicators: map[string]decimal.Decimal{"twap": decimal.NewFromInt(20400)},
+			Window: &model.Window{
+				TradeCount:    3,
+				Full:          true,
+				Volume:        decimal.RequireFromString("1.5"),
+				Notional:      decimal.RequireFromString("30649.965"),
+				OldestTradeAt: sessionStart,
+				NewestTradeAt: lastTradeAt,
+			},
+		},
+	)
+
+	want := &vwapv1.VWAP{
+		TradingPair:  "BTC-USD",
+		LastTradeAt:  timestamppb.New(lastTradeAt),
+		Vwap:         "20433.31",
+		SessionStart: timestamppb.New(sessionStart),
+		Bands:        []*vwapv1.Band{{Multiplier: "2", Upper: "20500", Lower: "20300"}},
+		Indicators:   map[string]string{"twap": "20400"},
+		Window: &vwapv1.Window{
+			TradeCount:    3,
+			Full:          true,
+			Volume:        "1.5",
+			Notional:      "30649.965",
+			OldestTradeAt: timestamppb.New(sessionStart),
+			NewestTradeAt: timestamppb.New(lastTradeAt),
+		},
+	}
+	assert.True(t, proto.Equal(want, got), "got %v", got)
+}
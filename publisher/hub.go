@@ -0,0 +1,223 @@
+package publisher
+
+import (
+	"log"
+	"sort"
+	"sync"
+)
+
+// newHub creates a hub queueing up to bufferSize messages for each subscriber. With keepLatest, the hub keeps the
+// latest message of every trading pair, which is queued first for the subscriptions to the trading pair.
+func newHub[T any](bufferSize int, keepLatest bool) *hub[T] {
+	h := &hub[T]{
+		bufferSize:  bufferSize,
+		subscribers: make(map[*subscriber[T]]struct{}),
+	}
+	if keepLatest {
+		h.latest = make(map[string]T)
+	}
+
+	return h
+}
+
+// hub fans the messages of every trading pair out to their subscribers, e.g. the websocket, SSE and gRPC clients.
+// Each subscriber has its own bounded queue, read by the goroutine serving it. A subscriber more than the buffer size
+// of messages behind is removed, so a slow subscriber never holds back the sender. It is safe for concurrent use.
+type hub[T any] struct {
+	bufferSize int
+
+	mu          sync.Mutex
+	subscribers map[*subscriber[T]]struct{}
+	// latest is the latest message of every trading pair, nil if the hub does not keep them
+	latest map[string]T
+	closed bool
+}
+
+// subscriber is a client of a hub. Its queue is closed once it is removed, which ends the goroutine serving it.
+type subscriber[T any] struct {
+	// name identifies the subscriber in the logs
+	name string
+	// pairs is nil for a subscriber of every trading pair
+	pairs map[string]bool
+	// queue is only written to and closed with the hub's lock held
+	queue chan T
+	// lagged is true once the subscriber is removed for being too far behind
+	lagged bool
+}
+
+// newSubscriber creates a subscriber of the trading pairs, or of every trading pair if pairs is nil.
+func (h *hub[T]) newSubscriber(name string, pairs map[string]bool) *subscriber[T] {
+	return &subscriber[T]{name: name, pairs: pairs, queue: make(chan T, h.bufferSize)}
+}
+
+func (s *subscriber[T]) wants(tradingPair string) bool {
+	return s.pairs == nil || s.pairs[tradingPair]
+}
+
+// add registers the subscriber and queues the latest message of the trading pairs it wants, sorted by trading pair.
+// It returns false once the hub is closed.
+func (h *hub[T]) add(s *subscriber[T]) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return false
+	}
+	h.subscribers[s] = struct{}{}
+
+	tradingPairs := make([]string, 0, len(h.latest))
+	for tradingPair := range h.latest {
+		if s.wants(tradingPair) {
+			tradingPairs = append(tradingPairs, tradingPair)
+		}
+	}
+	sort.Strings(tradingPairs)
+
+	for _, tradingPair := range tradingPairs {
+		h.enqueueLocked(s, h.latest[tradingPair])
+	}
+
+	return true
+}
+
+// remove unregisters the subscriber and closes its queue, if not done yet.
+func (h *hub[T]) remove(s *subscriber[T]) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.removeLocked(s)
+}
+
+// subscribe adds the trading pairs to those of the subscriber, queueing the latest message of the new ones.
+// A subscriber of every trading pair is left as is.
+func (h *hub[T]) subscribe(s *subscriber[T], tradingPairs []string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if s.pairs == nil {
+		return
+	}
+
+	for _, tradingPair := range tradingPairs {
+		if s.pairs[tradingPair] {
+			continue
+		}
+
+		s.pairs[tradingPair] = true
+		if msg, ok := h.latest[tradingPair]; ok {
+			h.enqueueLocked(s, msg)
+		}
+	}
+}
+
+// unsubscribe removes the trading pairs from those of the subscriber.
+func (h *hub[T]) unsubscribe(s *subscriber[T], tradingPairs []string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, tradingPair := range tradingPairs {
+		delete(s.pairs, tradingPair)
+	}
+}
+
+// subscriptions returns the number of trading pairs the subscriber is subscribed to.
+func (h *hub[T]) subscriptions(s *subscriber[T]) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return len(s.pairs)
+}
+
+// publish queues the message of the trading pair for its subscribers.
+func (h *hub[T]) publish(tradingPair string, msg T) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.latest != nil {
+		h.latest[tradingPair] = msg
+	}
+
+	for s := range h.subscribers {
+		if s.wants(tradingPair) {
+			h.enqueueLocked(s, msg)
+		}
+	}
+}
+
+// send queues a message for the subscriber only, e.g. the reply to one of its requests.
+func (h *hub[T]) send(s *subscriber[T], msg T) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.enqueueLocked(s, msg)
+}
+
+// latestOf returns the latest message of the trading pair, if the hub keeps them.
+func (h *hub[T]) latestOf(tradingPair string) (T, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	msg, ok := h.latest[tradingPair]
+
+	return msg, ok
+}
+
+// forget drops the latest message of the trading pair, once its pipeline is done.
+func (h *hub[T]) forget(tradingPair string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	delete(h.latest, tradingPair)
+}
+
+// lagged returns whether the subscriber was removed for being too far behind.
+func (h *hub[T]) lagged(s *subscriber[T]) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return s.lagged
+}
+
+// len returns the number of subscribers.
+func (h *hub[T]) len() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return len(h.subscribers)
+}
+
+// close removes every subscriber. No subscriber can be added afterwards.
+func (h *hub[T]) close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for s := range h.subscribers {
+		h.removeLocked(s)
+	}
+}
+
+// enqueueLocked queues the message for the subscriber, or removes the subscriber if its queue is full.
+// The messages of a removed subscriber are dropped, as its queue is closed.
+func (h *hub[T]) enqueueLocked(s *subscriber[T], msg T) {
+	if _, ok := h.subscribers[s]; !ok {
+		return
+	}
+
+	select {
+	case s.queue <- msg:
+	default:
+		log.Printf("%s is more than %d messages behind, removing it", s.name, h.bufferSize)
+		s.lagged = true
+		h.removeLocked(s)
+	}
+}
+
+func (h *hub[T]) removeLocked(s *subscriber[T]) {
+	if _, ok := h.subscribers[s]; !ok {
+		return
+	}
+
+	delete(h.subscribers, s)
+	close(s.queue)
+}
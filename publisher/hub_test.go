@@ -0,0 +1,89 @@
+package publisher
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/aprln/vwap-engine/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newMessage returns the message of a JSON VWAP result, as sent by a publisher.
+func newMessage(t *testing.T, msg string) Message {
+	t.Helper()
+
+	var vwap model.VWAP
+	require.NoError(t, json.Unmarshal([]byte(msg), &vwap))
+
+	return Message{Data: []byte(msg), TradingPair: vwap.TradingPair, VWAP: &vwap}
+}
+
+// drain returns the messages queued for the subscriber.
+func drain[T any](s *subscriber[T]) []T {
+	var msgs []T
+	for {
+		select {
+		case msg, more := <-s.queue:
+			if !more {
+				return msgs
+			}
+			msgs = append(msgs, msg)
+		default:
+			return msgs
+		}
+	}
+}
+
+func TestHub(t *testing.T) {
+	h := newHub[string](16, true)
+	h.publish("ETH-USD", "eth 1")
+	h.publish("BTC-USD", "btc 1")
+
+	all := h.newSubscriber("all", nil)
+	require.True(t, h.add(all))
+	assert.Equal(t, []string{"btc 1", "eth 1"}, drain(all), "the latest messages are sent first, sorted")
+
+	some := h.newSubscriber("some", map[string]bool{})
+	require.True(t, h.add(some))
+	h.subscribe(some, []string{"BTC-USD"})
+	assert.Equal(t, []string{"btc 1"}, drain(some))
+
+	h.publish("BTC-USD", "btc 2")
+	h.publish("ETH-USD", "eth 2")
+	assert.Equal(t, []string{"btc 2", "eth 2"}, drain(all))
+	assert.Equal(t, []string{"btc 2"}, drain(some))
+
+	h.forget("BTC-USD")
+	_, ok := h.latestOf("BTC-USD")
+	assert.False(t, ok, "a forgotten trading pair has no latest message")
+	h.subscribe(some, []string{"BTC-USD", "ETH-USD"})
+	assert.Equal(t, []string{"eth 2"}, drain(some), "a forgotten trading pair is not sent on subscribe")
+
+	h.unsubscribe(some, []string{"ETH-USD"})
+	h.publish("ETH-USD", "eth 3")
+	assert.Empty(t, drain(some))
+
+	h.close()
+	assert.Zero(t, h.len())
+	assert.Equal(t, "eth 3", <-all.queue)
+	_, more := <-all.queue
+	assert.False(t, more, "the queues are closed")
+	assert.False(t, h.add(h.newSubscriber("late", nil)), "no subscriber is added once closed")
+}
+
+func TestHub_lagging(t *testing.T) {
+	h := newHub[string](1, false)
+	s := h.newSubscriber("lagging", nil)
+	require.True(t, h.add(s))
+
+	h.publish("BTC-USD", "btc 1")
+	h.publish("BTC-USD", "btc 2")
+	h.send(s, "reply")
+
+	assert.Zero(t, h.len())
+	assert.True(t, h.lagged(s))
+	assert.Equal(t, []string{"btc 1"}, drain(s), "the queue is closed once removed")
+	_, ok := h.latestOf("BTC-USD")
+	assert.False(t, ok, "the latest messages are not kept")
+}
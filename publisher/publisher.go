@@ -5,6 +5,7 @@ import (
 	"log"
 	"sync"
 
+	"github.com/aprln/vwap-engine/config"
 	"github.com/aprln/vwap-engine/model"
 )
 
@@ -12,46 +13,147 @@ type Sender interface {
 	Send(msg []byte) error
 }
 
-func SetUp() Publisher {
-	return New(newStdoutSender())
+// Message is an encoded message along with the trading pair and the VWAP result it was encoded from, so the senders
+// routing the messages by trading pair or encoding the results their own way do not decode them.
+type Message struct {
+	Data []byte
+	// TradingPair is empty for the messages other than VWAP results.
+	TradingPair string
+	// VWAP is the VWAP result encoded in Data, nil for the other messages. It is shared by the senders,
+	// which must not modify it.
+	VWAP *model.VWAP
 }
 
-func New(s Sender) Publisher {
+// MessageSender is a Sender taking the messages along with their trading pair and VWAP result.
+// The publishers and sinks send to it with SendMessage instead of Send.
+type MessageSender interface {
+	Sender
+	SendMessage(msg Message) error
+}
+
+// Forgetter is a sender keeping the latest message of every trading pair, which must forget a trading pair
+// once its pipeline is done, see Sink.Forget.
+type Forgetter interface {
+	Forget(tradingPair string)
+}
+
+// sendMessage sends the message with SendMessage if the sender is a MessageSender, or its data with Send.
+func sendMessage(sender Sender, msg Message) error {
+	if ms, ok := sender.(MessageSender); ok {
+		return ms.SendMessage(msg)
+	}
+
+	return sender.Send(msg.Data)
+}
+
+// SetUp sets up a publisher writing to stdout, the VWAP results in the configured encoding.
+// Text encodings are written one result per line, after the header for CSV, binary encodings delimit the results
+// themselves.
+func SetUp(outputCfg config.Output) (Publisher, error) {
+	enc, err := NewEncoder(outputCfg.Encoding)
+	if err != nil {
+		return Publisher{}, err
+	}
+
+	if outputCfg.Encoding.IsBinary() {
+		return NewWithEncoder(enc, newBinaryStdoutSender()), nil
+	}
+
+	var header string
+	if outputCfg.Encoding == config.EncodingCSV {
+		header = CSVHeader
+	}
+
+	return NewWithEncoder(enc, newStdoutSender(header)), nil
+}
+
+// New creates a publisher sending JSON messages to every sender.
+func New(senders ...Sender) Publisher {
+	return NewWithEncoder(jsonEncoder{}, senders...)
+}
+
+// NewWithEncoder creates a publisher sending the VWAP results encoded by enc to every sender.
+// The bars, cross rates and alerts are always sent as JSON.
+func NewWithEncoder(enc Encoder, senders ...Sender) Publisher {
 	return Publisher{
-		sender: s,
+		senders: senders,
+		encoder: enc,
 	}
 }
 
+// Publisher sends every message to each of its senders in turn. A sender failing does not stop the publisher,
+// and senders that must not hold back the others are wrapped in a Sink.
 type Publisher struct {
-	sender Sender
+	senders []Sender
+	encoder Encoder
+	round   func(vwap model.VWAP) model.VWAP
+}
+
+// WithRounding returns a copy of the publisher that rounds the VWAP results right before encoding them,
+// e.g. with processor.Rounder's Round.
+func (p Publisher) WithRounding(round func(vwap model.VWAP) model.VWAP) Publisher {
+	p.round = round
+
+	return p
 }
 
 func (p Publisher) GoPublish(ch <-chan model.VWAP, wg *sync.WaitGroup) {
-	go p.publishForever(ch, wg)
+	encode := func(vwap model.VWAP) (Message, error) {
+		if p.round != nil {
+			vwap = p.round(vwap)
+		}
+
+		data, err := p.encoder.Encode(vwap)
+
+		return Message{Data: data, TradingPair: vwap.TradingPair, VWAP: &vwap}, err
+	}
+
+	go publishForever(p.senders, "vwap", ch, encode, wg)
+}
+
+func (p Publisher) GoPublishBars(ch <-chan model.Bar, wg *sync.WaitGroup) {
+	go publishForever(p.senders, "bar", ch, marshalJSON[model.Bar], wg)
 }
 
-func (p Publisher) publishForever(ch <-chan model.VWAP, wg *sync.WaitGroup) {
+func (p Publisher) GoPublishCrossRates(ch <-chan model.CrossRate, wg *sync.WaitGroup) {
+	go publishForever(p.senders, "cross rate", ch, marshalJSON[model.CrossRate], wg)
+}
+
+func (p Publisher) GoPublishAlerts(ch <-chan model.Alert, wg *sync.WaitGroup) {
+	go publishForever(p.senders, "alert", ch, marshalJSON[model.Alert], wg)
+}
+
+func marshalJSON[T any](msg T) (Message, error) {
+	data, err := json.Marshal(msg)
+
+	return Message{Data: data}, err
+}
+
+func publishForever[T any](
+	senders []Sender, name string, ch <-chan T, encode func(T) (Message, error), wg *sync.WaitGroup,
+) {
 	defer wg.Done()
 
 	for {
-		vwap, more := <-ch
+		msg, more := <-ch
 		if !more {
-			log.Println("no more to read from the vwap channel")
+			log.Printf("no more to read from the %s channel", name)
 
 			break
 		}
 
-		jsonMsg, err := json.Marshal(vwap)
+		encoded, err := encode(msg)
 		if err != nil {
-			log.Printf("JSON marshal error in publisher: %v", err)
+			log.Printf("%s encoding error in publisher: %v", name, err)
 
 			break
 		}
 
-		if err := p.sender.Send(jsonMsg); err != nil {
-			log.Printf("sender error %v", err)
-
-			break
+		// a failed message is not retried here, but the next ones are still sent
+		for _, sender := range senders {
+			if err := sendMessage(sender, encoded); err != nil {
+				log.Printf("sender error %v", err)
+			}
 		}
 	}
 }
@@ -35,3 +35,172 @@ func TestPublisher_GoPublish(t *testing.T) {
 
 	assert.Equal(t, gotMsg, wantMsg)
 }
+
+func TestPublisher_GoPublish_encoder(t *testing.T) {
+	in := make(chan model.VWAP, 1)
+	mockSender := NewMockSender()
+	in <- model.VWAP{
+		TradingPair: "BTC-USD",
+		LastTradeAt: time.Date(2020, 11, 1, 1, 1, 1, 1, time.UTC),
+		VWAP:        decimal.NewFromFloat(1.1),
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	NewWithEncoder(csvEncoder{}, mockSender).GoPublish(in, &wg)
+
+	gotMsg := mockSender.Read()
+	mockSender.Close()
+	close(in)
+
+	assert.Equal(t, "BTC-USD,2020-11-01T01:01:01.000000001Z,1.1,,,,,", string(gotMsg))
+}
+
+func TestPublisher_GoPublish_rounding(t *testing.T) {
+	in := make(chan model.VWAP, 1)
+	mockSender := NewMockSender()
+	in <- model.VWAP{
+		TradingPair: "BTC-USD",
+		LastTradeAt: time.Date(2020, 11, 1, 1, 1, 1, 1, time.UTC),
+		VWAP:        decimal.RequireFromString("1.16"),
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	round := func(vwap model.VWAP) model.VWAP {
+		vwap.VWAP = vwap.VWAP.Round(1)
+
+		return vwap
+	}
+	NewWithEncoder(csvEncoder{}, mockSender).WithRounding(round).GoPublish(in, &wg)
+
+	gotMsg := mockSender.Read()
+	mockSender.Close()
+	close(in)
+
+	assert.Equal(t, "BTC-USD,2020-11-01T01:01:01.000000001Z,1.2,,,,,", string(gotMsg))
+}
+
+func TestPublisher_GoPublish_senders(t *testing.T) {
+	in := make(chan model.VWAP, 2)
+	failing := &fakeSender{failures: 100}
+	mockSender := NewMockSender()
+	in <- model.VWAP{TradingPair: "BTC-USD"}
+	in <- model.VWAP{TradingPair: "ETH-USD"}
+	close(in)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	New(failing, mockSender).GoPublish(in, &wg)
+
+	assert.Contains(t, string(mockSender.Read()), "BTC-USD")
+	assert.Contains(t, string(mockSender.Read()), "ETH-USD", "a failing sender does not stop the publisher")
+	wg.Wait()
+	assert.Equal(t, 2, failing.Calls())
+}
+
+func TestPublisher_GoPublishBars(t *testing.T) {
+	in := make(chan model.Bar, 1)
+	mockSender := NewMockSender()
+	bar := model.Bar{
+		TradingPair: "BTC-USD",
+		Interval:    "1m0s",
+		OpenTime:    time.Date(2020, 11, 1, 1, 1, 0, 0, time.UTC),
+		CloseTime:   time.Date(2020, 11, 1, 1, 2, 0, 0, time.UTC),
+		Open:        decimal.NewFromFloat(1.1),
+		High:        decimal.NewFromFloat(1.2),
+		Low:         decimal.NewFromFloat(1.0),
+		Close:       decimal.NewFromFloat(1.2),
+		Volume:      decimal.NewFromFloat(3),
+		TradeCount:  2,
+		VWAP:        decimal.NewFromFloat(1.15),
+	}
+	wantMsg, err := json.Marshal(bar)
+	require.NoError(t, err)
+
+	in <- bar
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	New(mockSender).GoPublishBars(in, &wg)
+
+	gotMsg := mockSender.Read()
+	mockSender.Close()
+	close(in)
+
+	assert.Equal(t, gotMsg, wantMsg)
+}
+
+func TestPublisher_GoPublishCrossRates(t *testing.T) {
+	in := make(chan model.CrossRate, 1)
+	mockSender := NewMockSender()
+	rate := model.CrossRate{
+		TradingPair:  "ETH-BTC",
+		ImpliedFrom:  [2]string{"ETH-USD", "BTC-USD"},
+		LastTradeAt:  time.Date(2020, 11, 1, 1, 1, 1, 1, time.UTC),
+		Implied:      decimal.RequireFromString("0.075"),
+		Observed:     decimal.RequireFromString("0.0751"),
+		DeviationBps: decimal.RequireFromString("13.333333333333"),
+	}
+	wantMsg, err := json.Marshal(rate)
+	require.NoError(t, err)
+
+	in <- rate
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	New(mockSender).GoPublishCrossRates(in, &wg)
+
+	gotMsg := mockSender.Read()
+	mockSender.Close()
+	close(in)
+
+	assert.Equal(t, gotMsg, wantMsg)
+}
+
+func TestPublisher_GoPublishAlerts(t *testing.T) {
+	in := make(chan model.Alert, 1)
+	mockSender := NewMockSender()
+	alert := model.Alert{
+		TradingPair: "BTC-USD",
+		Rule:        "BTC-USD:above@20000",
+		Message:     "BTC-USD VWAP 20433.31 crossed above 20000",
+		Value:       decimal.RequireFromString("20433.31"),
+		Threshold:   decimal.NewFromInt(20000),
+		VWAP:        decimal.RequireFromString("20433.31"),
+		LastTradeAt: time.Date(2020, 11, 1, 1, 1, 1, 1, time.UTC),
+	}
+	wantMsg, err := json.Marshal(alert)
+	require.NoError(t, err)
+
+	in <- alert
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	New(mockSender).GoPublishAlerts(in, &wg)
+
+	gotMsg := mockSender.Read()
+	mockSender.Close()
+	close(in)
+
+	assert.Equal(t, gotMsg, wantMsg)
+}
+
+func TestQuarantine_Quarantine(t *testing.T) {
+	mockSender := NewMockSender()
+	rejected := model.RejectedTrade{
+		TradingPair: "BTC-USD",
+		Size:        decimal.NewFromInt(1),
+		Price:       decimal.NewFromInt(-1),
+		Time:        time.Date(2020, 11, 1, 1, 1, 1, 1, time.UTC),
+		Reason:      "price -1 is not positive",
+		RejectedAt:  time.Date(2020, 11, 1, 1, 1, 2, 0, time.UTC),
+	}
+	wantMsg, err := json.Marshal(rejected)
+	require.NoError(t, err)
+
+	NewQuarantine(mockSender).Quarantine(rejected)
+
+	assert.JSONEq(t, string(wantMsg), string(mockSender.Read()))
+	mockSender.Close()
+}
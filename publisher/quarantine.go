@@ -0,0 +1,38 @@
+package publisher
+
+import (
+	"encoding/json"
+	"log"
+
+	"github.com/aprln/vwap-engine/model"
+)
+
+// SetUpQuarantine sets up a quarantine that writes rejected trades to stderr,
+// keeping them apart from the VWAP results on stdout.
+func SetUpQuarantine() Quarantine {
+	return NewQuarantine(newStderrSender())
+}
+
+func NewQuarantine(s Sender) Quarantine {
+	return Quarantine{
+		sender: s,
+	}
+}
+
+// Quarantine publishes the trades rejected by the processor.
+type Quarantine struct {
+	sender Sender
+}
+
+func (q Quarantine) Quarantine(rejected model.RejectedTrade) {
+	jsonMsg, err := json.Marshal(rejected)
+	if err != nil {
+		log.Printf("JSON marshal error in quarantine: %v", err)
+
+		return
+	}
+
+	if err := q.sender.Send(jsonMsg); err != nil {
+		log.Printf("quarantine sender error %v", err)
+	}
+}
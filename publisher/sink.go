@@ -0,0 +1,183 @@
+package publisher
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/aprln/vwap-engine/config"
+)
+
+var errSinkQueueFull = errors.New("queue is full")
+
+func NewSink(name string, s Sender, sinkCfg config.Sink, deadLetter *DeadLetter) *Sink {
+	k := &Sink{
+		name:            name,
+		sender:          s,
+		maxRetries:      sinkCfg.MaxRetries,
+		retryBackoff:    sinkCfg.RetryBackoff,
+		retryMaxBackoff: sinkCfg.RetryMaxBackoff,
+		deadLetter:      deadLetter,
+		queue:           make(chan sinkItem, sinkCfg.QueueSize),
+		closing:         make(chan struct{}),
+		done:            make(chan struct{}),
+	}
+
+	go k.sendForever()
+
+	return k
+}
+
+// Sink sends the messages to its sender from its own bounded queue and goroutine, so a slow or broken sender never
+// blocks or fails the publisher nor the other sinks. A failed send is retried with an exponential backoff, and the
+// messages that cannot be sent or do not fit in the queue are written to the dead letter file, if any.
+// The messages are sent with SendMessage to a MessageSender.
+type Sink struct {
+	name            string
+	sender          Sender
+	maxRetries      int
+	retryBackoff    time.Duration
+	retryMaxBackoff time.Duration
+	deadLetter      *DeadLetter
+	closing         chan struct{}
+	closeOnce       sync.Once
+	done            chan struct{}
+	// forgets are the Forget calls waiting for room in the queue, which is closed once they are done
+	forgets sync.WaitGroup
+
+	mu sync.Mutex
+	// queue is only written to with the lock held, or by the forgets
+	queue  chan sinkItem
+	closed bool
+	// full is true from a message not fitting in the queue until the next one fits, to log it once
+	full bool
+}
+
+// sinkItem is a queued message, or the trading pair to forget once the messages queued before are sent.
+type sinkItem struct {
+	msg    Message
+	forget string
+}
+
+// Send queues the message without blocking, see SendMessage.
+func (k *Sink) Send(msg []byte) error {
+	return k.SendMessage(Message{Data: msg})
+}
+
+// SendMessage queues the message without blocking. It never fails, the messages that do not fit in the queue
+// are dead-lettered.
+func (k *Sink) SendMessage(msg Message) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if k.closed {
+		k.fail(msg, errors.New("sink is closed"))
+
+		return nil
+	}
+
+	select {
+	case k.queue <- sinkItem{msg: msg}:
+		k.full = false
+	default:
+		if !k.full {
+			log.Printf("%s sink queue is full, dead-lettering the messages until it has room", k.name)
+			k.full = true
+		}
+		k.fail(msg, errSinkQueueFull)
+	}
+
+	return nil
+}
+
+// Forget tells a Forgetter sender to forget the trading pair once the messages queued so far are sent, as they
+// would bring the trading pair back otherwise. Unlike the messages, it waits for room in the queue rather than
+// being dropped. It does nothing if the sender is not a Forgetter or the sink is closed.
+func (k *Sink) Forget(tradingPair string) {
+	if _, ok := k.sender.(Forgetter); !ok {
+		return
+	}
+
+	k.mu.Lock()
+	if k.closed {
+		k.mu.Unlock()
+
+		return
+	}
+	k.forgets.Add(1)
+	k.mu.Unlock()
+
+	defer k.forgets.Done()
+	k.queue <- sinkItem{forget: tradingPair}
+}
+
+// Close sends the queued messages and stops the sink. The retries are not waited for anymore,
+// the messages failing from then on are dead-lettered right away.
+func (k *Sink) Close() {
+	k.mu.Lock()
+	k.closed = true
+	k.mu.Unlock()
+
+	k.closeOnce.Do(
+		func() {
+			close(k.closing)
+			// no message is queued anymore, but the forgets may still be waiting for room
+			k.forgets.Wait()
+			close(k.queue)
+		},
+	)
+	<-k.done
+}
+
+func (k *Sink) sendForever() {
+	defer close(k.done)
+
+	for item := range k.queue {
+		if item.forget != "" {
+			k.sender.(Forgetter).Forget(item.forget)
+
+			continue
+		}
+
+		if err := k.sendWithRetries(item.msg); err != nil {
+			k.fail(item.msg, err)
+		}
+	}
+}
+
+func (k *Sink) sendWithRetries(msg Message) error {
+	backoff := k.retryBackoff
+	for retry := 0; ; retry++ {
+		err := sendMessage(k.sender, msg)
+		if err == nil || retry == k.maxRetries {
+			return err
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-k.closing:
+			return fmt.Errorf("%w, not retried as the sink is closing", err)
+		}
+
+		backoff *= 2
+		if backoff > k.retryMaxBackoff {
+			backoff = k.retryMaxBackoff
+		}
+	}
+}
+
+func (k *Sink) fail(msg Message, err error) {
+	if k.deadLetter == nil {
+		if !errors.Is(err, errSinkQueueFull) {
+			log.Printf("%s sink failed to send a message: %v", k.name, err)
+		}
+
+		return
+	}
+
+	if err := k.deadLetter.Write(k.name, msg.Data, err); err != nil {
+		log.Printf("failed to dead-letter a message of the %s sink: %v", k.name, err)
+	}
+}
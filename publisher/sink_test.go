@@ -0,0 +1,271 @@
+package publisher
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aprln/vwap-engine/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSender fails its first failures sends, and blocks while block is not closed if any.
+type fakeSender struct {
+	failures int
+	block    chan struct{}
+
+	mu    sync.Mutex
+	calls int
+	sent  []string
+}
+
+func (f *fakeSender) Send(msg []byte) error {
+	if f.block != nil {
+		<-f.block
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.calls++
+	if f.calls <= f.failures {
+		return errors.New("unavailable")
+	}
+	f.sent = append(f.sent, string(msg))
+
+	return nil
+}
+
+func (f *fakeSender) Sent() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.sent
+}
+
+func (f *fakeSender) Calls() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.calls
+}
+
+func newSinkConfig(queueSize, maxRetries int) config.Sink {
+	return config.Sink{
+		QueueSize:       queueSize,
+		MaxRetries:      maxRetries,
+		RetryBackoff:    time.Millisecond,
+		RetryMaxBackoff: 2 * time.Millisecond,
+	}
+}
+
+func newDeadLetter(t *testing.T) (*DeadLetter, string) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "dead_letters.jsonl")
+	deadLetter, err := NewDeadLetter(
+		path, 0, 16, func() time.Time { return time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC) },
+	)
+	require.NoError(t, err)
+
+	return deadLetter, path
+}
+
+func readDeadLetters(t *testing.T, deadLetter *DeadLetter, path string) []deadLetterEntry {
+	t.Helper()
+
+	require.NoError(t, deadLetter.Close())
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	var entries []deadLetterEntry
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		if line == "" {
+			continue
+		}
+		var entry deadLetterEntry
+		require.NoError(t, json.Unmarshal([]byte(line), &entry))
+		entries = append(entries, entry)
+	}
+
+	return entries
+}
+
+func TestSink_retry(t *testing.T) {
+	deadLetter, path := newDeadLetter(t)
+	sender := &fakeSender{failures: 2}
+	k := NewSink("test", sender, newSinkConfig(16, 2), deadLetter)
+
+	require.NoError(t, k.Send([]byte(btcMsg)))
+	assert.Eventually(
+		t, func() bool { return len(sender.Sent()) == 1 }, time.Second, time.Millisecond,
+		"the message is sent on the second retry",
+	)
+	k.Close()
+
+	assert.Equal(t, 3, sender.Calls())
+	assert.Empty(t, readDeadLetters(t, deadLetter, path))
+}
+
+func TestSink_deadLetter(t *testing.T) {
+	deadLetter, path := newDeadLetter(t)
+	sender := &fakeSender{failures: 100}
+	k := NewSink("test", sender, newSinkConfig(16, 2), deadLetter)
+
+	require.NoError(t, k.Send([]byte(btcMsg)), "a failing sender does not fail the sink")
+	require.NoError(t, k.Send([]byte{0xff}))
+	assert.Eventually(
+		t, func() bool { return sender.Calls() == 6 }, time.Second, time.Millisecond,
+		"each message is sent once and retried twice",
+	)
+	k.Close()
+	assert.Equal(
+		t, []deadLetterEntry{
+			{
+				Sink:     "test",
+				Error:    "unavailable",
+				FailedAt: time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+				Message:  json.RawMessage(btcMsg),
+			},
+			{
+				Sink:          "test",
+				Error:         "unavailable",
+				FailedAt:      time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+				MessageBase64: []byte{0xff},
+			},
+		}, readDeadLetters(t, deadLetter, path),
+	)
+}
+
+func TestSink_queueFull(t *testing.T) {
+	deadLetter, path := newDeadLetter(t)
+	sender := &fakeSender{block: make(chan struct{})}
+	k := NewSink("test", sender, newSinkConfig(1, 0), deadLetter)
+
+	// the first message is being sent, the second one is queued and the third one does not fit
+	require.NoError(t, k.Send([]byte(btcMsg)))
+	assert.Eventually(
+		t, func() bool { return len(k.queue) == 0 }, time.Second, time.Millisecond,
+	)
+	require.NoError(t, k.Send([]byte(ethMsg)))
+	require.NoError(t, k.Send([]byte(btcMsg)), "a slow sender does not block the sink")
+
+	close(sender.block)
+	k.Close()
+
+	assert.Equal(t, []string{btcMsg, ethMsg}, sender.Sent())
+	entries := readDeadLetters(t, deadLetter, path)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "queue is full", entries[0].Error)
+	assert.Equal(t, json.RawMessage(btcMsg), entries[0].Message)
+}
+
+func TestSink_Close(t *testing.T) {
+	deadLetter, path := newDeadLetter(t)
+	sender := &fakeSender{failures: 1}
+	cfg := newSinkConfig(16, 1)
+	cfg.RetryBackoff = time.Hour
+	k := NewSink("test", sender, cfg, deadLetter)
+
+	require.NoError(t, k.Send([]byte(btcMsg)))
+	require.NoError(t, k.Send([]byte(ethMsg)))
+	k.Close()
+	require.NoError(t, k.Send([]byte(ethMsg)), "a closed sink does not fail")
+
+	assert.Equal(t, []string{ethMsg}, sender.Sent(), "the queued messages are sent on close")
+	entries := readDeadLetters(t, deadLetter, path)
+	require.Len(t, entries, 2)
+	assert.Equal(t, json.RawMessage(btcMsg), entries[0].Message, "the retry is not waited for on close")
+	assert.Equal(t, "unavailable, not retried as the sink is closing", entries[0].Error)
+	assert.Equal(t, "sink is closed", entries[1].Error)
+}
+
+func TestSink_Forget(t *testing.T) {
+	ws := NewWSServerSender(16)
+	deadLetter, path := newDeadLetter(t)
+	k := NewSink("websocket", ws, newSinkConfig(16, 0), deadLetter)
+
+	require.NoError(t, k.SendMessage(newMessage(t, btcMsg)))
+	require.NoError(t, k.SendMessage(newMessage(t, ethMsg)))
+	k.Forget("BTC-USD")
+	k.Close()
+	k.Forget("ETH-USD")
+
+	_, ok := ws.hub.latestOf("BTC-USD")
+	assert.False(t, ok, "the trading pair is forgotten after its queued messages are sent")
+	latest, ok := ws.hub.latestOf("ETH-USD")
+	assert.True(t, ok, "the sink is closed")
+	assert.Equal(t, ethMsg, string(latest))
+	assert.Empty(t, readDeadLetters(t, deadLetter, path), "the messages are sent with their trading pair")
+}
+
+func TestDeadLetter(t *testing.T) {
+	t.Run(
+		"rotation", func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "dead_letters.jsonl")
+			line := `{"sink":"test","error":"unavailable","failed_at":"2022-01-01T00:00:00Z","message":` + btcMsg + "}\n"
+			deadLetter, err := NewDeadLetter(
+				path, int64(2*len(line)), 16, func() time.Time { return time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC) },
+			)
+			require.NoError(t, err)
+
+			for i := 0; i < 5; i++ {
+				require.NoError(t, deadLetter.Write("test", []byte(btcMsg), errors.New("unavailable")))
+			}
+			require.NoError(t, deadLetter.Close())
+
+			rotated, err := os.ReadFile(path + deadLetterBackupExt)
+			require.NoError(t, err)
+			assert.Equal(t, strings.Repeat(line, 2), string(rotated), "a single rotated file is kept")
+			current, err := os.ReadFile(path)
+			require.NoError(t, err)
+			assert.Equal(t, line, string(current))
+		},
+	)
+
+	t.Run(
+		"rotation after a close error", func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "dead_letters.jsonl")
+			file, err := os.Create(path)
+			require.NoError(t, err)
+			require.NoError(t, file.Close())
+			// without writing goroutine, the lines are written by the test, to a file failing to close again
+			deadLetter := &DeadLetter{path: path, maxSize: 4, file: file, size: 4}
+
+			assert.Error(t, deadLetter.write([]byte("{}\n")), "the file cannot be closed")
+			require.NoError(t, deadLetter.write([]byte("{}\n")), "the file is reopened")
+			require.NoError(t, deadLetter.file.Close())
+
+			current, err := os.ReadFile(path)
+			require.NoError(t, err)
+			assert.Equal(t, "{}\n", string(current))
+		},
+	)
+
+	t.Run(
+		"queue full", func(t *testing.T) {
+			// without writing goroutine, the queue stays full
+			deadLetter := &DeadLetter{now: time.Now, queue: make(chan []byte, 1)}
+			deadLetter.queue <- []byte("{}\n")
+
+			require.NoError(t, deadLetter.Write("test", []byte(btcMsg), errors.New("unavailable")))
+			require.NoError(t, deadLetter.Write("test", []byte(btcMsg), errors.New("unavailable")))
+			assert.Equal(t, 2, deadLetter.Dropped(), "the entries are dropped rather than waited for")
+		},
+	)
+
+	t.Run(
+		"closed", func(t *testing.T) {
+			deadLetter, path := newDeadLetter(t)
+			require.NoError(t, deadLetter.Write("test", []byte(btcMsg), errors.New("unavailable")))
+			assert.Len(t, readDeadLetters(t, deadLetter, path), 1, "the queued entries are written on close")
+			assert.Equal(t, errDeadLetterClosed, deadLetter.Write("test", []byte(btcMsg), errors.New("unavailable")))
+		},
+	)
+}
@@ -0,0 +1,256 @@
+package publisher
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aprln/vwap-engine/config"
+)
+
+const (
+	sseEvent      = "vwap"
+	sseResetEvent = "reset"
+)
+
+func SetUpSSE(httpServerCfg config.HTTPServer) *SSESender {
+	return NewSSESender(
+		httpServerCfg.StreamReplaySize, httpServerCfg.StreamBufferSize, httpServerCfg.StreamKeepAlive, time.Now,
+	)
+}
+
+// NewSSESender creates a sender whose event IDs start with the time it is created at, given by now.
+func NewSSESender(replaySize, bufferSize int, keepAlive time.Duration, now func() time.Time) *SSESender {
+	return &SSESender{
+		replay:    make([]sseMessage, replaySize),
+		keepAlive: keepAlive,
+		epoch:     strconv.FormatInt(now().UnixNano(), 10),
+		hub:       newHub[sseMessage](bufferSize, false),
+	}
+}
+
+// SSESender streams each VWAP result as a server-sent event to the clients subscribed to its trading pair.
+//
+// Clients connect with GET ?pairs=BTC-USD,ETH-USD, all trading pairs being streamed without pairs. Every event has
+// an ID "<epoch>-<seq>", the epoch being the start time of the sender in Unix nanoseconds and seq increasing.
+// A client reconnecting with a Last-Event-ID header gets the events it missed first, as far as they are still in the
+// replay buffer. If some are not, e.g. the ID is older than the replay buffer or from before a restart, a reset event
+// is sent first, so the client knows to resynchronize. A keepalive comment is sent every keepalive duration, and
+// a client more than the buffer size of events behind is disconnected. It is safe for concurrent use.
+type SSESender struct {
+	keepAlive time.Duration
+	epoch     string
+	hub       *hub[sseMessage]
+
+	// mu is held while publishing to the hub too, so a client registers either before or after an event
+	mu sync.Mutex
+	// replay is a ring of the latest messages, the next one being written at replayNext
+	replay     []sseMessage
+	replayNext int
+	lastID     uint64
+}
+
+type sseMessage struct {
+	id          uint64
+	tradingPair string
+	data        []byte
+}
+
+// Send fails, as the trading pair of the message is required, see SendMessage.
+func (s *SSESender) Send(msg []byte) error {
+	return s.SendMessage(Message{Data: msg})
+}
+
+// SendMessage never fails because of a client, it only fails on a message without trading pair.
+func (s *SSESender) SendMessage(msg Message) error {
+	if msg.TradingPair == "" {
+		return fmt.Errorf("SSE: message without trading pair: %s", msg.Data)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastID++
+	sm := sseMessage{id: s.lastID, tradingPair: msg.TradingPair, data: msg.Data}
+	if len(s.replay) > 0 {
+		s.replay[s.replayNext] = sm
+		s.replayNext = (s.replayNext + 1) % len(s.replay)
+	}
+
+	s.hub.publish(sm.tradingPair, sm)
+
+	return nil
+}
+
+// ServeHTTP streams the events to the client until it disconnects, lags behind or the sender is closed.
+func (s *SSESender) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, fmt.Sprintf("method %s is not allowed", r.Method), http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is unsupported", http.StatusInternalServerError)
+
+		return
+	}
+
+	c := s.hub.newSubscriber("SSE client "+r.RemoteAddr, parsePairs(r.URL.Query().Get("pairs")))
+	// the missed events are collected along with the registration, so no event is missed or sent twice
+	lastEventID := r.Header.Get("Last-Event-ID")
+	missed, gap, ok := s.register(c, lastEventID)
+	if !ok {
+		http.Error(w, "server is shutting down", http.StatusServiceUnavailable)
+
+		return
+	}
+	defer s.hub.remove(c)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	if gap {
+		if err := writeSSEReset(w, lastEventID); err != nil {
+			return
+		}
+	}
+	for _, sm := range missed {
+		if err := s.writeEvent(w, sm); err != nil {
+			return
+		}
+	}
+	flusher.Flush()
+
+	// a zero keepalive duration never sends keepalive comments
+	var keepAliveTick <-chan time.Time
+	if s.keepAlive > 0 {
+		keepAlive := time.NewTicker(s.keepAlive)
+		defer keepAlive.Stop()
+		keepAliveTick = keepAlive.C
+	}
+
+	for {
+		var err error
+		select {
+		case sm, more := <-c.queue:
+			if !more {
+				return
+			}
+
+			err = s.writeEvent(w, sm)
+
+		case <-keepAliveTick:
+			_, err = fmt.Fprint(w, ": keepalive\n\n")
+
+		case <-r.Context().Done():
+			return
+		}
+
+		if err != nil {
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+// Close disconnects every client.
+func (s *SSESender) Close() {
+	s.hub.close()
+}
+
+// register adds the client and returns the buffered events after lastEventID it wants, and whether some events
+// after lastEventID are not buffered anymore. It returns false once the sender is closed.
+func (s *SSESender) register(c *subscriber[sseMessage], lastEventID string) ([]sseMessage, bool, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.hub.add(c) {
+		return nil, false, false
+	}
+
+	if lastEventID == "" {
+		return nil, false, true
+	}
+
+	after, ok := s.parseEventID(lastEventID)
+	// the oldest buffered event follows the one before it, there is a gap if the client missed that one
+	buffered := uint64(len(s.replay))
+	if s.lastID < buffered {
+		buffered = s.lastID
+	}
+	gap := !ok || after < s.lastID-buffered
+	if gap {
+		after = 0
+	}
+
+	var missed []sseMessage
+	for i := range s.replay {
+		sm := s.replay[(s.replayNext+i)%len(s.replay)]
+		if sm.id > after && c.wants(sm.tradingPair) {
+			missed = append(missed, sm)
+		}
+	}
+
+	return missed, gap, true
+}
+
+// parseEventID returns the seq of an event ID, or false if it is invalid or from another epoch,
+// e.g. before a restart.
+func (s *SSESender) parseEventID(id string) (uint64, bool) {
+	epoch, seq, ok := strings.Cut(id, "-")
+	if !ok || epoch != s.epoch {
+		return 0, false
+	}
+
+	after, err := strconv.ParseUint(seq, 10, 64)
+	if err != nil || after > s.lastID {
+		return 0, false
+	}
+
+	return after, true
+}
+
+func (s *SSESender) writeEvent(w http.ResponseWriter, sm sseMessage) error {
+	_, err := fmt.Fprintf(w, "id: %s-%d\nevent: %s\ndata: %s\n\n", s.epoch, sm.id, sseEvent, sm.data)
+
+	return err
+}
+
+// writeSSEReset writes a reset event, without ID so the client keeps its last event ID.
+func writeSSEReset(w http.ResponseWriter, lastEventID string) error {
+	data, err := json.Marshal(struct {
+		LastEventID string `json:"last_event_id"`
+	}{LastEventID: lastEventID})
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", sseResetEvent, data)
+
+	return err
+}
+
+// parsePairs parses comma separated trading pairs, or returns nil for every trading pair.
+func parsePairs(s string) map[string]bool {
+	if s == "" {
+		return nil
+	}
+
+	pairs := make(map[string]bool)
+	for _, tradingPair := range strings.Split(s, ",") {
+		if tradingPair = strings.TrimSpace(tradingPair); tradingPair != "" {
+			pairs[tradingPair] = true
+		}
+	}
+
+	return pairs
+}
@@ -0,0 +1,215 @@
+package publisher
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const sseEpoch = "1640995200000000000"
+
+func sseNow() time.Time {
+	return time.Unix(0, 1640995200000000000)
+}
+
+type sseStream struct {
+	lines  *bufio.Scanner
+	cancel context.CancelFunc
+}
+
+// connectSSE connects to the stream and waits until the client is registered.
+func connectSSE(t *testing.T, s *SSESender, url, lastEventID string) sseStream {
+	t.Helper()
+
+	clients := s.hub.len()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	require.NoError(t, err)
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(
+		func() {
+			cancel()
+			_ = resp.Body.Close()
+		},
+	)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	assert.Eventually(t, func() bool { return s.hub.len() == clients+1 }, time.Second, time.Millisecond)
+
+	return sseStream{lines: bufio.NewScanner(resp.Body), cancel: cancel}
+}
+
+// next returns the next event or comment, without its trailing blank line.
+func (s sseStream) next(t *testing.T) string {
+	t.Helper()
+
+	var lines []string
+	for s.lines.Scan() {
+		if s.lines.Text() == "" {
+			return strings.Join(lines, "\n")
+		}
+		lines = append(lines, s.lines.Text())
+	}
+	require.NoError(t, s.lines.Err())
+	t.Fatal("the stream ended")
+
+	return ""
+}
+
+func TestSSESender(t *testing.T) {
+	s := NewSSESender(2, 16, 0, sseNow)
+	svr := httptest.NewServer(s)
+	defer svr.Close()
+	defer s.Close()
+
+	require.NoError(t, s.SendMessage(newMessage(t, ethMsg)))
+	require.NoError(t, s.SendMessage(newMessage(t, btcMsg)))
+
+	btc := connectSSE(t, s, svr.URL+"?pairs=BTC-USD", "")
+	all := connectSSE(t, s, svr.URL, "")
+
+	require.NoError(t, s.SendMessage(newMessage(t, ethMsg)))
+	require.NoError(t, s.SendMessage(newMessage(t, btcMsg)))
+
+	assert.Equal(
+		t, "id: "+sseEpoch+"-4\nevent: vwap\ndata: "+btcMsg, btc.next(t), "only the subscribed trading pairs are sent",
+	)
+	assert.Equal(t, "id: "+sseEpoch+"-3\nevent: vwap\ndata: "+ethMsg, all.next(t))
+	assert.Equal(t, "id: "+sseEpoch+"-4\nevent: vwap\ndata: "+btcMsg, all.next(t))
+
+	assert.Error(t, s.Send([]byte(`{"vwap":"1"}`)), "no trading pair")
+}
+
+func TestSSESender_lastEventID(t *testing.T) {
+	const reset = "event: reset\ndata: "
+
+	tests := []struct {
+		name        string
+		lastEventID string
+		wantEvents  []string
+	}{
+		{name: "missed events", lastEventID: sseEpoch + "-3", wantEvents: []string{"id: " + sseEpoch + "-4"}},
+		// event 2 is out of the replay buffer
+		{
+			name:        "too old",
+			lastEventID: sseEpoch + "-1",
+			wantEvents: []string{
+				reset + `{"last_event_id":"` + sseEpoch + `-1"}`, "id: " + sseEpoch + "-3", "id: " + sseEpoch + "-4",
+			},
+		},
+		{
+			name:        "oldest buffered",
+			lastEventID: sseEpoch + "-2",
+			wantEvents:  []string{"id: " + sseEpoch + "-3", "id: " + sseEpoch + "-4"},
+		},
+		{
+			name:        "before a restart",
+			lastEventID: "1640995100000000000-4",
+			wantEvents: []string{
+				reset + `{"last_event_id":"1640995100000000000-4"}`, "id: " + sseEpoch + "-3", "id: " + sseEpoch + "-4",
+			},
+		},
+		{
+			name:        "invalid",
+			lastEventID: "4",
+			wantEvents:  []string{reset + `{"last_event_id":"4"}`, "id: " + sseEpoch + "-3", "id: " + sseEpoch + "-4"},
+		},
+		{name: "up to date", lastEventID: sseEpoch + "-5"},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				s := NewSSESender(3, 16, 0, sseNow)
+				svr := httptest.NewServer(s)
+				defer svr.Close()
+				defer s.Close()
+
+				for i := 0; i < 4; i++ {
+					require.NoError(t, s.SendMessage(newMessage(t, btcMsg)))
+				}
+				require.NoError(t, s.SendMessage(newMessage(t, ethMsg)))
+
+				stream := connectSSE(t, s, svr.URL+"?pairs=BTC-USD", tt.lastEventID)
+
+				var gotEvents []string
+				for _, want := range tt.wantEvents {
+					event := stream.next(t)
+					if !strings.HasPrefix(want, reset) {
+						event = strings.Split(event, "\n")[0]
+					}
+					gotEvents = append(gotEvents, event)
+				}
+				assert.Equal(t, tt.wantEvents, gotEvents)
+
+				// the replay is followed by the live events
+				require.NoError(t, s.SendMessage(newMessage(t, btcMsg)))
+				assert.Equal(t, "id: "+sseEpoch+"-6\nevent: vwap\ndata: "+btcMsg, stream.next(t))
+			},
+		)
+	}
+}
+
+func TestSSESender_keepAlive(t *testing.T) {
+	s := NewSSESender(0, 16, 10*time.Millisecond, sseNow)
+	svr := httptest.NewServer(s)
+	defer svr.Close()
+	defer s.Close()
+
+	stream := connectSSE(t, s, svr.URL, "")
+
+	assert.Equal(t, ": keepalive", stream.next(t))
+}
+
+func TestSSESender_disconnect(t *testing.T) {
+	s := NewSSESender(0, 1, 0, sseNow)
+	svr := httptest.NewServer(s)
+	defer svr.Close()
+
+	t.Run(
+		"client disconnected", func(t *testing.T) {
+			stream := connectSSE(t, s, svr.URL, "")
+			stream.cancel()
+
+			assert.Eventually(t, func() bool { return s.hub.len() == 0 }, time.Second, time.Millisecond)
+		},
+	)
+
+	t.Run(
+		"lagging client", func(t *testing.T) {
+			c := s.hub.newSubscriber("SSE client", nil)
+			require.True(t, s.hub.add(c))
+
+			require.NoError(t, s.SendMessage(newMessage(t, btcMsg)))
+			require.NoError(t, s.SendMessage(newMessage(t, btcMsg)), "a lagging client does not fail the sender")
+
+			assert.Zero(t, s.hub.len())
+			<-c.queue
+			_, more := <-c.queue
+			assert.False(t, more, "the lagging client's queue is closed")
+		},
+	)
+
+	t.Run(
+		"sender closed", func(t *testing.T) {
+			stream := connectSSE(t, s, svr.URL, "")
+			s.Close()
+
+			assert.False(t, stream.lines.Scan(), "the stream ends")
+		},
+	)
+}
@@ -0,0 +1,19 @@
+package publisher
+
+import (
+	"fmt"
+	"os"
+)
+
+func newStderrSender() stderrSender {
+	return stderrSender{}
+}
+
+type stderrSender struct {
+}
+
+func (p stderrSender) Send(msg []byte) error {
+	_, err := fmt.Fprintln(os.Stderr, string(msg))
+
+	return err
+}
@@ -1,8 +1,17 @@
 package publisher
 
-import "fmt"
+import (
+	"fmt"
+	"os"
+)
+
+// newStdoutSender creates a sender writing a message per line, starting with the header line if any,
+// e.g. the CSV header.
+func newStdoutSender(header string) stdoutSender {
+	if header != "" {
+		fmt.Println(header)
+	}
 
-func newStdoutSender() stdoutSender {
 	return stdoutSender{}
 }
 
@@ -14,3 +23,17 @@ func (p stdoutSender) Send(msg []byte) error {
 
 	return nil
 }
+
+func newBinaryStdoutSender() binaryStdoutSender {
+	return binaryStdoutSender{}
+}
+
+// binaryStdoutSender writes the messages as they are, for binary encodings delimiting their messages themselves.
+type binaryStdoutSender struct {
+}
+
+func (p binaryStdoutSender) Send(msg []byte) error {
+	_, err := os.Stdout.Write(msg)
+
+	return err
+}
@@ -0,0 +1,241 @@
+package publisher
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aprln/vwap-engine/config"
+)
+
+const (
+	// WebhookTimestampHeader is the Unix time in seconds at which a webhook request was signed.
+	WebhookTimestampHeader = "X-VWAP-Timestamp"
+	// WebhookSignatureHeader is "sha256=" followed by the hex encoded signature of a webhook request, see SignWebhook.
+	WebhookSignatureHeader = "X-VWAP-Signature"
+)
+
+// SetUpWebhookSender checks the webhook settings and creates a webhook sender posting to the configured URLs.
+func SetUpWebhookSender(webhookCfg config.Webhook, deadLetter *DeadLetter) (*WebhookSender, error) {
+	if webhookCfg.Secret == "" {
+		return nil, errors.New("a webhook secret is required to sign the requests")
+	}
+
+	if webhookCfg.BatchSize > 1 && webhookCfg.BatchInterval <= 0 {
+		return nil, errors.New("a webhook batch interval is required to post the batches that are not full")
+	}
+
+	for _, u := range webhookCfg.URLs {
+		parsed, err := url.Parse(u)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return nil, fmt.Errorf(`invalid webhook URL "%s"`, u)
+		}
+	}
+
+	return NewWebhookSender(webhookCfg, &http.Client{Timeout: webhookCfg.Timeout}, time.Now, deadLetter), nil
+}
+
+func NewWebhookSender(
+	webhookCfg config.Webhook, client *http.Client, now func() time.Time, deadLetter *DeadLetter,
+) *WebhookSender {
+	s := &WebhookSender{
+		webhookCfg: webhookCfg,
+		client:     client,
+		now:        now,
+		deadLetter: deadLetter,
+		closing:    make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+
+	if webhookCfg.BatchSize > 1 && webhookCfg.BatchInterval > 0 {
+		go s.flushForever()
+	} else {
+		close(s.done)
+	}
+
+	return s
+}
+
+// WebhookSender posts the messages to every URL, one by one or in batches as a JSON array. Every request is signed,
+// see SignWebhook. A failed request is retried with an exponential backoff, unless the endpoint rejected it
+// with a 4xx status other than 408 and 429. The messages of a request that still fails are dead-lettered, if there
+// is a dead letter file, so Send only fails once the sender is closed. As it retries every URL on its own,
+// a Sink of the webhook sender must not retry it, see config.Sink.WithoutRetries.
+type WebhookSender struct {
+	webhookCfg config.Webhook
+	client     *http.Client
+	now        func() time.Time
+	deadLetter *DeadLetter
+	closing    chan struct{}
+	closeOnce  sync.Once
+	done       chan struct{}
+
+	// mu is held while a batch is posted, so the batches are posted in order
+	mu     sync.Mutex
+	batch  [][]byte
+	closed bool
+}
+
+// Send adds the message to the batch, and posts the batch once it is full. A batch of one is posted as is.
+func (s *WebhookSender) Send(msg []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return errors.New("webhook sender is closed")
+	}
+
+	s.batch = append(s.batch, msg)
+	if len(s.batch) >= s.webhookCfg.BatchSize {
+		s.flush()
+	}
+
+	return nil
+}
+
+// Close posts the pending batch and stops the sender. The retries are not waited for anymore,
+// the requests failing from then on are dead-lettered right away.
+func (s *WebhookSender) Close() {
+	s.closeOnce.Do(func() { close(s.closing) })
+	<-s.done
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.closed {
+		s.closed = true
+		s.flush()
+	}
+}
+
+// SignWebhook returns the hex encoded HMAC-SHA256 of the timestamp, a dot and the body, keyed by the secret.
+// An endpoint checks it against the WebhookSignatureHeader, and the timestamp against its clock to reject replays.
+func SignWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// flushForever posts the pending batch at every batch interval, so the messages do not wait for a full batch.
+func (s *WebhookSender) flushForever() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.webhookCfg.BatchInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.mu.Lock()
+			s.flush()
+			s.mu.Unlock()
+		case <-s.closing:
+			return
+		}
+	}
+}
+
+// flush posts the batch to every URL concurrently. It must be called with the lock held.
+func (s *WebhookSender) flush() {
+	if len(s.batch) == 0 {
+		return
+	}
+
+	body := s.batch[0]
+	if s.webhookCfg.BatchSize > 1 {
+		body = append(append([]byte{'['}, bytes.Join(s.batch, []byte{','})...), ']')
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(len(s.webhookCfg.URLs))
+	for _, u := range s.webhookCfg.URLs {
+		go func(u string) {
+			defer wg.Done()
+
+			if err := s.postWithRetries(u, body); err != nil {
+				s.fail(u, s.batch, err)
+			}
+		}(u)
+	}
+	wg.Wait()
+
+	s.batch = nil
+}
+
+func (s *WebhookSender) postWithRetries(u string, body []byte) error {
+	backoff := s.webhookCfg.RetryBackoff
+	for retry := 0; ; retry++ {
+		retryable, err := s.post(u, body)
+		if err == nil || !retryable || retry == s.webhookCfg.MaxRetries {
+			return err
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-s.closing:
+			return fmt.Errorf("%w, not retried as the webhook sender is closing", err)
+		}
+
+		backoff *= 2
+		if backoff > s.webhookCfg.RetryMaxBackoff {
+			backoff = s.webhookCfg.RetryMaxBackoff
+		}
+	}
+}
+
+// post signs and posts the body, and tells whether a failed request may succeed if retried.
+func (s *WebhookSender) post(u string, body []byte) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+
+	timestamp := strconv.FormatInt(s.now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookTimestampHeader, timestamp)
+	req.Header.Set(WebhookSignatureHeader, "sha256="+SignWebhook(s.webhookCfg.Secret, timestamp, body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	// the body is read to the end, so the connection is reused
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+
+	retryable := resp.StatusCode >= 500 ||
+		resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusRequestTimeout
+
+	return retryable, fmt.Errorf("webhook responded %s", resp.Status)
+}
+
+func (s *WebhookSender) fail(u string, batch [][]byte, err error) {
+	if s.deadLetter == nil {
+		log.Printf("failed to post %d messages to the webhook %s: %v", len(batch), u, err)
+
+		return
+	}
+
+	for _, msg := range batch {
+		if err := s.deadLetter.Write("webhook "+u, msg, err); err != nil {
+			log.Printf("failed to dead-letter a message of the webhook %s: %v", u, err)
+		}
+	}
+}
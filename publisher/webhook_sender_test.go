@@ -0,0 +1,253 @@
+package publisher
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aprln/vwap-engine/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const webhookSecret = "secret"
+
+var webhookNow = time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// webhookServer records the bodies of the requests with a valid signature, and responds with the given statuses
+// in turn, then 200.
+type webhookServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	statuses []int
+	requests int
+	bodies   []string
+}
+
+func newWebhookServer(t *testing.T, statuses ...int) *webhookServer {
+	t.Helper()
+
+	s := &webhookServer{statuses: statuses}
+	s.Server = httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				body, err := io.ReadAll(r.Body)
+				require.NoError(t, err)
+
+				mac := hmac.New(sha256.New, []byte(webhookSecret))
+				mac.Write([]byte(r.Header.Get(WebhookTimestampHeader) + "." + string(body)))
+				assert.Equal(t, "sha256="+hex.EncodeToString(mac.Sum(nil)), r.Header.Get(WebhookSignatureHeader))
+				assert.Equal(t, "1640995200", r.Header.Get(WebhookTimestampHeader))
+				assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+
+				s.mu.Lock()
+				defer s.mu.Unlock()
+
+				s.requests++
+				if len(s.statuses) > 0 {
+					status := s.statuses[0]
+					s.statuses = s.statuses[1:]
+					w.WriteHeader(status)
+
+					return
+				}
+				s.bodies = append(s.bodies, string(body))
+			},
+		),
+	)
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+func (s *webhookServer) Requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.requests
+}
+
+func (s *webhookServer) Bodies() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.bodies
+}
+
+func newWebhookConfig(batchSize int, urls ...string) config.Webhook {
+	return config.Webhook{
+		URLs:            urls,
+		Secret:          webhookSecret,
+		BatchSize:       batchSize,
+		MaxRetries:      2,
+		RetryBackoff:    time.Millisecond,
+		RetryMaxBackoff: 2 * time.Millisecond,
+	}
+}
+
+func newWebhookSender(webhookCfg config.Webhook, deadLetter *DeadLetter) *WebhookSender {
+	return NewWebhookSender(webhookCfg, http.DefaultClient, func() time.Time { return webhookNow }, deadLetter)
+}
+
+func TestSetUpWebhookSender(t *testing.T) {
+	_, err := SetUpWebhookSender(newWebhookConfig(1, "https://example.com/vwap"), nil)
+	assert.NoError(t, err)
+
+	cfg := newWebhookConfig(1, "https://example.com/vwap")
+	cfg.Secret = ""
+	_, err = SetUpWebhookSender(cfg, nil)
+	assert.Error(t, err, "no secret")
+
+	cfg = newWebhookConfig(2, "https://example.com/vwap")
+	_, err = SetUpWebhookSender(cfg, nil)
+	assert.Error(t, err, "a partial batch would only be posted on close")
+	cfg.BatchInterval = time.Second
+	_, err = SetUpWebhookSender(cfg, nil)
+	assert.NoError(t, err)
+
+	for _, u := range []string{"example.com/vwap", "ftp://example.com/vwap", "https://", ":"} {
+		_, err = SetUpWebhookSender(newWebhookConfig(1, u), nil)
+		assert.Error(t, err, u)
+	}
+}
+
+func TestSignWebhook(t *testing.T) {
+	assert.Equal(
+		t, "7b21f9877771c2bb646a28c2b70421d334761ed76e55457918e7165c938a3af6",
+		SignWebhook("secret", "1640995200", []byte(btcMsg)),
+	)
+}
+
+func TestWebhookSender_Send(t *testing.T) {
+	servers := []*webhookServer{newWebhookServer(t), newWebhookServer(t)}
+	s := newWebhookSender(newWebhookConfig(1, servers[0].URL, servers[1].URL), nil)
+
+	require.NoError(t, s.Send([]byte(btcMsg)))
+	require.NoError(t, s.Send([]byte(ethMsg)))
+	s.Close()
+	assert.Error(t, s.Send([]byte(btcMsg)), "the sender is closed")
+
+	for _, server := range servers {
+		assert.Equal(t, []string{btcMsg, ethMsg}, server.Bodies(), "every message is posted to every URL")
+	}
+}
+
+func TestWebhookSender_batch(t *testing.T) {
+	t.Run(
+		"by size", func(t *testing.T) {
+			server := newWebhookServer(t)
+			s := newWebhookSender(newWebhookConfig(2, server.URL), nil)
+
+			for _, msg := range []string{btcMsg, ethMsg, btcMsg} {
+				require.NoError(t, s.Send([]byte(msg)))
+			}
+			assert.Equal(t, []string{"[" + btcMsg + "," + ethMsg + "]"}, server.Bodies())
+
+			s.Close()
+			assert.Equal(
+				t, []string{"[" + btcMsg + "," + ethMsg + "]", "[" + btcMsg + "]"}, server.Bodies(),
+				"the pending batch is posted on close",
+			)
+		},
+	)
+
+	t.Run(
+		"by interval", func(t *testing.T) {
+			server := newWebhookServer(t)
+			cfg := newWebhookConfig(10, server.URL)
+			cfg.BatchInterval = time.Millisecond
+			s := newWebhookSender(cfg, nil)
+			defer s.Close()
+
+			require.NoError(t, s.Send([]byte(btcMsg)))
+			assert.Eventually(
+				t, func() bool { return len(server.Bodies()) == 1 }, time.Second, time.Millisecond,
+				"a partial batch is posted after the interval",
+			)
+			assert.Equal(t, "["+btcMsg+"]", server.Bodies()[0])
+		},
+	)
+}
+
+func TestWebhookSender_retry(t *testing.T) {
+	tests := []struct {
+		name             string
+		statuses         []int
+		wantRequests     int
+		wantBodies       []string
+		wantDeadLettered string
+	}{
+		{
+			name:         "succeeds on the second retry",
+			statuses:     []int{http.StatusServiceUnavailable, http.StatusTooManyRequests},
+			wantRequests: 3,
+			wantBodies:   []string{"[" + btcMsg + "," + ethMsg + "]"},
+		},
+		{
+			name:             "fails after the retries",
+			statuses:         []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway},
+			wantRequests:     3,
+			wantDeadLettered: "webhook responded 502 Bad Gateway",
+		},
+		{
+			name:             "rejected",
+			statuses:         []int{http.StatusUnauthorized},
+			wantRequests:     1,
+			wantDeadLettered: "webhook responded 401 Unauthorized",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				deadLetter, path := newDeadLetter(t)
+				server := newWebhookServer(t, tt.statuses...)
+				s := newWebhookSender(newWebhookConfig(2, server.URL), deadLetter)
+
+				require.NoError(t, s.Send([]byte(btcMsg)))
+				require.NoError(t, s.Send([]byte(ethMsg)), "a failing webhook does not fail the sender")
+				s.Close()
+
+				assert.Equal(t, tt.wantRequests, server.Requests())
+				assert.Equal(t, tt.wantBodies, server.Bodies())
+
+				entries := readDeadLetters(t, deadLetter, path)
+				if tt.wantDeadLettered == "" {
+					assert.Empty(t, entries)
+
+					return
+				}
+				require.Len(t, entries, 2, "every message of the batch is dead-lettered")
+				for i, msg := range []string{btcMsg, ethMsg} {
+					assert.Equal(t, "webhook "+server.URL, entries[i].Sink)
+					assert.Equal(t, tt.wantDeadLettered, entries[i].Error)
+					assert.Equal(t, json.RawMessage(msg), entries[i].Message)
+				}
+			},
+		)
+	}
+}
+
+func TestWebhookSender_unreachable(t *testing.T) {
+	server := newWebhookServer(t)
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+	deadLetter, path := newDeadLetter(t)
+	s := newWebhookSender(newWebhookConfig(1, down.URL, server.URL), deadLetter)
+
+	require.NoError(t, s.Send([]byte(btcMsg)))
+	s.Close()
+
+	assert.Equal(t, []string{btcMsg}, server.Bodies(), "an unreachable URL does not affect the others")
+	entries := readDeadLetters(t, deadLetter, path)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "webhook "+down.URL, entries[0].Sink)
+}
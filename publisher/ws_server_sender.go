@@ -0,0 +1,188 @@
+package publisher
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/aprln/vwap-engine/config"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	wsSubscribe   = "subscribe"
+	wsUnsubscribe = "unsubscribe"
+	wsError       = "error"
+
+	wsWriteTimeout = 10 * time.Second
+)
+
+// SetUpWSServer starts a websocket server listening on the configured address.
+// Clients connect to /ws and subscribe to trading pairs, see WSServerSender.
+func SetUpWSServer(wsServerCfg config.WSServer) (*WSServerSender, error) {
+	ln, err := net.Listen("tcp", wsServerCfg.Addr)
+	if err != nil {
+		return nil, err
+	}
+
+	s := NewWSServerSender(wsServerCfg.BufferSize)
+
+	mux := http.NewServeMux()
+	mux.Handle("/ws", s)
+	s.server = &http.Server{Handler: mux, ReadHeaderTimeout: wsWriteTimeout}
+
+	go func() {
+		if err := s.server.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("websocket server error: %v", err)
+		}
+	}()
+
+	log.Printf("websocket server listening on %s", ln.Addr())
+
+	return s, nil
+}
+
+func NewWSServerSender(bufferSize int) *WSServerSender {
+	return &WSServerSender{hub: newHub[[]byte](bufferSize, true)}
+}
+
+// WSServerSender sends each VWAP result to the websocket clients subscribed to its trading pair.
+//
+// Clients subscribe and unsubscribe with {"type":"subscribe","trading_pairs":["BTC-USD"]} and
+// {"type":"unsubscribe","trading_pairs":["BTC-USD"]}. On subscribe, a client gets the latest message of the trading
+// pair right away. A client more than the buffer size of messages behind is disconnected, so a slow client never
+// holds back the pipeline. It is safe for concurrent use.
+type WSServerSender struct {
+	upgrader websocket.Upgrader
+	server   *http.Server
+	hub      *hub[[]byte]
+}
+
+// wsRequest is a message from a client.
+type wsRequest struct {
+	Type         string   `json:"type"`
+	TradingPairs []string `json:"trading_pairs"`
+}
+
+// wsResponse is an error sent to a client.
+type wsResponse struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+// Send fails, as the trading pair of the message is required, see SendMessage.
+func (s *WSServerSender) Send(msg []byte) error {
+	return s.SendMessage(Message{Data: msg})
+}
+
+// SendMessage never fails because of a client, it only fails on a message without trading pair.
+func (s *WSServerSender) SendMessage(msg Message) error {
+	if msg.TradingPair == "" {
+		return fmt.Errorf("websocket server: message without trading pair: %s", msg.Data)
+	}
+
+	s.hub.publish(msg.TradingPair, msg.Data)
+
+	return nil
+}
+
+// Forget drops the latest message of the trading pair, so it is not sent on subscribe anymore.
+func (s *WSServerSender) Forget(tradingPair string) {
+	s.hub.forget(tradingPair)
+}
+
+// ServeHTTP upgrades the connection to a websocket and serves the client until it disconnects.
+func (s *WSServerSender) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Printf("websocket upgrade error: %v", err)
+
+		return
+	}
+
+	c := s.hub.newSubscriber("websocket client "+conn.RemoteAddr().String(), make(map[string]bool))
+	if !s.hub.add(c) {
+		_ = conn.Close()
+
+		return
+	}
+
+	go s.writeForever(conn, c)
+	s.readForever(conn, c)
+}
+
+// Close stops the server, if any, and disconnects every client.
+func (s *WSServerSender) Close() error {
+	var err error
+	if s.server != nil {
+		err = s.server.Close()
+	}
+
+	s.hub.close()
+
+	return err
+}
+
+// readForever handles the requests of the client until it disconnects.
+func (s *WSServerSender) readForever(conn *websocket.Conn, c *subscriber[[]byte]) {
+	defer s.hub.remove(c)
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		var req wsRequest
+		if err := json.Unmarshal(data, &req); err != nil {
+			s.reply(c, fmt.Sprintf("invalid request: %v", err))
+
+			continue
+		}
+
+		switch req.Type {
+		case wsSubscribe:
+			s.hub.subscribe(c, req.TradingPairs)
+
+		case wsUnsubscribe:
+			s.hub.unsubscribe(c, req.TradingPairs)
+
+		default:
+			s.reply(c, fmt.Sprintf(`request type "%s" is unsupported`, req.Type))
+		}
+	}
+}
+
+func (s *WSServerSender) reply(c *subscriber[[]byte], message string) {
+	msg, err := json.Marshal(wsResponse{Type: wsError, Message: message})
+	if err != nil {
+		log.Printf("JSON marshal error in websocket server: %v", err)
+
+		return
+	}
+
+	s.hub.send(c, msg)
+}
+
+// writeForever writes the queued messages to the client until it is removed or a write fails.
+// Closing the connection ends the reader too.
+func (s *WSServerSender) writeForever(conn *websocket.Conn, c *subscriber[[]byte]) {
+	defer func() { _ = conn.Close() }()
+	defer s.hub.remove(c)
+
+	for msg := range c.queue {
+		if err := conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout)); err != nil {
+			return
+		}
+
+		if err := conn.WriteMessage(websocket.TextMessage, msg); err != nil {
+			log.Printf("websocket write error: %v", err)
+
+			return
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package publisher
+
+import (
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	btcMsg = `{"trading_pair":"BTC-USD","vwap":"20433.31"}`
+	ethMsg = `{"trading_pair":"ETH-USD","vwap":"1500"}`
+)
+
+func dialWSServer(t *testing.T, svr *httptest.Server) *websocket.Conn {
+	t.Helper()
+
+	conn, _, err := websocket.DefaultDialer.Dial(strings.Replace(svr.URL, "http://", "ws://", 1), nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return conn
+}
+
+// request sends the request and waits until the sender has applied it to the client's subscriptions.
+func request(t *testing.T, s *WSServerSender, conn *websocket.Conn, req string, wantSubscribed int) {
+	t.Helper()
+
+	require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(req)))
+	assert.Eventually(
+		t, func() bool {
+			s.hub.mu.Lock()
+			defer s.hub.mu.Unlock()
+
+			for c := range s.hub.subscribers {
+				if c.name == "websocket client "+conn.LocalAddr().String() {
+					return len(c.pairs) == wantSubscribed
+				}
+			}
+
+			return false
+		}, time.Second, time.Millisecond,
+	)
+}
+
+func readWS(t *testing.T, conn *websocket.Conn) string {
+	t.Helper()
+
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+	_, msg, err := conn.ReadMessage()
+	require.NoError(t, err)
+
+	return string(msg)
+}
+
+func TestWSServerSender(t *testing.T) {
+	s := NewWSServerSender(16)
+	svr := httptest.NewServer(s)
+	defer svr.Close()
+	defer s.Close()
+
+	first := dialWSServer(t, svr)
+	request(t, s, first, `{"type":"subscribe","trading_pairs":["BTC-USD"]}`, 1)
+
+	require.NoError(t, s.SendMessage(newMessage(t, ethMsg)))
+	require.NoError(t, s.SendMessage(newMessage(t, btcMsg)))
+	assert.Equal(t, btcMsg, readWS(t, first), "only the subscribed trading pairs are sent")
+
+	// a late subscriber gets the latest messages right away
+	second := dialWSServer(t, svr)
+	request(t, s, second, `{"type":"subscribe","trading_pairs":["ETH-USD","BTC-USD"]}`, 2)
+	assert.ElementsMatch(t, []string{ethMsg, btcMsg}, []string{readWS(t, second), readWS(t, second)})
+
+	request(t, s, second, `{"type":"unsubscribe","trading_pairs":["BTC-USD"]}`, 1)
+	require.NoError(t, s.SendMessage(newMessage(t, btcMsg)))
+	require.NoError(t, s.SendMessage(newMessage(t, ethMsg)))
+	assert.Equal(t, btcMsg, readWS(t, first))
+	assert.Equal(t, ethMsg, readWS(t, second))
+
+	require.NoError(t, second.WriteMessage(websocket.TextMessage, []byte(`{"type":"banana"}`)))
+	assert.Equal(t, `{"type":"error","message":"request type \"banana\" is unsupported"}`, readWS(t, second))
+
+	assert.Error(t, s.Send([]byte(`{"vwap":"1"}`)), "no trading pair")
+}
+
+func TestWSServerSender_lagging(t *testing.T) {
+	s := NewWSServerSender(1)
+	svr := httptest.NewServer(s)
+	defer svr.Close()
+	defer s.Close()
+
+	client := dialWSServer(t, svr)
+	request(t, s, client, `{"type":"subscribe","trading_pairs":["BTC-USD"]}`, 1)
+
+	// the client does not read, so its connection's buffers fill up, then its queue
+	msg := newMessage(t, btcMsg)
+	msg.Data = []byte(strings.Repeat(" ", 1<<20) + btcMsg)
+	assert.Eventually(
+		t, func() bool {
+			require.NoError(t, s.SendMessage(msg), "a lagging client does not fail the sender")
+
+			return s.hub.len() == 0
+		}, 10*time.Second, time.Millisecond,
+	)
+
+	require.NoError(t, client.SetReadDeadline(time.Now().Add(time.Second)))
+	for {
+		if _, _, err := client.ReadMessage(); err != nil {
+			assert.NotErrorIs(t, err, os.ErrDeadlineExceeded, "the lagging client is disconnected")
+
+			break
+		}
+	}
+}
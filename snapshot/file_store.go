@@ -0,0 +1,85 @@
+package snapshot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aprln/vwap-engine/config"
+	"github.com/aprln/vwap-engine/processor"
+)
+
+func SetUp(snapshotCfg config.Snapshot) (FileStore, error) {
+	return NewFileStore(snapshotCfg.Dir)
+}
+
+// NewFileStore creates a store writing one JSON file per trading pair in dir, creating dir if needed.
+func NewFileStore(dir string) (FileStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return FileStore{}, fmt.Errorf("failed to create snapshot directory %s: %v", dir, err)
+	}
+
+	return FileStore{dir: dir}, nil
+}
+
+type FileStore struct {
+	dir string
+}
+
+// Save writes the snapshot to a temporary file first and renames it,
+// so a crash while saving never leaves a truncated snapshot behind.
+func (s FileStore) Save(snapshot processor.Snapshot) error {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(s.dir, ".snapshot-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+
+		return err
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), s.path(snapshot.TradingPair))
+}
+
+func (s FileStore) Load(tradingPair string) (processor.Snapshot, bool, error) {
+	data, err := os.ReadFile(s.path(tradingPair))
+	if errors.Is(err, os.ErrNotExist) {
+		return processor.Snapshot{}, false, nil
+	}
+
+	if err != nil {
+		return processor.Snapshot{}, false, err
+	}
+
+	var snapshot processor.Snapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return processor.Snapshot{}, false, fmt.Errorf("invalid snapshot %s: %v", s.path(tradingPair), err)
+	}
+
+	return snapshot, true, nil
+}
+
+func (s FileStore) path(tradingPair string) string {
+	return filepath.Join(s.dir, strings.ReplaceAll(tradingPair, string(filepath.Separator), "_")+".json")
+}
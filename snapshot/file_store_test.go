@@ -0,0 +1,61 @@
+package snapshot
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aprln/vwap-engine/config"
+	"github.com/aprln/vwap-engine/processor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileStore(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "snapshots")
+	store, err := SetUp(config.Snapshot{Dir: dir})
+	require.NoError(t, err)
+
+	_, ok, err := store.Load("BTC-USD")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	snapshot := processor.Snapshot{
+		TradingPair: "BTC-USD",
+		Calculator:  config.CalculatorNameWindow,
+		TakenAt:     time.Date(2022, 1, 1, 1, 1, 1, 0, time.UTC),
+		LastTradeID: 42,
+		State:       json.RawMessage(`{"window_size":3}`),
+	}
+	require.NoError(t, store.Save(snapshot))
+
+	got, ok, err := store.Load("BTC-USD")
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, snapshot, got)
+
+	snapshot.LastTradeID = 43
+	require.NoError(t, store.Save(snapshot))
+
+	got, ok, err = store.Load("BTC-USD")
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, int64(43), got.LastTradeID)
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1, "no temporary file is left behind")
+	assert.Equal(t, "BTC-USD.json", entries[0].Name())
+}
+
+func TestFileStore_Load_invalid(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewFileStore(dir)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "BTC-USD.json"), []byte("{"), 0o644))
+
+	_, _, err = store.Load("BTC-USD")
+	require.Error(t, err)
+}
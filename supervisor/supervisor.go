@@ -0,0 +1,355 @@
+package supervisor
+
+import (
+	"fmt"
+	"log"
+	"reflect"
+	"sort"
+	"sync"
+
+	"github.com/aprln/vwap-engine/config"
+)
+
+// Pipeline is the running feed → processor → publisher chain of a trading pair.
+type Pipeline struct {
+	// Stop ends the pipeline by closing its feed. The rest of the pipeline drains before Done is closed.
+	Stop func() error
+	// Done is closed once every stage of the pipeline has returned.
+	Done <-chan struct{}
+	// ResetSession starts a new session of the pipeline's calculator with the next trade, nil if unsupported.
+	ResetSession func() error
+	// Verify asks for the pipeline's calculator totals to be verified before the next trade, nil if unsupported.
+	Verify func() error
+}
+
+// StartFunc starts the pipeline of a trading pair with the given config.
+type StartFunc func(cfg config.Config, tradingPair string) (Pipeline, error)
+
+func New(cfg config.Config, start StartFunc) *Supervisor {
+	return &Supervisor{
+		cfg:       cfg,
+		start:     start,
+		pipelines: make(map[string]*pipeline),
+		done:      make(chan struct{}),
+	}
+}
+
+// Supervisor starts and stops the pipelines of trading pairs while the process runs.
+// It is safe for concurrent use.
+type Supervisor struct {
+	start StartFunc
+
+	mu           sync.Mutex
+	cfg          config.Config
+	pipelines    map[string]*pipeline
+	shuttingDown bool
+	done         chan struct{}
+	doneOnce     sync.Once
+}
+
+type pipeline struct {
+	Pipeline
+	cfg     pairConfig
+	removed bool
+	// starting is true while the pipeline is being started, which is done without holding the lock
+	// as it connects to the exchange. The pipeline cannot be stopped until then.
+	starting bool
+}
+
+// pairConfig holds the settings of a trading pair whose change restarts its pipeline, which are those the
+// pipeline is built with. The stages shared by the pipelines are not rebuilt.
+type pairConfig struct {
+	vwap         config.VWAP
+	validation   config.Validation
+	feed         config.Feed
+	bar          config.Bar
+	output       config.Output
+	backpressure config.Backpressure
+}
+
+// Add starts the pipeline of the trading pair with the current config, along with the overrides of the trading pair.
+// The other trading pairs can be added, removed and listed while the pipeline starts.
+func (s *Supervisor) Add(tradingPair string) error {
+	s.mu.Lock()
+	if s.shuttingDown {
+		s.mu.Unlock()
+
+		return fmt.Errorf("supervisor is shutting down")
+	}
+
+	if _, ok := s.pipelines[tradingPair]; ok {
+		s.mu.Unlock()
+
+		return fmt.Errorf(`trading pair "%s" is already running`, tradingPair)
+	}
+
+	// the overrides of a trading pair added at runtime are only loaded now
+	cfg, err := s.cfg.WithPairOverrides(tradingPair)
+	if err != nil {
+		s.mu.Unlock()
+
+		return fmt.Errorf(`invalid config of trading pair "%s": %w`, tradingPair, err)
+	}
+	// the placeholder keeps the trading pair from being added twice while it starts
+	p := &pipeline{cfg: pairSettings(cfg, tradingPair), starting: true}
+	s.pipelines[tradingPair] = p
+	s.mu.Unlock()
+
+	started, err := s.start(cfg, tradingPair)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err != nil {
+		delete(s.pipelines, tradingPair)
+		// a shutdown may have been waiting for this pipeline
+		if s.shuttingDown {
+			s.closeDoneIfIdleLocked()
+		}
+
+		return fmt.Errorf(`failed to start trading pair "%s": %w`, tradingPair, err)
+	}
+
+	p.Pipeline = started
+	p.starting = false
+	go s.watch(tradingPair, p)
+
+	log.Printf("%s pipeline started", tradingPair)
+
+	// the shutdown skipped the pipeline while it was starting
+	if s.shuttingDown {
+		go func() {
+			if err := started.Stop(); err != nil {
+				log.Printf(`failed to stop trading pair "%s": %v`, tradingPair, err)
+			}
+		}()
+	}
+
+	return nil
+}
+
+// Remove stops the pipeline of the trading pair and waits until it has drained.
+// Unlike a pipeline ending on its own, removing the last pipeline does not end Wait, so pairs can be added again.
+func (s *Supervisor) Remove(tradingPair string) error {
+	s.mu.Lock()
+	p, ok := s.pipelines[tradingPair]
+	if !ok || p.removed {
+		s.mu.Unlock()
+
+		return fmt.Errorf(`trading pair "%s" is not running`, tradingPair)
+	}
+	if p.starting {
+		s.mu.Unlock()
+
+		return fmt.Errorf(`trading pair "%s" is still starting`, tradingPair)
+	}
+	p.removed = true
+	s.mu.Unlock()
+
+	if err := p.Stop(); err != nil {
+		s.mu.Lock()
+		p.removed = false
+		s.mu.Unlock()
+
+		return fmt.Errorf(`failed to stop trading pair "%s": %w`, tradingPair, err)
+	}
+
+	<-p.Done
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.pipelines[tradingPair] == p {
+		delete(s.pipelines, tradingPair)
+	}
+	// a shutdown may have been waiting for this pipeline
+	if s.shuttingDown {
+		s.closeDoneIfIdleLocked()
+	}
+
+	log.Printf("%s pipeline removed", tradingPair)
+
+	return nil
+}
+
+// Reload applies the config: the pipelines of the trading pairs no longer configured are removed, those of new
+// trading pairs are started, and those whose pipeline settings changed are restarted, e.g. their VWAP settings
+// or the backpressure policies.
+// Restarted pipelines keep their window if snapshots are enabled, since a processor snapshots when its feed
+// is closed. Reload carries on when a trading pair fails, and returns the first error.
+func (s *Supervisor) Reload(cfg config.Config) error {
+	s.mu.Lock()
+	s.cfg = cfg
+	running := make(map[string]*pipeline, len(s.pipelines))
+	for tradingPair, p := range s.pipelines {
+		running[tradingPair] = p
+	}
+	s.mu.Unlock()
+
+	wanted := make(map[string]bool, len(cfg.VWAP.TradingPairs))
+	for _, tradingPair := range cfg.VWAP.TradingPairs {
+		wanted[tradingPair] = true
+	}
+
+	var firstErr error
+	keepErr := func(err error) {
+		if err == nil {
+			return
+		}
+
+		log.Printf("reload error: %v", err)
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	for tradingPair, p := range running {
+		if !wanted[tradingPair] {
+			keepErr(s.Remove(tradingPair))
+
+			continue
+		}
+
+		if !reflect.DeepEqual(p.cfg, pairSettings(cfg, tradingPair)) {
+			log.Printf("%s settings changed, restarting its pipeline", tradingPair)
+			keepErr(s.Remove(tradingPair))
+			keepErr(s.Add(tradingPair))
+		}
+	}
+
+	for _, tradingPair := range cfg.VWAP.TradingPairs {
+		if _, ok := running[tradingPair]; !ok {
+			keepErr(s.Add(tradingPair))
+		}
+	}
+
+	return firstErr
+}
+
+// TradingPairs returns the sorted trading pairs whose pipelines are running.
+func (s *Supervisor) TradingPairs() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tradingPairs := make([]string, 0, len(s.pipelines))
+	for tradingPair := range s.pipelines {
+		tradingPairs = append(tradingPairs, tradingPair)
+	}
+	sort.Strings(tradingPairs)
+
+	return tradingPairs
+}
+
+// ResetSession starts a new session of the trading pair with its next trade, e.g. with the "manual" anchor.
+func (s *Supervisor) ResetSession(tradingPair string) error {
+	p, err := s.running(tradingPair)
+	if err != nil {
+		return err
+	}
+
+	if p.ResetSession == nil {
+		return fmt.Errorf(`trading pair "%s" does not support session reset`, tradingPair)
+	}
+
+	return p.ResetSession()
+}
+
+// Verify asks for the running totals of the trading pair to be verified before its next trade.
+func (s *Supervisor) Verify(tradingPair string) error {
+	p, err := s.running(tradingPair)
+	if err != nil {
+		return err
+	}
+
+	if p.Verify == nil {
+		return fmt.Errorf(`trading pair "%s" does not support verification`, tradingPair)
+	}
+
+	return p.Verify()
+}
+
+// Shutdown stops every pipeline without waiting, see Wait. No pipeline can be added afterwards.
+func (s *Supervisor) Shutdown() {
+	s.mu.Lock()
+	s.shuttingDown = true
+	pipelines := make(map[string]*pipeline, len(s.pipelines))
+	for tradingPair, p := range s.pipelines {
+		// the pipelines being removed are already stopping, and those starting are stopped once started
+		if !p.removed && !p.starting {
+			pipelines[tradingPair] = p
+		}
+	}
+	s.closeDoneIfIdleLocked()
+	s.mu.Unlock()
+
+	for tradingPair, p := range pipelines {
+		if err := p.Stop(); err != nil {
+			log.Printf(`failed to stop trading pair "%s": %v`, tradingPair, err)
+		}
+	}
+}
+
+// Wait blocks until no pipeline is running after a shutdown, or after the last pipeline ended on its own,
+// e.g. because its feed's connection was closed.
+func (s *Supervisor) Wait() {
+	<-s.done
+}
+
+// watch forgets the pipeline once it ended, unless it is being removed.
+func (s *Supervisor) watch(tradingPair string, p *pipeline) {
+	<-p.Done
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if p.removed {
+		return
+	}
+
+	if s.pipelines[tradingPair] == p {
+		delete(s.pipelines, tradingPair)
+		log.Printf("%s pipeline ended", tradingPair)
+	}
+	s.closeDoneIfIdleLocked()
+}
+
+// running returns the pipeline of the trading pair, unless it is starting or being removed.
+func (s *Supervisor) running(tradingPair string) (*pipeline, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.pipelines[tradingPair]
+	if !ok || p.removed {
+		return nil, fmt.Errorf(`trading pair "%s" is not running`, tradingPair)
+	}
+	if p.starting {
+		return nil, fmt.Errorf(`trading pair "%s" is still starting`, tradingPair)
+	}
+
+	return p, nil
+}
+
+func (s *Supervisor) closeDoneIfIdleLocked() {
+	if len(s.pipelines) == 0 {
+		s.doneOnce.Do(func() { close(s.done) })
+	}
+}
+
+// pairSettings returns the pipeline settings of the trading pair, without the other trading pairs'.
+func pairSettings(cfg config.Config, tradingPair string) pairConfig {
+	v := cfg.VWAP.ForPair(tradingPair)
+	v.TradingPairs = nil
+	v.PerPair = nil
+
+	validation := cfg.Validation.ForPair(tradingPair)
+	validation.PerPair = nil
+
+	return pairConfig{
+		vwap:         v,
+		validation:   validation,
+		feed:         cfg.Feed,
+		bar:          cfg.Bar,
+		output:       cfg.Output,
+		backpressure: cfg.Backpressure,
+	}
+}
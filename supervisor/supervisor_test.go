@@ -0,0 +1,308 @@
+package supervisor
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aprln/vwap-engine/config"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeStarter starts pipelines that run until they are stopped or ended.
+type fakeStarter struct {
+	mu       sync.Mutex
+	started  []string
+	stops    map[string]chan struct{}
+	failing  map[string]bool
+	resets   []string
+	verifies []string
+}
+
+func newFakeStarter() *fakeStarter {
+	return &fakeStarter{stops: make(map[string]chan struct{}), failing: make(map[string]bool)}
+}
+
+func (f *fakeStarter) start(_ config.Config, tradingPair string) (Pipeline, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.failing[tradingPair] {
+		return Pipeline{}, errors.New("connection refused")
+	}
+
+	f.started = append(f.started, tradingPair)
+	stop := make(chan struct{})
+	f.stops[tradingPair] = stop
+	var once sync.Once
+
+	return Pipeline{
+		Stop: func() error {
+			once.Do(func() { close(stop) })
+
+			return nil
+		},
+		Done: stop,
+		ResetSession: func() error {
+			f.mu.Lock()
+			defer f.mu.Unlock()
+
+			f.resets = append(f.resets, tradingPair)
+
+			return nil
+		},
+		Verify: func() error {
+			f.mu.Lock()
+			defer f.mu.Unlock()
+
+			f.verifies = append(f.verifies, tradingPair)
+
+			return nil
+		},
+	}, nil
+}
+
+// end ends the pipeline on its own, e.g. as if its feed's connection was closed.
+func (f *fakeStarter) end(tradingPair string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	close(f.stops[tradingPair])
+}
+
+func (f *fakeStarter) startedPairs() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]string(nil), f.started...)
+}
+
+func newConfig(tradingPairs ...string) config.Config {
+	perPair := make(map[string]config.PairVWAP, len(tradingPairs))
+	for _, tradingPair := range tradingPairs {
+		perPair[tradingPair] = config.PairVWAP{Calculator: config.CalculatorNameWindow}
+	}
+
+	return config.Config{
+		VWAP: config.VWAP{
+			TradingPairs: tradingPairs,
+			WindowSize:   200,
+			PairVWAP:     config.PairVWAP{Calculator: config.CalculatorNameWindow},
+			PerPair:      perPair,
+		},
+	}
+}
+
+func TestSupervisor_AddRemove(t *testing.T) {
+	starter := newFakeStarter()
+	s := New(newConfig(), starter.start)
+
+	require.NoError(t, s.Add("BTC-USD"))
+	require.NoError(t, s.Add("ETH-USD"))
+	assert.Error(t, s.Add("BTC-USD"), "already running")
+	assert.Equal(t, []string{"BTC-USD", "ETH-USD"}, s.TradingPairs())
+
+	require.NoError(t, s.Remove("BTC-USD"))
+	assert.Error(t, s.Remove("BTC-USD"), "not running")
+	assert.Equal(t, []string{"ETH-USD"}, s.TradingPairs())
+
+	// a removed trading pair can be added again
+	require.NoError(t, s.Add("BTC-USD"))
+	assert.Equal(t, []string{"BTC-USD", "ETH-USD", "BTC-USD"}, starter.startedPairs())
+
+	starter.failing["SOL-USD"] = true
+	assert.Error(t, s.Add("SOL-USD"))
+	assert.Equal(t, []string{"BTC-USD", "ETH-USD"}, s.TradingPairs())
+}
+
+func TestSupervisor_ResetSession(t *testing.T) {
+	starter := newFakeStarter()
+	s := New(newConfig(), starter.start)
+	require.NoError(t, s.Add("BTC-USD"))
+
+	require.NoError(t, s.ResetSession("BTC-USD"))
+	assert.Error(t, s.ResetSession("ETH-USD"), "not running")
+	assert.Equal(t, []string{"BTC-USD"}, starter.resets)
+}
+
+func TestSupervisor_Verify(t *testing.T) {
+	starter := newFakeStarter()
+	s := New(newConfig(), starter.start)
+	require.NoError(t, s.Add("BTC-USD"))
+
+	require.NoError(t, s.Verify("BTC-USD"))
+	assert.Error(t, s.Verify("ETH-USD"), "not running")
+	assert.Equal(t, []string{"BTC-USD"}, starter.verifies)
+}
+
+func TestSupervisor_Add_starting(t *testing.T) {
+	starter := newFakeStarter()
+	dialing := make(chan struct{})
+	dialed := make(chan struct{})
+	s := New(
+		newConfig(), func(cfg config.Config, tradingPair string) (Pipeline, error) {
+			if tradingPair == "BTC-USD" {
+				close(dialing)
+				<-dialed
+			}
+
+			return starter.start(cfg, tradingPair)
+		},
+	)
+
+	added := make(chan error)
+	go func() {
+		added <- s.Add("BTC-USD")
+	}()
+	<-dialing
+
+	// the supervisor is not locked while BTC-USD starts
+	require.NoError(t, s.Add("ETH-USD"))
+	assert.Equal(t, []string{"BTC-USD", "ETH-USD"}, s.TradingPairs())
+	assert.Error(t, s.Add("BTC-USD"), "already starting")
+	assert.Error(t, s.Remove("BTC-USD"), "still starting")
+
+	s.Shutdown()
+	close(dialed)
+	require.NoError(t, <-added)
+
+	waited := make(chan struct{})
+	go func() {
+		s.Wait()
+		close(waited)
+	}()
+	select {
+	case <-waited:
+	case <-time.After(time.Second):
+		t.Fatal("the pipeline started during the shutdown was not stopped")
+	}
+}
+
+func TestSupervisor_Reload(t *testing.T) {
+	starter := newFakeStarter()
+	cfg := newConfig("BTC-USD", "ETH-USD")
+	s := New(cfg, starter.start)
+	for _, tradingPair := range cfg.VWAP.TradingPairs {
+		require.NoError(t, s.Add(tradingPair))
+	}
+
+	reloaded := newConfig("ETH-USD", "SOL-USD")
+	require.NoError(t, s.Reload(reloaded))
+	assert.Equal(t, []string{"ETH-USD", "SOL-USD"}, s.TradingPairs())
+	assert.Equal(t, []string{"BTC-USD", "ETH-USD", "SOL-USD"}, starter.startedPairs(), "ETH-USD is not restarted")
+
+	// changed settings restart the pipeline
+	changed := newConfig("ETH-USD", "SOL-USD")
+	changed.VWAP.PerPair["ETH-USD"] = config.PairVWAP{Calculator: config.CalculatorNameVolume}
+	require.NoError(t, s.Reload(changed))
+	assert.Equal(t, []string{"ETH-USD", "SOL-USD"}, s.TradingPairs())
+	assert.Equal(t, []string{"BTC-USD", "ETH-USD", "SOL-USD", "ETH-USD"}, starter.startedPairs())
+
+	// so do changed validation limits
+	limited := newConfig("ETH-USD", "SOL-USD")
+	limited.VWAP.PerPair["ETH-USD"] = changed.VWAP.PerPair["ETH-USD"]
+	limited.Validation.PerPair = map[string]config.PairValidation{"SOL-USD": {MaxSize: decimal.NewFromInt(10)}}
+	require.NoError(t, s.Reload(limited))
+	assert.Equal(t, []string{"BTC-USD", "ETH-USD", "SOL-USD", "ETH-USD", "SOL-USD"}, starter.startedPairs())
+
+	// and so do the other settings the pipelines are built with
+	buffered := limited
+	buffered.Backpressure.TradesCapacity = 10
+	require.NoError(t, s.Reload(buffered))
+	started := starter.startedPairs()
+	require.Len(t, started, 7)
+	assert.ElementsMatch(t, []string{"ETH-USD", "SOL-USD"}, started[5:])
+
+	// a failing trading pair does not stop the others from being started
+	starter.failing["ADA-USD"] = true
+	assert.Error(t, s.Reload(newConfig("ETH-USD", "SOL-USD", "ADA-USD", "BTC-USD")))
+	assert.Equal(t, []string{"BTC-USD", "ETH-USD", "SOL-USD"}, s.TradingPairs())
+}
+
+func TestSupervisor_Add_pairOverrides(t *testing.T) {
+	t.Setenv("VWAP_CALCULATOR_SOL_USD", "ewma")
+	var started config.Config
+	s := New(
+		newConfig("BTC-USD"), func(cfg config.Config, _ string) (Pipeline, error) {
+			started = cfg
+
+			return Pipeline{Stop: func() error { return nil }, Done: make(chan struct{})}, nil
+		},
+	)
+
+	// SOL-USD was not configured at startup, so its override is loaded when it is added
+	require.NoError(t, s.Add("SOL-USD"))
+	assert.Equal(t, config.CalculatorNameEWMA, started.VWAP.ForPair("SOL-USD").Calculator)
+
+	t.Setenv("VWAP_WINDOW_VOLUME_ADA_USD", "lots")
+	assert.Error(t, s.Add("ADA-USD"))
+	assert.Equal(t, []string{"SOL-USD"}, s.TradingPairs())
+}
+
+func TestSupervisor_Wait(t *testing.T) {
+	tests := []struct {
+		name string
+		end  func(s *Supervisor, starter *fakeStarter)
+	}{
+		{
+			name: "shutdown",
+			end: func(s *Supervisor, _ *fakeStarter) {
+				s.Shutdown()
+			},
+		},
+		{
+			name: "pipelines ended on their own",
+			end: func(_ *Supervisor, starter *fakeStarter) {
+				starter.end("BTC-USD")
+				starter.end("ETH-USD")
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				starter := newFakeStarter()
+				s := New(newConfig(), starter.start)
+				require.NoError(t, s.Add("BTC-USD"))
+				require.NoError(t, s.Add("ETH-USD"))
+
+				tt.end(s, starter)
+
+				waited := make(chan struct{})
+				go func() {
+					s.Wait()
+					close(waited)
+				}()
+
+				select {
+				case <-waited:
+				case <-time.After(time.Second):
+					t.Fatal("Wait did not return")
+				}
+				assert.Empty(t, s.TradingPairs())
+			},
+		)
+	}
+}
+
+func TestSupervisor_Wait_removed(t *testing.T) {
+	starter := newFakeStarter()
+	s := New(newConfig(), starter.start)
+	require.NoError(t, s.Add("BTC-USD"))
+
+	require.NoError(t, s.Remove("BTC-USD"))
+
+	select {
+	case <-s.done:
+		t.Fatal("Wait returned after the last trading pair was removed")
+	default:
+	}
+
+	s.Shutdown()
+	s.Wait()
+	assert.Error(t, s.Add("BTC-USD"), "shutting down")
+}